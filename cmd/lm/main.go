@@ -1,16 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"lunchmoney-cli/internal/cli"
 )
 
 func main() {
+	// The first Ctrl-C or SIGTERM cancels the command's context so it can
+	// stop between requests; a second one kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	root := cli.NewRootCmd()
-	if err := root.Execute(); err != nil {
+	err := cli.Execute(ctx, root)
+	stop()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
@@ -1,10 +1,17 @@
 module lunchmoney-cli
 
-go 1.26
+go 1.26.0
 
-require github.com/spf13/cobra v1.10.1
+require (
+	filippo.io/age v1.3.2
+	github.com/spf13/cobra v1.10.1
+	golang.org/x/sync v0.23.0
+)
 
 require (
+	filippo.io/hpke v0.4.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
+	golang.org/x/crypto v0.55.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
 )
@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// exitCodeAlerts is returned by lm alerts when at least one alert fires, so
+// it can be told apart from a failed run (1).
+const exitCodeAlerts = 2
+
+// fewChargesRatio is how many times the largest earlier charge a payee seen
+// only once or twice must be charged to count as an outlier; with so little
+// history there is no spread to measure.
+const fewChargesRatio = 3
+
+const (
+	alertPayeeOutlier  = "payee_outlier"
+	alertNewMerchant   = "new_merchant"
+	alertPriceIncrease = "price_increase"
+	alertCategorySpike = "category_spike"
+)
+
+// alert is one finding. Amounts are spending in base currency, positive for
+// money out.
+type alert struct {
+	Kind          string            `json:"kind"`
+	Date          string            `json:"date,omitempty"`
+	Subject       string            `json:"subject"`
+	TransactionID int64             `json:"transaction_id,omitempty"`
+	Amount        lunchmoney.Amount `json:"amount"`
+	Baseline      lunchmoney.Amount `json:"baseline"`
+	Currency      string            `json:"currency"`
+	Message       string            `json:"message"`
+}
+
+type alertOptions struct {
+	recentStart       time.Time
+	days              int
+	historyDays       int
+	threshold         float64
+	minAmount         float64
+	newMerchantAmount float64
+	spikeRatio        float64
+}
+
+func newAlertsCmd() *cobra.Command {
+	var (
+		endDate    string
+		opts       alertOptions
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "alerts",
+		Short: "Flag unusual spending in a recent window (exits 2 when alerts fire)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			end := time.Now()
+			if endDate != "" {
+				parsed, err := time.Parse("2006-01-02", endDate)
+				if err != nil {
+					return fmt.Errorf("invalid --end %q (expected YYYY-MM-DD)", endDate)
+				}
+				end = parsed
+			}
+			if opts.days <= 0 || opts.historyDays <= 0 {
+				return errors.New("--days and --history-days must be positive")
+			}
+			if opts.historyDays < opts.days {
+				return errors.New("--history-days must be at least --days")
+			}
+			opts.recentStart = end.AddDate(0, 0, -(opts.days - 1))
+			historyStart := opts.recentStart.AddDate(0, 0, -opts.historyDays)
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			transactions, err := client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+				StartDate: historyStart.Format("2006-01-02"),
+				EndDate:   end.Format("2006-01-02"),
+				Limit:     1000,
+			})
+			if err != nil {
+				return err
+			}
+			lookups, err := loadTxLookups(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			alerts := findAlerts(transactions, lookups, opts)
+			if jsonOutput {
+				if err := printJSON(alerts); err != nil {
+					return err
+				}
+			} else if len(alerts) == 0 {
+				fmt.Println("No alerts.")
+			} else {
+				printAlerts(alerts)
+			}
+
+			if len(alerts) > 0 {
+				return &exitError{code: exitCodeAlerts, msg: fmt.Sprintf("%d alert(s) fired", len(alerts))}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&endDate, "end", "", "Last day of the recent window (YYYY-MM-DD), defaults to today")
+	cmd.Flags().IntVar(&opts.days, "days", 7, "Length of the recent window in days")
+	cmd.Flags().IntVar(&opts.historyDays, "history-days", 180, "Days of history before the window used for baselines")
+	cmd.Flags().Float64Var(&opts.threshold, "threshold", 3, "Deviations above a payee's typical charge that count as an outlier")
+	cmd.Flags().Float64Var(&opts.minAmount, "min-amount", 25, "Ignore outliers and spikes smaller than this (base currency)")
+	cmd.Flags().Float64Var(&opts.newMerchantAmount, "new-merchant-amount", 200, "Flag charges from payees with no history at or above this amount")
+	cmd.Flags().Float64Var(&opts.spikeRatio, "spike-ratio", 1.5, "Flag categories spending this many times their trailing average")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON")
+
+	return cmd
+}
+
+// findAlerts compares expenses in the recent window against baselines built
+// from the history before it. Transfers, income and categories excluded from
+// totals are ignored.
+func findAlerts(transactions []lunchmoney.Transaction, lookups txLookups, opts alertOptions) []alert {
+	views := lookups.views(transactions)
+	recentStart := opts.recentStart.Format("2006-01-02")
+	currency := lookups.baseCurrency
+	money := func(a lunchmoney.Amount) string { return formatAmountWithCurrency(a, currency) }
+
+	var (
+		spending        []lunchmoney.Transaction
+		baselineByPayee = make(map[string][]float64)
+		baselineByCat   = make(map[string]lunchmoney.Amount)
+		recentByCat     = make(map[string]lunchmoney.Amount)
+		recent          []int
+	)
+	for i, tx := range transactions {
+		if views[i].Type != txTypeExpense || shouldExcludeFromTotalsFilter(tx, lookups.categoryByID) {
+			continue
+		}
+		spending = append(spending, tx)
+		category := views[i].Category
+		if category == "" {
+			category = "Uncategorized"
+		}
+		if tx.Date < recentStart {
+			baselineByCat[category] += tx.ToBase
+			if tx.ToBase.Sign() > 0 {
+				key := cleanPayee(rawPayee(tx))
+				baselineByPayee[key] = append(baselineByPayee[key], tx.ToBase.Float64())
+			}
+			continue
+		}
+		recentByCat[category] += tx.ToBase
+		if tx.ToBase.Sign() > 0 {
+			recent = append(recent, i)
+		}
+	}
+
+	var alerts []alert
+	for _, i := range recent {
+		tx := transactions[i]
+		amount := tx.ToBase.Float64()
+		history := baselineByPayee[cleanPayee(rawPayee(tx))]
+		switch {
+		case len(history) == 0 && amount >= opts.newMerchantAmount:
+			alerts = append(alerts, alert{
+				Kind:          alertNewMerchant,
+				Date:          tx.Date,
+				Subject:       tx.Payee,
+				TransactionID: tx.ID,
+				Amount:        tx.ToBase,
+				Currency:      currency,
+				Message:       fmt.Sprintf("first charge from %s in %d days is %s", tx.Payee, opts.historyDays, money(tx.ToBase)),
+			})
+		case len(history) > 0 && len(history) < 3:
+			largest := slices.Max(history)
+			if amount >= fewChargesRatio*largest && amount-largest >= opts.minAmount {
+				baseline := lunchmoney.AmountFromFloat(largest)
+				alerts = append(alerts, alert{
+					Kind:          alertPayeeOutlier,
+					Date:          tx.Date,
+					Subject:       tx.Payee,
+					TransactionID: tx.ID,
+					Amount:        tx.ToBase,
+					Baseline:      baseline,
+					Currency:      currency,
+					Message:       fmt.Sprintf("%s at %s is %.1fx the largest of %d earlier charge(s), %s", money(tx.ToBase), tx.Payee, amount/largest, len(history), money(baseline)),
+				})
+			}
+		case len(history) >= 3:
+			median := medianFloat(history)
+			deviations := make([]float64, len(history))
+			for j, h := range history {
+				deviations[j] = math.Abs(h - median)
+			}
+			spread := math.Max(1.4826*medianFloat(deviations), 0.1*median)
+			if amount > median+opts.threshold*spread && amount >= 1.5*median && amount-median >= opts.minAmount {
+				baseline := lunchmoney.AmountFromFloat(median)
+				alerts = append(alerts, alert{
+					Kind:          alertPayeeOutlier,
+					Date:          tx.Date,
+					Subject:       tx.Payee,
+					TransactionID: tx.ID,
+					Amount:        tx.ToBase,
+					Baseline:      baseline,
+					Currency:      currency,
+					Message:       fmt.Sprintf("%s at %s is %.1fx the usual %s (median of %d charges)", money(tx.ToBase), tx.Payee, amount/median, money(baseline), len(history)),
+				})
+			}
+		}
+	}
+
+	for _, s := range detectRecurring(spending) {
+		last := s.last()
+		if s.income() || last.Date < recentStart {
+			continue
+		}
+		change := s.priceChange()
+		previous := s.previous().ToBase
+		if change.Sign() <= 0 || change.Float64() < 0.01*previous.Float64() {
+			continue
+		}
+		alerts = append(alerts, alert{
+			Kind:          alertPriceIncrease,
+			Date:          last.Date,
+			Subject:       s.payee,
+			TransactionID: last.ID,
+			Amount:        last.ToBase,
+			Baseline:      previous,
+			Currency:      currency,
+			Message:       fmt.Sprintf("%s %s charge went from %s to %s (+%.0f%%)", s.payee, s.cadence.name, money(previous), money(last.ToBase), 100*change.Float64()/previous.Float64()),
+		})
+	}
+
+	periods := float64(opts.historyDays) / float64(opts.days)
+	categories := make([]string, 0, len(recentByCat))
+	for c := range recentByCat {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	for _, c := range categories {
+		spent := recentByCat[c]
+		if baselineByCat[c].Sign() <= 0 {
+			continue
+		}
+		average := lunchmoney.AmountFromFloat(baselineByCat[c].Float64() / periods)
+		if spent.Float64() >= opts.spikeRatio*average.Float64() && (spent-average).Float64() >= opts.minAmount {
+			alerts = append(alerts, alert{
+				Kind:     alertCategorySpike,
+				Subject:  c,
+				Amount:   spent,
+				Baseline: average,
+				Currency: currency,
+				Message:  fmt.Sprintf("%s spent %s in the last %d days vs a %s average (%.1fx)", c, money(spent), opts.days, money(average), spent.Float64()/average.Float64()),
+			})
+		}
+	}
+
+	return alerts
+}
+
+func printAlerts(alerts []alert) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "KIND\tDATE\tSUBJECT\tID\tMESSAGE")
+	for _, a := range alerts {
+		id := ""
+		if a.TransactionID != 0 {
+			id = fmt.Sprint(a.TransactionID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", a.Kind, a.Date, a.Subject, id, a.Message)
+	}
+	_ = w.Flush()
+}
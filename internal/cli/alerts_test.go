@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func TestFindAlertsFewPriorCharges(t *testing.T) {
+	charge := func(id int64, date, payee string, amount float64) lunchmoney.Transaction {
+		a := lunchmoney.AmountFromFloat(amount)
+		return lunchmoney.Transaction{ID: id, Date: date, Payee: payee, Amount: a, ToBase: a, Currency: "usd", Status: "reviewed"}
+	}
+	txs := []lunchmoney.Transaction{
+		charge(1, "2025-02-10", "Corner Hardware", 10),
+		charge(2, "2025-03-25", "Corner Hardware", 900),
+		charge(3, "2025-01-05", "Bakery", 12),
+		charge(4, "2025-02-05", "Bakery", 14),
+		charge(5, "2025-03-26", "Bakery", 30),
+	}
+	opts := alertOptions{
+		recentStart:       time.Date(2025, 3, 22, 0, 0, 0, 0, time.UTC),
+		days:              7,
+		historyDays:       180,
+		threshold:         3,
+		minAmount:         25,
+		newMerchantAmount: 200,
+		spikeRatio:        1.5,
+	}
+
+	outliers := map[int64]alert{}
+	for _, a := range findAlerts(txs, txLookups{baseCurrency: "usd"}, opts) {
+		if a.Kind == alertPayeeOutlier {
+			outliers[a.TransactionID] = a
+		}
+	}
+	if a, ok := outliers[2]; !ok || a.Baseline != lunchmoney.AmountFromFloat(10) {
+		t.Errorf("$900 after one $10 charge: outliers = %+v, want 2 with a $10 baseline", outliers)
+	}
+	if _, ok := outliers[5]; ok {
+		t.Errorf("$30 after $12 and $14 flagged; it is under --min-amount above the largest")
+	}
+}
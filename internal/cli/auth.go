@@ -0,0 +1,330 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// savedKeyIdentity stands for whichever key `lm auth login` saved; login
+// and logout drop the caches made with it.
+const savedKeyIdentity = "saved"
+
+// currentKeyIdentity names the key a command will use without resolving
+// (and possibly prompting for) it: the key itself when it is in the
+// environment, the api_key_command, the --replay directory or
+// savedKeyIdentity.
+func currentKeyIdentity(ctx context.Context) string {
+	if replay := optionsFrom(ctx).replay; replay != "" {
+		return "replay:" + replay
+	}
+	if env := strings.TrimSpace(os.Getenv(lunchmoney.EnvAPIKey)); env != "" {
+		return "env:" + env
+	}
+	if cfg, err := loadConfig(); err == nil && cfg.APIKeyCommand != "" {
+		return "api_key_command:" + cfg.APIKeyCommand
+	}
+	return savedKeyIdentity
+}
+
+// keyFingerprint hashes the base URL and a key identity, so caches of one
+// budget's data (completion, the suggestion model) are not used with
+// another key.
+func keyFingerprint(identity string) string {
+	sum := sha256.Sum256([]byte(os.Getenv(lunchmoney.EnvBaseURL) + "\x00" + identity))
+	return hex.EncodeToString(sum[:8])
+}
+
+// clearSavedKeyCaches drops the caches made with the saved key when it
+// changes.
+func clearSavedKeyCaches() {
+	clearCompletionCache()
+	if path, err := suggestModelPath(keyFingerprint(savedKeyIdentity)); err == nil {
+		_ = os.Remove(path)
+	}
+}
+
+// keyProviders lists where lunchmoney.NewFromEnv looks for the API key
+// after LUNCHMONEY_API_KEY: the config's api_key_command if set, otherwise
+// the key saved by `lm auth login`. The list is built once per command and
+// each provider is asked at most once, so a passphrase or password manager
+// prompt is not repeated for every client a command makes.
+func keyProviders(opts *globalOptions) ([]lunchmoney.KeyProvider, error) {
+	if opts.keyProviders != nil {
+		return opts.keyProviders, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var providers []lunchmoney.KeyProvider
+	if cfg.APIKeyCommand != "" {
+		providers = append(providers, &onceKey{KeyProvider: commandKey{command: cfg.APIKeyCommand}})
+	} else {
+		stores, err := savedKeyStores(opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			providers = append(providers, &onceKey{KeyProvider: store})
+		}
+	}
+	opts.keyProviders = providers
+	return providers, nil
+}
+
+// onceKey remembers what its provider returned the first time.
+type onceKey struct {
+	lunchmoney.KeyProvider
+	once sync.Once
+	key  string
+	err  error
+}
+
+func (k *onceKey) APIKey() (string, error) {
+	k.once.Do(func() { k.key, k.err = k.KeyProvider.APIKey() })
+	return k.key, k.err
+}
+
+// commandKey runs api_key_command with sh and uses what it prints. Its
+// stderr is passed through so password managers can prompt.
+type commandKey struct {
+	command string
+}
+
+func (commandKey) Name() string { return "api_key_command" }
+
+func (k commandKey) APIKey() (string, error) {
+	cmd := exec.Command("sh", "-c", k.command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("api_key_command failed: %w", err)
+	}
+	key := strings.TrimSpace(string(out))
+	if key == "" {
+		return "", errors.New("api_key_command printed no API key")
+	}
+	return key, nil
+}
+
+// savedKeyStores lists where `lm auth login` may have saved the key, in the
+// order they are checked.
+func savedKeyStores(opts *globalOptions) ([]secretStore, error) {
+	var stores []secretStore
+	if keyringAvailable() {
+		stores = append(stores, keyringStore{})
+	}
+	prompt := askPassphrase
+	if opts.noPrompt {
+		prompt = func(bool) (string, error) {
+			return "", errors.New("the credentials file needs a passphrase, which cannot be asked for here")
+		}
+	}
+	file, err := newFileStore(prompt)
+	if err != nil {
+		return nil, err
+	}
+	return append(stores, file), nil
+}
+
+func askPassphrase(confirm bool) (string, error) {
+	if !confirm {
+		return readSecret("Passphrase for the credentials file: ")
+	}
+	passphrase, err := readSecret("New passphrase for the credentials file: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the passphrase cannot be empty")
+	}
+	again, err := readSecret("Repeat the passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+type authStatus struct {
+	Source string `json:"source"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Budget string `json:"budget"`
+}
+
+func newAuthCmd() *cobra.Command {
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Save, check and remove the API key",
+	}
+
+	var (
+		fromStdin bool
+		storeName string
+	)
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Validate an API key and save it in the keyring or an encrypted file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var store secretStore
+			file, err := newFileStore(askPassphrase)
+			if err != nil {
+				return err
+			}
+			switch storeName {
+			case "auto":
+				store = file
+				if keyringAvailable() {
+					store = keyringStore{}
+				}
+			case "keyring":
+				if !keyringAvailable() {
+					return errors.New("the Secret Service keyring is not available (it needs secret-tool and a D-Bus session)")
+				}
+				store = keyringStore{}
+			case "file":
+				store = file
+			default:
+				return fmt.Errorf("invalid --store %q (expected auto, keyring or file)", storeName)
+			}
+
+			var key string
+			if fromStdin {
+				raw, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return err
+				}
+				key = string(raw)
+			} else if key, err = readSecret("Lunch Money API key: "); err != nil {
+				return err
+			}
+			key = strings.TrimSpace(key)
+			if key == "" {
+				return errors.New("no API key given")
+			}
+
+			client, err := lunchmoney.New(key)
+			if err != nil {
+				return err
+			}
+			if err := optionsFrom(cmd.Context()).configureClient(client); err != nil {
+				return err
+			}
+			me, err := client.GetMe(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("the API key was not accepted: %w", err)
+			}
+
+			if err := store.set(key); err != nil {
+				return err
+			}
+			// Keep a single saved key: the keyring is checked first, so a
+			// key left there would shadow a new one in the file.
+			var old secretStore
+			if _, inKeyring := store.(keyringStore); inKeyring {
+				old = file
+			} else if keyringAvailable() {
+				old = keyringStore{}
+			}
+			if old != nil {
+				if err := old.remove(); err != nil && !errors.Is(err, lunchmoney.ErrNoAPIKey) {
+					fmt.Fprintf(os.Stderr, "warning: failed to remove the old key from %s: %v\n", old.Name(), err)
+				}
+			}
+
+			clearSavedKeyCaches()
+			fmt.Printf("Logged in to %s as %s (%s). API key saved in %s.\n", me.BudgetName, me.Name, me.Email, store.Name())
+			warnKeyOverrides()
+			return nil
+		},
+	}
+	loginCmd.Flags().BoolVar(&fromStdin, "stdin", false, "Read the API key from stdin instead of prompting")
+	loginCmd.Flags().StringVar(&storeName, "store", "auto", "Where to save the key: auto, keyring or file")
+	_ = loginCmd.RegisterFlagCompletionFunc("store", cobra.FixedCompletions([]cobra.Completion{"auto", "keyring", "file"}, cobra.ShellCompDirectiveNoFileComp))
+
+	var jsonOutput bool
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show where the API key comes from and who it belongs to",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			me, err := client.GetMe(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("API key from %s: %w", client.KeySource(), err)
+			}
+
+			status := authStatus{Source: client.KeySource(), Name: me.Name, Email: me.Email, Budget: me.BudgetName}
+			if jsonOutput {
+				return printJSON(status)
+			}
+			fmt.Printf("Logged in to %s as %s (%s).\n", status.Budget, status.Name, status.Email)
+			fmt.Printf("API key from %s.\n", status.Source)
+			return nil
+		},
+	}
+	statusCmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON")
+
+	logoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the saved API key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, err := savedKeyStores(optionsFrom(cmd.Context()))
+			if err != nil {
+				return err
+			}
+			removed := false
+			for _, store := range stores {
+				err := store.remove()
+				if errors.Is(err, lunchmoney.ErrNoAPIKey) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				removed = true
+				fmt.Printf("Removed the API key from %s.\n", store.Name())
+			}
+			if !removed {
+				fmt.Println("No saved API key.")
+			}
+			clearSavedKeyCaches()
+			warnKeyOverrides()
+			return nil
+		},
+	}
+
+	authCmd.AddCommand(loginCmd)
+	authCmd.AddCommand(statusCmd)
+	authCmd.AddCommand(logoutCmd)
+	return authCmd
+}
+
+// warnKeyOverrides points out key sources that take precedence over the
+// saved key.
+func warnKeyOverrides() {
+	if os.Getenv(lunchmoney.EnvAPIKey) != "" {
+		fmt.Fprintf(os.Stderr, "Note: %s is set and takes precedence over the saved key.\n", lunchmoney.EnvAPIKey)
+		return
+	}
+	if cfg, err := loadConfig(); err == nil && cfg.APIKeyCommand != "" {
+		fmt.Fprintln(os.Stderr, "Note: api_key_command is set in the config and takes precedence over the saved key.")
+	}
+}
@@ -1,7 +1,7 @@
 package cli
 
 import (
-	"context"
+	"fmt"
 	"sort"
 
 	"github.com/spf13/cobra"
@@ -21,12 +21,12 @@ func newCategoryCmd() *cobra.Command {
 		Use:   "list",
 		Short: "List categories",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			client, err := lunchmoney.NewFromEnv()
+			client, err := newClient(cmd.Context())
 			if err != nil {
 				return err
 			}
 
-			categories, err := client.ListCategories(context.Background())
+			categories, err := client.ListCategories(cmd.Context())
 			if err != nil {
 				return err
 			}
@@ -82,3 +82,53 @@ func toCategoryViews(categories []lunchmoney.Category) []categoryView {
 
 	return views
 }
+
+func toTagViews(tags []lunchmoney.Tag) []tagView {
+	views := make([]tagView, 0, len(tags))
+	for _, t := range tags {
+		views = append(views, tagView{ID: t.ID, Name: t.Name})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+// toAccountViews lists manual accounts before Plaid accounts, each sorted by
+// name, with the display names used in transaction views.
+func toAccountViews(manual []lunchmoney.ManualAccount, plaid []lunchmoney.PlaidAccount) []accountView {
+	views := make([]accountView, 0, len(manual)+len(plaid))
+	manualMeta := buildManualAccountLookup(manual)
+	for _, a := range manual {
+		views = append(views, accountView{
+			Key:         fmt.Sprintf("manual:%d", a.ID),
+			ID:          a.ID,
+			Source:      "manual",
+			Name:        manualMeta[a.ID].DisplayName,
+			Institution: manualMeta[a.ID].Institution,
+			Type:        a.Type,
+			Balance:     a.Balance,
+			Currency:    a.Currency,
+			Status:      a.Status,
+		})
+	}
+	plaidMeta := buildPlaidAccountLookup(plaid)
+	for _, a := range plaid {
+		views = append(views, accountView{
+			Key:         fmt.Sprintf("plaid:%d", a.ID),
+			ID:          a.ID,
+			Source:      "plaid",
+			Name:        plaidMeta[a.ID].DisplayName,
+			Institution: plaidMeta[a.ID].Institution,
+			Type:        a.Type,
+			Balance:     a.Balance,
+			Currency:    a.Currency,
+			Status:      a.Status,
+		})
+	}
+	sort.SliceStable(views, func(i, j int) bool {
+		if views[i].Source != views[j].Source {
+			return views[i].Source == "manual"
+		}
+		return views[i].Name < views[j].Name
+	})
+	return views
+}
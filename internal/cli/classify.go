@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const (
+	txTypeExpense  = "expense"
+	txTypeIncome   = "income"
+	txTypeTransfer = "transfer"
+)
+
+// classificationConfig controls how transactions are typed as income,
+// expense or transfer.
+type classificationConfig struct {
+	// Rules are checked in order; the first match decides the type.
+	Rules []classifyRule `json:"rules"`
+	// PairWindowDays enables transfer pairing: two transactions on different
+	// accounts with exactly opposite amounts within this many days are both
+	// treated as transfers. Zero disables pairing.
+	PairWindowDays int `json:"pair_window_days"`
+}
+
+// classifyRule assigns Type to transactions whose category matches every
+// selector that is set.
+type classifyRule struct {
+	Type        string  `json:"type"`
+	CategoryIDs []int64 `json:"category_ids,omitempty"`
+	Category    string  `json:"category,omitempty"`
+	Group       string  `json:"group,omitempty"`
+}
+
+// defaultClassifyRules preserves the historical behavior when no rules are
+// configured.
+var defaultClassifyRules = []classifyRule{
+	{Type: txTypeTransfer, Category: `^Payment, Transfer$`},
+}
+
+type txClassifier struct {
+	rules    []compiledClassifyRule
+	pairDays int
+}
+
+type compiledClassifyRule struct {
+	typ   string
+	ids   map[int64]bool
+	name  *regexp.Regexp
+	group string
+}
+
+func newTxClassifier(cfg classificationConfig) (txClassifier, error) {
+	rules := cfg.Rules
+	if len(rules) == 0 {
+		rules = defaultClassifyRules
+	}
+	if cfg.PairWindowDays < 0 {
+		return txClassifier{}, errors.New("classification.pair_window_days cannot be negative")
+	}
+
+	c := txClassifier{pairDays: cfg.PairWindowDays}
+	for i, r := range rules {
+		switch r.Type {
+		case txTypeExpense, txTypeIncome, txTypeTransfer:
+		default:
+			return txClassifier{}, fmt.Errorf("classification rule %d: invalid type %q (expected expense, income or transfer)", i+1, r.Type)
+		}
+		if len(r.CategoryIDs) == 0 && r.Category == "" && r.Group == "" {
+			return txClassifier{}, fmt.Errorf("classification rule %d: needs category_ids, category or group", i+1)
+		}
+
+		compiled := compiledClassifyRule{typ: r.Type, group: r.Group}
+		if len(r.CategoryIDs) > 0 {
+			compiled.ids = make(map[int64]bool, len(r.CategoryIDs))
+			for _, id := range r.CategoryIDs {
+				compiled.ids[id] = true
+			}
+		}
+		if r.Category != "" {
+			re, err := regexp.Compile("(?i)" + r.Category)
+			if err != nil {
+				return txClassifier{}, fmt.Errorf("classification rule %d: invalid category pattern: %w", i+1, err)
+			}
+			compiled.name = re
+		}
+		c.rules = append(c.rules, compiled)
+	}
+	return c, nil
+}
+
+func (r compiledClassifyRule) matches(categoryID int64, c categoryMeta) bool {
+	if r.ids != nil && !r.ids[categoryID] {
+		return false
+	}
+	if r.name != nil && !r.name.MatchString(c.Name) {
+		return false
+	}
+	if r.group != "" && !strings.EqualFold(r.group, c.Group) {
+		return false
+	}
+	return true
+}
+
+// classify applies, in order: the configured rules, transfer pairing, the
+// category's income flag, and finally the sign of the amount for
+// uncategorized transactions.
+func (c txClassifier) classify(tx lunchmoney.Transaction, categories map[int64]categoryMeta, paired bool) string {
+	var (
+		meta  categoryMeta
+		known bool
+	)
+	if tx.CategoryID != nil {
+		meta, known = categories[*tx.CategoryID]
+	}
+
+	if known {
+		for _, r := range c.rules {
+			if r.matches(*tx.CategoryID, meta) {
+				return r.typ
+			}
+		}
+	}
+	if paired {
+		return txTypeTransfer
+	}
+	if known {
+		if meta.IsIncome {
+			return txTypeIncome
+		}
+		return txTypeExpense
+	}
+	if tx.ToBase.Neg().Sign() > 0 {
+		return txTypeIncome
+	}
+	return txTypeExpense
+}
+
+// transferCandidate is a possible pair of transfer legs, as indexes into the
+// transactions passed to transferCandidates.
+type transferCandidate struct {
+	out, in int
+	gapDays int
+}
+
+// transferCandidates lists pairs of transactions on different accounts with
+// exactly opposite amounts in the same currency no more than windowDays
+// apart. Base amounts are not compared: each leg converts at its own day's
+// rate, so a transfer between two accounts in another currency would never
+// match. Transactions without an account (cash) are never paired.
+func transferCandidates(txs []lunchmoney.Transaction, windowDays int) []transferCandidate {
+	type leg struct {
+		idx     int
+		account string
+		date    time.Time
+	}
+	type amountKey struct {
+		currency string
+		amount   lunchmoney.Amount
+	}
+	byAmount := make(map[amountKey][]leg)
+	for i, tx := range txs {
+		account := transactionAccountKey(tx)
+		if account == "" || tx.Amount == 0 {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", tx.Date)
+		if err != nil {
+			continue
+		}
+		key := amountKey{strings.ToLower(tx.Currency), tx.Amount.Abs()}
+		byAmount[key] = append(byAmount[key], leg{idx: i, account: account, date: date})
+	}
+
+	var candidates []transferCandidate
+	for _, legs := range byAmount {
+		for a := 0; a < len(legs); a++ {
+			for b := a + 1; b < len(legs); b++ {
+				x, y := legs[a], legs[b]
+				if x.account == y.account || txs[x.idx].Amount != txs[y.idx].Amount.Neg() {
+					continue
+				}
+				gap := int(x.date.Sub(y.date).Hours() / 24)
+				if gap < 0 {
+					gap = -gap
+				}
+				if gap > windowDays {
+					continue
+				}
+				// In API sign convention a positive amount is money leaving.
+				out, in := x.idx, y.idx
+				if txs[out].Amount.Sign() < 0 {
+					out, in = in, out
+				}
+				candidates = append(candidates, transferCandidate{out: out, in: in, gapDays: gap})
+			}
+		}
+	}
+	return candidates
+}
+
+// findTransferPairs greedily pairs candidates, closest dates first, so each
+// transaction belongs to at most one pair. The result maps each paired
+// transaction ID to its partner.
+func findTransferPairs(txs []lunchmoney.Transaction, windowDays int) map[int64]int64 {
+	candidates := transferCandidates(txs, windowDays)
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].gapDays != candidates[j].gapDays {
+			return candidates[i].gapDays < candidates[j].gapDays
+		}
+		if a, b := txs[candidates[i].out].ID, txs[candidates[j].out].ID; a != b {
+			return a < b
+		}
+		return txs[candidates[i].in].ID < txs[candidates[j].in].ID
+	})
+
+	pairs := make(map[int64]int64)
+	for _, c := range candidates {
+		a, b := txs[c.out].ID, txs[c.in].ID
+		if _, taken := pairs[a]; taken {
+			continue
+		}
+		if _, taken := pairs[b]; taken {
+			continue
+		}
+		pairs[a] = b
+		pairs[b] = a
+	}
+	return pairs
+}
+
+func transactionAccountKey(tx lunchmoney.Transaction) string {
+	switch {
+	case tx.ManualAccountID != nil:
+		return fmt.Sprintf("manual:%d", *tx.ManualAccountID)
+	case tx.PlaidAccountID != nil:
+		return fmt.Sprintf("plaid:%d", *tx.PlaidAccountID)
+	}
+	return ""
+}
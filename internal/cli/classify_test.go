@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"testing"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func TestFindTransferPairs(t *testing.T) {
+	a, b, c := int64(1), int64(2), int64(3)
+	cad := func(id int64, date string, amount, toBase float64, account *int64) lunchmoney.Transaction {
+		return lunchmoney.Transaction{
+			ID: id, Date: date, Currency: "cad", ManualAccountID: account,
+			Amount: lunchmoney.AmountFromFloat(amount), ToBase: lunchmoney.AmountFromFloat(toBase),
+		}
+	}
+	// A CAD to CAD transfer in a USD budget: each leg converted at its own
+	// day's rate.
+	txs := []lunchmoney.Transaction{
+		cad(10, "2025-03-03", 500, 365.10, &a),
+		cad(11, "2025-03-04", -500, -364.80, &b),
+	}
+	if pairs := findTransferPairs(txs, 3); pairs[10] != 11 {
+		t.Fatalf("pairs = %v, want 10 and 11", pairs)
+	}
+
+	// One out-leg, two equally close in-legs: the lower ID wins every time.
+	txs = []lunchmoney.Transaction{
+		cad(20, "2025-03-03", 500, 365, &a),
+		cad(22, "2025-03-04", -500, -365, &c),
+		cad(21, "2025-03-04", -500, -365, &b),
+	}
+	for range 20 {
+		if pairs := findTransferPairs(txs, 3); pairs[20] != 21 {
+			t.Fatalf("pairs = %v, want 20 paired with 21", pairs)
+		}
+	}
+	if matches := detectTransfers(txs, txLookups{}, 3); len(matches) != 1 || matches[0].inTx.ID != 21 {
+		t.Fatalf("detectTransfers = %+v, want 20 matched with 21", matches)
+	}
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// globalOptions holds the values of persistent root flags and what they
+// set up. NewRootCmd makes one per root command and Execute hands it to
+// the command through its context; see optionsFrom.
+type globalOptions struct {
+	dryRun bool
+	trace  string
+	record string
+	replay string
+
+	// timeout bounds the whole command; 0 means no limit.
+	timeout time.Duration
+
+	// traceOut is the opened --trace destination, shared by every client
+	// a command creates. traceFile is set when it is a file, which Execute
+	// closes.
+	traceOut  io.Writer
+	traceFile *os.File
+	// dryRunOut is where dry-run clients print the writes they skip.
+	dryRunOut *dryRunLog
+	// cancelTimeout releases the --timeout context once the command ends.
+	cancelTimeout context.CancelFunc
+	// keyProviders are where the API key may come from; see keyProviders.
+	keyProviders []lunchmoney.KeyProvider
+	// noPrompt is set during shell completion, which must not block on a
+	// passphrase prompt.
+	noPrompt bool
+}
+
+type optionsKey struct{}
+
+func withOptions(ctx context.Context, opts *globalOptions) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// optionsFrom returns the options of the command running with ctx, or
+// defaults when ctx does not come from Execute (e.g. in tests).
+func optionsFrom(ctx context.Context) *globalOptions {
+	if ctx != nil {
+		if opts, ok := ctx.Value(optionsKey{}).(*globalOptions); ok {
+			return opts
+		}
+	}
+	return &globalOptions{}
+}
+
+// newClient builds an API client configured by the global flags. Commands
+// should use it instead of calling lunchmoney.New directly.
+func newClient(ctx context.Context) (*lunchmoney.Client, error) {
+	opts := optionsFrom(ctx)
+	var (
+		client *lunchmoney.Client
+		err    error
+	)
+	if opts.replay != "" {
+		client, err = lunchmoney.NewReplay(opts.replay)
+	} else {
+		var providers []lunchmoney.KeyProvider
+		if providers, err = keyProviders(opts); err == nil {
+			client, err = lunchmoney.NewFromEnv(providers...)
+		}
+		if errors.Is(err, lunchmoney.ErrNoAPIKey) {
+			err = fmt.Errorf("no API key: run `lm auth login`, set api_key_command in %s, or set %s", configFile, lunchmoney.EnvAPIKey)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.configureClient(client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// configureClient applies the global flags to client.
+func (opts *globalOptions) configureClient(client *lunchmoney.Client) error {
+	if opts.timeout > 0 {
+		// Let one request run as long as the whole command may.
+		client.SetTimeout(opts.timeout)
+	}
+	if opts.dryRun {
+		if opts.dryRunOut == nil {
+			opts.dryRunOut = &dryRunLog{w: os.Stderr}
+		}
+		client.SetDryRun(opts.dryRunOut)
+	}
+	if opts.trace != "" {
+		out, err := opts.traceOutput()
+		if err != nil {
+			return err
+		}
+		client.SetTrace(out)
+	}
+	if opts.record != "" {
+		if err := client.SetRecord(opts.record); err != nil {
+			return fmt.Errorf("invalid --record directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// traceOutput opens the --trace destination once: "-" is stderr, anything
+// else a file that traces are appended to.
+func (opts *globalOptions) traceOutput() (io.Writer, error) {
+	if opts.traceOut != nil {
+		return opts.traceOut, nil
+	}
+	if opts.trace == "-" {
+		opts.traceOut = os.Stderr
+		return opts.traceOut, nil
+	}
+	f, err := os.OpenFile(opts.trace, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --trace file: %w", err)
+	}
+	opts.traceOut, opts.traceFile = f, f
+	return f, nil
+}
+
+// finish releases what the command set up: the --timeout context and the
+// --trace file.
+func (opts *globalOptions) finish() error {
+	if opts.cancelTimeout != nil {
+		opts.cancelTimeout()
+	}
+	if opts.traceFile != nil {
+		return opts.traceFile.Close()
+	}
+	return nil
+}
+
+// dryRunLog remembers whether a dry run skipped any write, so read-only
+// commands do not claim that changes were held back.
+type dryRunLog struct {
+	w     io.Writer
+	wrote bool
+}
+
+func (l *dryRunLog) Write(p []byte) (int, error) {
+	l.wrote = true
+	return l.w.Write(p)
+}
@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const (
+	completionCacheFile = "completion.json"
+	// completionCacheTTL keeps repeated tabs off the network while still
+	// picking up changes made elsewhere within minutes.
+	completionCacheTTL = 5 * time.Minute
+	// completionTimeout bounds a refresh so a slow API cannot hang the shell.
+	completionTimeout = 5 * time.Second
+	// maxCompletionTransactions caps the unreviewed transactions offered.
+	maxCompletionTransactions = 200
+)
+
+// unreviewedCompletionDays is how far back unreviewed transactions are
+// offered for completion. It is a variable so tests can reach fixture dates.
+var unreviewedCompletionDays = 90
+
+// completionData is what dynamic completion suggests. It is cached in the
+// cache directory for completionCacheTTL, keyed by the API it came from.
+type completionData struct {
+	Source         string           `json:"source"`
+	FetchedAt      time.Time        `json:"fetched_at"`
+	Unreviewed     []completionItem `json:"unreviewed"`
+	Categories     []completionItem `json:"categories"`
+	Tags           []completionItem `json:"tags"`
+	ManualAccounts []completionItem `json:"manual_accounts"`
+	PlaidAccounts  []completionItem `json:"plaid_accounts"`
+}
+
+type completionItem struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// completionSource identifies the API and key suggestions come from; see
+// keyFingerprint.
+func completionSource(ctx context.Context) string {
+	return keyFingerprint(currentKeyIdentity(ctx))
+}
+
+func completionCachePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, completionCacheFile), nil
+}
+
+// clearCompletionCache drops cached suggestions after lm changes data, e.g.
+// so transactions just marked reviewed are no longer offered.
+func clearCompletionCache() {
+	if path, err := completionCachePath(); err == nil {
+		_ = os.Remove(path)
+	}
+}
+
+// loadCompletionData returns cached suggestions while they are fresh and
+// fetches them again otherwise. It never prompts: a key that needs a
+// passphrase yields no suggestions until another command fills the cache.
+func loadCompletionData(cmd *cobra.Command) (completionData, error) {
+	path, err := completionCachePath()
+	if err != nil {
+		return completionData{}, err
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	source := completionSource(ctx)
+	if raw, err := os.ReadFile(path); err == nil {
+		var data completionData
+		if json.Unmarshal(raw, &data) == nil && data.Source == source && time.Since(data.FetchedAt) < completionCacheTTL {
+			return data, nil
+		}
+	}
+
+	opts := optionsFrom(ctx)
+	opts.noPrompt = true
+	ctx = withOptions(ctx, opts)
+	client, err := newClient(ctx)
+	if err != nil {
+		return completionData{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	data, err := fetchCompletionData(ctx, client)
+	if err != nil {
+		return completionData{}, err
+	}
+	data.Source = source
+	data.FetchedAt = time.Now()
+	if raw, err := json.Marshal(data); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err == nil {
+			_ = os.WriteFile(path, raw, 0o600)
+		}
+	}
+	return data, nil
+}
+
+func fetchCompletionData(ctx context.Context, client *lunchmoney.Client) (completionData, error) {
+	var (
+		lookups txLookups
+		txs     []lunchmoney.Transaction
+	)
+	end := time.Now()
+	params := lunchmoney.ListTransactionsParams{
+		StartDate: end.AddDate(0, 0, -unreviewedCompletionDays).Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Status:    "unreviewed",
+	}
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		lookups, err = loadTxLookups(ctx, client)
+		return err
+	})
+	g.Go(func() (err error) {
+		txs, err = client.ListTransactions(ctx, params)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return completionData{}, err
+	}
+
+	var data completionData
+	// Newest first: those are the ones most likely being worked on.
+	slices.Reverse(txs)
+	for _, tx := range txs[:min(len(txs), maxCompletionTransactions)] {
+		v := lookups.view(tx)
+		data.Unreviewed = append(data.Unreviewed, completionItem{
+			ID:     tx.ID,
+			Name:   v.Description,
+			Detail: fmt.Sprintf("%s %s %s", v.Date, v.Description, formatAmountWithCurrency(v.Amount, v.Currency)),
+		})
+	}
+	for _, c := range lookups.categories {
+		if c.IsGroup || c.Archived {
+			continue
+		}
+		detail := ""
+		if meta, ok := lookups.categoryByID[c.ID]; ok && meta.Group != "" {
+			detail = meta.Group
+		}
+		data.Categories = append(data.Categories, completionItem{ID: c.ID, Name: c.Name, Detail: detail})
+	}
+	for _, t := range lookups.tags {
+		data.Tags = append(data.Tags, completionItem{ID: t.ID, Name: t.Name})
+	}
+	data.ManualAccounts = accountCompletions(lookups.manual)
+	data.PlaidAccounts = accountCompletions(lookups.plaid)
+	return data, nil
+}
+
+func accountCompletions(accounts map[int64]accountMeta) []completionItem {
+	items := make([]completionItem, 0, len(accounts))
+	for id, a := range accounts {
+		items = append(items, completionItem{ID: id, Name: a.DisplayName, Detail: a.Institution})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items
+}
+
+// completeWith adapts a function that picks suggestions from the cached
+// data into a cobra completion function. Errors are only logged for
+// `lm __complete` debugging, leaving the shell without suggestions.
+func completeWith(pick func(data completionData, args []string, toComplete string) []cobra.Completion) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		data, err := loadCompletionData(cmd)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return pick(data, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeUnreviewedIDs suggests unreviewed transaction IDs not already on
+// the command line, described by date, payee and amount. With single set,
+// only the first argument is completed.
+func completeUnreviewedIDs(single bool) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if single && len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeWith(func(data completionData, args []string, toComplete string) []cobra.Completion {
+			var out []cobra.Completion
+			for _, item := range data.Unreviewed {
+				id := strconv.FormatInt(item.ID, 10)
+				if slices.Contains(args, id) || !strings.HasPrefix(id, toComplete) {
+					continue
+				}
+				out = append(out, cobra.CompletionWithDesc(id, item.Detail))
+			}
+			return out
+		})(cmd, args, toComplete)
+	}
+}
+
+// completeIDs suggests the IDs of items, described by their names.
+func completeIDs(items func(completionData) []completionItem) cobra.CompletionFunc {
+	return completeWith(func(data completionData, args []string, toComplete string) []cobra.Completion {
+		var out []cobra.Completion
+		for _, item := range items(data) {
+			id := strconv.FormatInt(item.ID, 10)
+			if strings.HasPrefix(id, toComplete) {
+				out = append(out, cobra.CompletionWithDesc(id, completionDesc(item)))
+			}
+		}
+		return out
+	})
+}
+
+// completeNames suggests the names of items matching toComplete without
+// regard to case. With list set the flag takes comma-separated values and
+// only the part after the last comma is completed.
+func completeNames(items func(completionData) []completionItem, list bool) cobra.CompletionFunc {
+	return completeWith(func(data completionData, args []string, toComplete string) []cobra.Completion {
+		prefix := ""
+		if list {
+			if i := strings.LastIndex(toComplete, ","); i >= 0 {
+				prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+			}
+		}
+		var out []cobra.Completion
+		for _, item := range items(data) {
+			if strings.HasPrefix(strings.ToLower(item.Name), strings.ToLower(toComplete)) {
+				out = append(out, cobra.CompletionWithDesc(prefix+item.Name, item.Detail))
+			}
+		}
+		return out
+	})
+}
+
+func completionDesc(item completionItem) string {
+	if item.Detail == "" {
+		return item.Name
+	}
+	return item.Name + " (" + item.Detail + ")"
+}
+
+func completionCategories(d completionData) []completionItem { return d.Categories }
+func completionTags(d completionData) []completionItem       { return d.Tags }
+func completionManual(d completionData) []completionItem     { return d.ManualAccounts }
+func completionPlaid(d completionData) []completionItem      { return d.PlaidAccounts }
+
+func completionAccounts(d completionData) []completionItem {
+	var items []completionItem
+	for _, a := range d.ManualAccounts {
+		items = append(items, completionItem{ID: a.ID, Name: a.Name, Detail: fmt.Sprintf("manual:%d", a.ID)})
+	}
+	for _, a := range d.PlaidAccounts {
+		items = append(items, completionItem{ID: a.ID, Name: a.Name, Detail: fmt.Sprintf("plaid:%d", a.ID)})
+	}
+	return items
+}
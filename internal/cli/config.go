@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const configFile = "config.json"
+
+// config is the optional user configuration read from <config dir>/config.json.
+type config struct {
+	Classification classificationConfig `json:"classification"`
+	PayeeAliases   []payeeAlias         `json:"payee_aliases"`
+	// APIKeyCommand is a shell command that prints the API key, e.g. a
+	// password manager lookup.
+	APIKeyCommand string `json:"api_key_command"`
+}
+
+func configPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFile), nil
+}
+
+// loadConfig reads the config file. A missing file yields the zero config.
+func loadConfig() (config, error) {
+	path, err := configPath()
+	if err != nil {
+		return config{}, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config{}, nil
+	}
+	if err != nil {
+		return config{}, err
+	}
+
+	var cfg config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/mockapi"
+)
+
+func newDevCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Tools for developing against lm",
+	}
+	cmd.AddCommand(newDevMockServerCmd())
+	return cmd
+}
+
+func newDevMockServerCmd() *cobra.Command {
+	var (
+		addr     string
+		fixtures string
+		pageSize int
+		latency  time.Duration
+		apiKey   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run an in-memory fake of the Lunch Money v2 API seeded from fixtures",
+		Long: `Run an in-memory fake of the Lunch Money v2 API seeded from fixtures.
+
+Point lm at it with the exported variables it prints. Writes change only the
+in-memory copy; restart the server to reset. --fixtures takes a directory of
+API response bodies (me.json, categories.json, tags.json,
+manual_accounts.json, plaid_accounts.json, recurring_items.json,
+transactions.json); without it a built-in sample budget is used.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pageSize < 0 {
+				return errors.New("--page-size cannot be negative")
+			}
+			if latency < 0 {
+				return errors.New("--latency cannot be negative")
+			}
+			fixture := mockapi.DefaultFixture()
+			if fixtures != "" {
+				var err error
+				if fixture, err = mockapi.LoadFixture(fixtures); err != nil {
+					return err
+				}
+			}
+			server := mockapi.New(fixture)
+			server.APIKey = apiKey
+			server.PageSize = pageSize
+			server.Latency = latency
+
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("export LUNCHMONEY_BASE_URL=http://%s/v2\n", listener.Addr())
+			fmt.Printf("export LUNCHMONEY_API_KEY=%s\n", apiKey)
+
+			return serveHTTP(cmd.Context(), listener, server)
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8081", "Address to listen on")
+	cmd.Flags().StringVar(&fixtures, "fixtures", "", "Fixture directory (defaults to the built-in sample budget)")
+	cmd.Flags().IntVar(&pageSize, "page-size", 0, "Cap transactions per page to exercise pagination (0 for no cap)")
+	cmd.Flags().DurationVar(&latency, "latency", 0, "Delay every response by this long, e.g. 50ms")
+	cmd.Flags().StringVar(&apiKey, "api-key", mockapi.DefaultAPIKey, "Bearer token the server accepts")
+
+	return cmd
+}
@@ -0,0 +1,1262 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+	"lunchmoney-cli/internal/mockapi"
+)
+
+// e2e runs lm commands in-process against a fresh mock API with its own
+// config directory. Commands share process state (os.Stdout, os.Args), so
+// these tests must not run in parallel.
+type e2e struct {
+	t   *testing.T
+	api *mockapi.Server
+	// ctx is the context commands run in, as main's signal context.
+	ctx context.Context
+	// root is the command tree of the last run.
+	root *cobra.Command
+}
+
+func newE2E(t *testing.T) *e2e {
+	t.Helper()
+	api := mockapi.New(mockapi.DefaultFixture())
+	ts := httptest.NewServer(api)
+	t.Cleanup(ts.Close)
+
+	t.Setenv("LUNCHMONEY_BASE_URL", ts.URL+"/v2")
+	t.Setenv("LUNCHMONEY_API_KEY", mockapi.DefaultAPIKey)
+	t.Setenv(envConfigDir, t.TempDir())
+	t.Setenv(envCacheDir, t.TempDir())
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "") // keep the host's keyring out
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	return &e2e{t: t, api: api, ctx: context.Background()}
+}
+
+type result struct {
+	stdout string
+	stderr string
+	err    error
+}
+
+// run executes lm with args and no stdin.
+func (e *e2e) run(args ...string) result {
+	e.t.Helper()
+	return e.runWithInput("", args...)
+}
+
+// runWithInput executes lm with args, feeding input on stdin.
+func (e *e2e) runWithInput(input string, args ...string) result {
+	e.t.Helper()
+	dir := e.t.TempDir()
+	stdin := writeTempFile(e.t, dir, "stdin", input)
+	stdout := createTempFile(e.t, dir, "stdout")
+	stderr := createTempFile(e.t, dir, "stderr")
+
+	origIn, origOut, origErr, origArgs := os.Stdin, os.Stdout, os.Stderr, os.Args
+	os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr
+	os.Args = append([]string{"lm"}, args...)
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr, os.Args = origIn, origOut, origErr, origArgs
+		stdin.Close()
+		stdout.Close()
+		stderr.Close()
+	}()
+
+	e.root = NewRootCmd()
+	e.root.SetArgs(args)
+	err := Execute(e.ctx, e.root)
+	return result{stdout: readTempFile(e.t, stdout), stderr: readTempFile(e.t, stderr), err: err}
+}
+
+// ok is run that fails the test on error.
+func (e *e2e) ok(args ...string) string {
+	e.t.Helper()
+	r := e.run(args...)
+	if r.err != nil {
+		e.t.Fatalf("lm %s: %v\nstderr:\n%s", strings.Join(args, " "), r.err, r.stderr)
+	}
+	return r.stdout
+}
+
+// okJSON runs a command and decodes its stdout into v.
+func (e *e2e) okJSON(v any, args ...string) {
+	e.t.Helper()
+	out := e.ok(args...)
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		e.t.Fatalf("lm %s: decoding output: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func writeTempFile(t *testing.T, dir, name, content string) *os.File {
+	t.Helper()
+	path := dir + "/" + name
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func createTempFile(t *testing.T, dir, name string) *os.File {
+	t.Helper()
+	f, err := os.Create(dir + "/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func readTempFile(t *testing.T, f *os.File) string {
+	t.Helper()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func assertContains(t *testing.T, got string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("output missing %q:\n%s", w, got)
+		}
+	}
+}
+
+func TestE2ETxList(t *testing.T) {
+	e := newE2E(t)
+
+	out := e.ok("tx", "list", "--start", "2025-03-01", "--end", "2025-03-31")
+	assertContains(t, out, "1040  Apple Store", "-1299.00", "2500.00", "Oak Street Apartments")
+	if strings.Contains(out, "1041") {
+		t.Errorf("reviewed list includes unreviewed 1041:\n%s", out)
+	}
+
+	var views []transactionView
+	e.okJSON(&views, "tx", "list", "--start", "2025-03-01", "--end", "2025-03-31", "--unreviewed", "--json")
+	if len(views) != 5 || views[0].ID != 1041 {
+		t.Fatalf("unreviewed = %+v, want 5 starting with 1041", views)
+	}
+
+	out = e.ok("tx", "list", "--start", "2025-03-01", "--end", "2025-03-31", "--unreviewed", "--include-pending")
+	assertContains(t, out, "1042", "Shell Oil")
+
+	if r := e.run("tx", "list", "--start", "2025-04-01", "--end", "2025-03-01"); r.err == nil {
+		t.Error("expected an error for an inverted date range")
+	}
+}
+
+func TestE2ETxListColumns(t *testing.T) {
+	e := newE2E(t)
+	march := []string{"tx", "list", "--start", "2025-03-01", "--end", "2025-03-31"}
+
+	out := e.ok(append(march, "--columns", "date,payee,amount,type", "--sort", "amount", "--max-width", "8")...)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if !strings.HasPrefix(lines[0], "DATE        DESCRIPTION") || strings.Contains(lines[0], "CATEGORY") {
+		t.Errorf("header = %q, want only the chosen columns", lines[0])
+	}
+	assertContains(t, lines[1], "Oak Str…", "-1800.00", "expense")
+	assertContains(t, lines[len(lines)-1], "ACME Co…", "2500.00", "income")
+	// Amounts are right-aligned, so they end in the same place.
+	if strings.Index(lines[0], "AMOUNT")+6 != strings.Index(lines[6], "-17.99")+6 {
+		t.Errorf("amounts not right-aligned:\n%s", out)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("colored output to a pipe:\n%s", out)
+	}
+
+	var views []transactionView
+	e.okJSON(&views, append(march, "--sort", "amount", "--reverse", "--json")...)
+	if len(views) != 9 || views[0].Amount < views[8].Amount {
+		t.Errorf("--sort amount --reverse = %+v, want largest first", views)
+	}
+
+	out = e.ok(append(march, "--sort", "id", "--template", "{{.ID}} {{upper .Description}} {{money .Amount .Currency}}")...)
+	assertContains(t, out, "1040 APPLE STORE -1299.00 USD\n")
+	if !strings.HasPrefix(out, "1008 ") {
+		t.Errorf("template output not sorted by id:\n%s", out)
+	}
+
+	out = e.ok(append(march, "--color", "always")...)
+	assertContains(t, out, "\x1b[31m-1299.00\x1b[0m")
+	out = e.ok("tx", "list", "--start", "2025-03-01", "--end", "2025-03-31", "--unreviewed", "--include-pending", "--color", "always")
+	assertContains(t, out, "\x1b[2mShell Oil")
+
+	for _, args := range [][]string{
+		{"--columns", "date,bogus"},
+		{"--sort", "bogus"},
+		{"--color", "sometimes"},
+		{"--max-width", "-1"},
+		{"--template", "{{.Date"},
+		{"--template", "{{.Date}}", "--json"},
+		{"--columns", "date", "--format", "csv"},
+		{"--sort", "amount", "--format", "ndjson"},
+	} {
+		if r := e.run(append(march, args...)...); r.err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestE2ETxListPaginates(t *testing.T) {
+	e := newE2E(t)
+	e.api.PageSize = 3
+
+	var views []transactionView
+	e.okJSON(&views, "tx", "list", "--start", "2024-12-01", "--end", "2025-03-31", "--json")
+	if len(views) != 36 {
+		t.Fatalf("got %d transactions across pages, want 36", len(views))
+	}
+	pages := 0
+	for _, req := range e.api.Requests() {
+		if strings.HasPrefix(req, "GET /transactions?") {
+			pages++
+		}
+	}
+	if pages < 12 {
+		t.Errorf("fetched %d pages, want at least 12", pages)
+	}
+}
+
+func TestE2ETxUpdate(t *testing.T) {
+	e := newE2E(t)
+
+	e.ok("tx", "update", "1041", "--category", "Groceries", "--note", "weekly shop", "--add-tags", "work")
+	tx, _ := e.api.Transaction(1041)
+	if tx.CategoryID == nil || *tx.CategoryID != 2 || tx.Notes == nil || *tx.Notes != "weekly shop" || len(tx.TagIDs) != 1 || tx.TagIDs[0] != 3 {
+		t.Fatalf("after update: category=%v notes=%v tags=%v", tx.CategoryID, tx.Notes, tx.TagIDs)
+	}
+
+	if r := e.run("tx", "update", "1041", "--category", "Nope"); r.err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+	if r := e.run("tx", "update", "999999", "--note", "x"); r.err == nil {
+		t.Error("expected an error for a missing transaction")
+	}
+
+	r := e.run("--dry-run", "tx", "update", "1041", "--note", "changed")
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	assertContains(t, r.stderr, "PUT", "/transactions/1041", "Dry run")
+	if r := e.run("--dry-run", "tx", "list", "--start", "2025-03-01"); r.err != nil || strings.Contains(r.stderr, "Dry run") {
+		t.Errorf("read-only dry run: err = %v, stderr:\n%s", r.err, r.stderr)
+	}
+	if r := e.run("--dry-run", "tx", "update", "1041", "--category", "Nope"); r.err == nil || strings.Contains(r.stderr, "Dry run") {
+		t.Errorf("failed dry run before any write: err = %v, stderr:\n%s", r.err, r.stderr)
+	}
+	if tx, _ := e.api.Transaction(1041); *tx.Notes != "weekly shop" {
+		t.Errorf("dry run changed notes to %q", *tx.Notes)
+	}
+
+	before := len(e.api.Requests())
+	if r := e.run("tx", "update", "1041", "--dry-run"); r.err == nil || !strings.Contains(r.err.Error(), "at least one field") {
+		t.Errorf("update with only global flags: err = %v", r.err)
+	}
+	if got := len(e.api.Requests()); got != before {
+		t.Errorf("update with no fields made %d requests", got-before)
+	}
+}
+
+func TestE2ETxMarkReviewed(t *testing.T) {
+	e := newE2E(t)
+
+	e.ok("tx", "mark-reviewed", "1038", "1039")
+	for _, id := range []int64{1038, 1039} {
+		if tx, _ := e.api.Transaction(id); tx.Status != "reviewed" {
+			t.Errorf("transaction %d status = %q, want reviewed", id, tx.Status)
+		}
+	}
+}
+
+func TestE2ETxApply(t *testing.T) {
+	e := newE2E(t)
+
+	input := "id,category,notes\n1041,Groceries,from csv\n1038,Coffee Shops,\n"
+	out := e.runWithInput(input, "tx", "apply", "--format", "csv", "--yes")
+	if out.err != nil {
+		t.Fatalf("apply: %v\n%s", out.err, out.stderr)
+	}
+	tx, _ := e.api.Transaction(1041)
+	if tx.CategoryID == nil || *tx.CategoryID != 2 || tx.Notes == nil || *tx.Notes != "from csv" {
+		t.Fatalf("1041 after apply: category=%v notes=%v", tx.CategoryID, tx.Notes)
+	}
+	if tx, _ := e.api.Transaction(1038); tx.CategoryID == nil || *tx.CategoryID != 4 {
+		t.Fatalf("1038 after apply: category=%v", tx.CategoryID)
+	}
+
+	bad := e.runWithInput("id,category\n1041,Nope\n", "tx", "apply", "--format", "csv", "--yes")
+	if bad.err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+
+	// The dry-run diff reuses the transactions fetched for the plan.
+	before := len(e.api.Requests())
+	dry := e.runWithInput("id,notes\n1041,dry\n1038,dry\n", "--dry-run", "tx", "apply", "--format", "csv")
+	if dry.err != nil {
+		t.Fatalf("dry-run apply: %v\n%s", dry.err, dry.stderr)
+	}
+	assertContains(t, dry.stderr, `transaction 1041 notes: "from csv" -> "dry"`, "Dry run: no changes were sent.")
+	fetches := map[string]int{}
+	for _, req := range e.api.Requests()[before:] {
+		if strings.HasPrefix(req, "GET /transactions/") {
+			fetches[req]++
+		}
+	}
+	for req, n := range fetches {
+		if n > 1 {
+			t.Errorf("dry-run apply sent %s %d times, want once", req, n)
+		}
+	}
+}
+
+func TestE2ETxEdit(t *testing.T) {
+	e := newE2E(t)
+	t.Setenv("EDITOR", `sed -i 's/Whole Foods/Whole Foods Market/'`)
+
+	e.ok("tx", "edit", "1041", "--yes")
+	if tx, _ := e.api.Transaction(1041); tx.Payee != "Whole Foods Market" {
+		t.Fatalf("payee = %q, want Whole Foods Market", tx.Payee)
+	}
+
+	t.Setenv("EDITOR", "true")
+	assertContains(t, e.ok("tx", "edit", "1041", "--yes"), "Nothing to change.")
+}
+
+func TestE2ETxDuplicates(t *testing.T) {
+	e := newE2E(t)
+
+	out := e.ok("tx", "duplicates", "--start", "2025-03-01", "--end", "2025-03-31")
+	assertContains(t, out, "keep    1038", "delete  1039", "1 cluster(s)")
+
+	e.ok("tx", "duplicates", "--start", "2025-03-01", "--end", "2025-03-31", "--delete", "--yes")
+	if _, ok := e.api.Transaction(1039); ok {
+		t.Error("duplicate 1039 was not deleted")
+	}
+	if _, ok := e.api.Transaction(1038); !ok {
+		t.Error("kept transaction 1038 was deleted")
+	}
+
+	var entries []journalEntry
+	e.okJSON(&entries, "history", "--json")
+	if len(entries) != 1 || len(entries[0].Changes) != 1 {
+		t.Fatalf("journal = %+v, want one delete", entries)
+	}
+	if c := entries[0].Changes[0]; c.Action != journalDelete || c.TxID != 1039 || c.Before.Payee != "BLUE BOTTLE COFFEE #12" {
+		t.Errorf("delete change = %+v", c)
+	}
+	assertContains(t, e.ok("history", "1"), "1039", "(deleted)", "BLUE BOTTLE COFFEE #12")
+	if r := e.run("undo", "--yes"); r.err == nil || !strings.Contains(r.err.Error(), "cannot be restored") {
+		t.Errorf("undo of a delete: err = %v", r.err)
+	}
+}
+
+func TestE2ETxSuggest(t *testing.T) {
+	e := newE2E(t)
+
+	out := e.ok("tx", "suggest", "--start", "2025-03-01", "--end", "2025-03-31", "--history-start", "2024-12-01")
+	assertContains(t, out, "1041", "Groceries", "Coffee Shops")
+
+	e.ok("tx", "suggest", "--start", "2025-03-01", "--end", "2025-03-31", "--history-start", "2024-12-01", "--apply", "--yes")
+	if tx, _ := e.api.Transaction(1041); tx.CategoryID == nil || *tx.CategoryID != 2 {
+		t.Errorf("1041 category = %v, want Groceries", tx.CategoryID)
+	}
+	if tx, _ := e.api.Transaction(1036); tx.CategoryID != nil {
+		t.Errorf("low-confidence suggestion for 1036 was applied: %v", *tx.CategoryID)
+	}
+
+	// A different --history-start retrains the fresh cached model.
+	e.ok("tx", "suggest", "--start", "2025-03-01", "--end", "2025-03-31", "--history-start", "2025-02-01", "--json")
+	if model, err := loadSuggestModel(e.ctx); err != nil || model == nil || model.Start != "2025-02-01" {
+		t.Errorf("cached model after --history-start 2025-02-01 = %+v, %v", model, err)
+	}
+
+	// Another API key is another budget and does not see this model.
+	t.Setenv(lunchmoney.EnvAPIKey, "other-budget-key")
+	if model, err := loadSuggestModel(e.ctx); err != nil || model != nil {
+		t.Errorf("cached model for another key = %+v, %v; want none", model, err)
+	}
+}
+
+func TestE2ECategoryList(t *testing.T) {
+	e := newE2E(t)
+
+	out := e.ok("category", "list")
+	assertContains(t, out, "Groceries", "Payment, Transfer")
+	if strings.Contains(out, "Old Stuff") {
+		t.Errorf("archived category listed:\n%s", out)
+	}
+
+	var cats []categoryView
+	e.okJSON(&cats, "category", "list", "--json")
+	if len(cats) != 10 {
+		t.Errorf("got %d categories, want 10", len(cats))
+	}
+}
+
+func TestE2ETransfersDetect(t *testing.T) {
+	e := newE2E(t)
+
+	var matches []struct {
+		Out   transactionView `json:"out"`
+		In    transactionView `json:"in"`
+		Score float64         `json:"score"`
+	}
+	e.okJSON(&matches, "transfers", "detect", "--start", "2025-03-01", "--end", "2025-03-31", "--json")
+	if len(matches) != 1 || matches[0].Out.ID != 1036 || matches[0].In.ID != 1037 {
+		t.Fatalf("matches = %+v, want 1036 -> 1037", matches)
+	}
+
+	r := e.run("--dry-run", "transfers", "detect", "--start", "2025-03-01", "--end", "2025-03-31")
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	assertContains(t, r.stdout, "Would group 1036 and 1037")
+	assertContains(t, r.stderr, "POST /v2/transactions/group", "transaction 1036 (Payment to Chase Sapphire", "Dry run: no changes were sent.")
+	if _, ok := e.api.Transaction(1036); !ok {
+		t.Fatal("dry run grouped the transfer legs")
+	}
+
+	r = e.run("transfers", "detect", "--start", "2025-03-01", "--end", "2025-03-31", "--category", "Nope", "--yes")
+	if r.err == nil || r.stdout != "" {
+		t.Errorf("unknown --category: err = %v, stdout:\n%s", r.err, r.stdout)
+	}
+
+	legs := e.api.Transactions()
+	e.ok("transfers", "detect", "--start", "2025-03-01", "--end", "2025-03-31", "--yes")
+	if _, ok := e.api.Transaction(1036); ok {
+		t.Error("transfer legs were not grouped")
+	}
+	var entries []journalEntry
+	e.okJSON(&entries, "history", "--json")
+	if len(entries) != 1 {
+		t.Fatalf("journal = %+v, want one entry", entries)
+	}
+	last := entries[0].Changes[len(entries[0].Changes)-1]
+	if last.Action != journalGroup || len(last.Children) != 2 || last.Children[0] != 1036 || last.Children[1] != 1037 || last.TxID == 0 {
+		t.Errorf("group change = %+v", last)
+	}
+	assertContains(t, e.ok("history", "1"), "(group)", "1036, 1037")
+
+	r = e.run("--dry-run", "undo", "--yes")
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	assertContains(t, r.stderr, fmt.Sprintf("DELETE /v2/transactions/group/%d", last.TxID), "PUT /v2/transactions")
+	if _, ok := e.api.Transaction(last.TxID); !ok {
+		t.Fatal("dry run ungrouped the transfer")
+	}
+
+	assertContains(t, e.ok("undo", "--yes"), "(ungroup)", "1 group(s) ungrouped, 2 transaction(s) restored")
+	if _, ok := e.api.Transaction(last.TxID); ok {
+		t.Error("group still exists after undo")
+	}
+	for _, before := range legs {
+		if before.ID != 1036 && before.ID != 1037 {
+			continue
+		}
+		after, ok := e.api.Transaction(before.ID)
+		if !ok || !reflect.DeepEqual(after.CategoryID, before.CategoryID) {
+			t.Errorf("transaction %d after undo: %+v, want category %v", before.ID, after, before.CategoryID)
+		}
+	}
+	if r := e.run("undo", "2", "--yes"); r.err == nil || !strings.Contains(r.err.Error(), "cannot regroup") {
+		t.Errorf("undo of an ungroup: err = %v", r.err)
+	}
+}
+
+func TestE2EPayee(t *testing.T) {
+	e := newE2E(t)
+
+	out := e.ok("payee", "list", "--start", "2024-12-01", "--end", "2025-03-31")
+	assertContains(t, out, "Whole Foods                10", "SQ *SPOTIFY USA 8884")
+
+	out = e.ok("payee", "normalize", "--start", "2024-12-01", "--end", "2025-03-31")
+	assertContains(t, out, "Blue Bottle Coffee", "2 transaction(s) to rename")
+
+	e.ok("payee", "normalize", "--start", "2024-12-01", "--end", "2025-03-31", "--apply", "--yes")
+	if tx, _ := e.api.Transaction(1039); tx.Payee != "Blue Bottle Coffee" {
+		t.Errorf("1039 payee = %q, want Blue Bottle Coffee", tx.Payee)
+	}
+}
+
+func TestE2EAlerts(t *testing.T) {
+	e := newE2E(t)
+
+	r := e.run("alerts", "--end", "2025-03-28")
+	if r.err == nil || ExitCode(r.err) != 2 {
+		t.Fatalf("err = %v, want exit code 2", r.err)
+	}
+	assertContains(t, r.stdout, "payee_outlier", "new_merchant", "price_increase", "Netflix")
+
+	if r := e.run("alerts", "--end", "2024-12-10"); r.err != nil {
+		t.Errorf("quiet week: %v\n%s", r.err, r.stdout)
+	}
+}
+
+func TestE2ESubscriptionsDetect(t *testing.T) {
+	e := newE2E(t)
+
+	out := e.ok("subscriptions", "detect", "--end", "2025-03-31")
+	assertContains(t, out, "Oak Street Apartments", "Netflix", "suggested", "price +16%", "Spotify")
+	if strings.Contains(out, "ACME") {
+		t.Errorf("income listed as a subscription:\n%s", out)
+	}
+
+	out = e.ok("subscriptions", "detect", "--end", "2025-03-31", "--uncovered")
+	if strings.Contains(out, "Oak Street") {
+		t.Errorf("--uncovered lists the covered rent:\n%s", out)
+	}
+}
+
+func TestE2EForecast(t *testing.T) {
+	e := newE2E(t)
+
+	// A month from the 31st ends on the last day of the next month, so
+	// only one rent (due on the 1st) falls in the range.
+	for _, asOf := range []string{"2026-01-31", "2026-05-31", "2026-06-15"} {
+		var result forecastResult
+		e.okJSON(&result, "forecast", "--as-of", asOf, "--months", "1", "--json")
+		ends := map[string]string{}
+		for _, a := range result.Accounts {
+			ends[a.Name] = a.End.Format("")
+		}
+		if ends["Checking"] != "2400.00" || ends["Cash Wallet"] != "120.00" {
+			t.Errorf("as of %s: account ends = %v, want Checking 2400.00 (after rent) and Cash Wallet 120.00", asOf, ends)
+		}
+	}
+
+	assertContains(t, e.ok("forecast", "--as-of", "2026-01-31", "--months", "1", "--account", "Checking", "--chart"), "Checking", "2400.00 USD")
+	if r := e.run("forecast", "--as-of", "31/01/2026"); r.err == nil || !strings.Contains(r.err.Error(), "--as-of") {
+		t.Errorf("bad --as-of: err = %v", r.err)
+	}
+}
+
+func TestE2EHistoryAndUndo(t *testing.T) {
+	e := newE2E(t)
+
+	assertContains(t, e.ok("history"), "No history recorded.")
+
+	e.ok("tx", "update", "1041", "--note", "first")
+	e.ok("tx", "mark-reviewed", "1041")
+
+	var entries []journalEntry
+	e.okJSON(&entries, "history", "--json")
+	if len(entries) != 2 {
+		t.Fatalf("got %d history entries, want 2", len(entries))
+	}
+
+	e.ok("undo", "--yes")
+	if tx, _ := e.api.Transaction(1041); tx.Status != "unreviewed" || tx.Notes == nil || *tx.Notes != "first" {
+		t.Fatalf("after undo: status=%q notes=%v", tx.Status, tx.Notes)
+	}
+	e.ok("undo", "1", "--force", "--yes")
+	if tx, _ := e.api.Transaction(1041); tx.Notes != nil && *tx.Notes != "" {
+		t.Fatalf("after second undo: notes=%q", *tx.Notes)
+	}
+}
+
+func TestE2EMCPServe(t *testing.T) {
+	e := newE2E(t)
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"list_transactions","arguments":{"start_date":"2025-03-01","end_date":"2025-03-31","status":"unreviewed"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"update_transaction","arguments":{"id":1041,"category":"Groceries"}}}`,
+	}, "\n") + "\n"
+	r := e.runWithInput(input, "mcp", "serve", "--allow-writes", "--yes")
+	if r.err != nil {
+		t.Fatalf("mcp serve: %v\n%s", r.err, r.stderr)
+	}
+
+	type response struct {
+		ID     int `json:"id"`
+		Result struct {
+			IsError           bool            `json:"isError"`
+			StructuredContent json.RawMessage `json:"structuredContent"`
+		} `json:"result"`
+	}
+	var responses []response
+	dec := json.NewDecoder(strings.NewReader(r.stdout))
+	for dec.More() {
+		var one response
+		if err := dec.Decode(&one); err != nil {
+			t.Fatalf("decoding response: %v\n%s", err, r.stdout)
+		}
+		responses = append(responses, one)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3 (no reply to the notification):\n%s", len(responses), r.stdout)
+	}
+
+	var list mcpTransactionList
+	if err := json.Unmarshal(responses[1].Result.StructuredContent, &list); err != nil || list.Count != 5 {
+		t.Errorf("list_transactions = %s (%v), want 5 transactions", responses[1].Result.StructuredContent, err)
+	}
+	if responses[2].Result.IsError {
+		t.Errorf("update_transaction failed: %s", responses[2].Result.StructuredContent)
+	}
+	if tx, _ := e.api.Transaction(1041); tx.CategoryID == nil || *tx.CategoryID != 2 {
+		t.Errorf("1041 category = %v, want Groceries", tx.CategoryID)
+	}
+}
+
+func TestE2EMCPReadOnlyByDefault(t *testing.T) {
+	e := newE2E(t)
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"mark_reviewed","arguments":{"ids":[1038]}}}` + "\n"
+	r := e.runWithInput(input, "mcp", "serve")
+	if r.err != nil {
+		t.Fatalf("mcp serve: %v\n%s", r.err, r.stderr)
+	}
+	if strings.Contains(r.stdout, `"name":"update_transaction"`) || !strings.Contains(r.stdout, `unknown tool \"mark_reviewed\"`) {
+		t.Errorf("write tools served without --allow-writes:\n%s", r.stdout)
+	}
+	if r := e.runWithInput("", "mcp", "serve", "--yes"); r.err == nil || !strings.Contains(r.err.Error(), "--allow-writes") {
+		t.Errorf("--yes without --allow-writes: err = %v", r.err)
+	}
+}
+
+func TestE2EMCPElicitation(t *testing.T) {
+	e := newE2E(t)
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"elicitation":{}},"clientInfo":{"name":"test","version":"0"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"update_transaction","arguments":{"id":1041,"category":"Groceries"}}}`,
+		// A request sent while the user is deciding is answered afterwards.
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":"lm-elicit-1","result":{"action":"decline"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"mark_reviewed","arguments":{"ids":[1038]}}}`,
+		`{"jsonrpc":"2.0","id":"lm-elicit-2","result":{"action":"accept","content":{}}}`,
+	}, "\n") + "\n"
+	r := e.runWithInput(input, "mcp", "serve", "--allow-writes")
+	if r.err != nil {
+		t.Fatalf("mcp serve: %v\n%s", r.err, r.stderr)
+	}
+
+	type message struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Message string `json:"message"`
+		} `json:"params"`
+		Result struct {
+			Instructions      string         `json:"instructions"`
+			StructuredContent mcpWriteResult `json:"structuredContent"`
+		} `json:"result"`
+	}
+	var messages []message
+	dec := json.NewDecoder(strings.NewReader(r.stdout))
+	for dec.More() {
+		var m message
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("decoding message: %v\n%s", err, r.stdout)
+		}
+		messages = append(messages, m)
+	}
+	var order []string
+	for _, m := range messages {
+		order = append(order, string(m.ID))
+	}
+	want := []string{"1", `"lm-elicit-1"`, "2", "3", `"lm-elicit-2"`, "4"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("message ids = %v, want %v:\n%s", order, want, r.stdout)
+	}
+	assertContains(t, messages[0].Result.Instructions, "ask the user to approve")
+	if m := messages[1]; m.Method != "elicitation/create" {
+		t.Errorf("server sent %q, want elicitation/create", m.Method)
+	}
+	assertContains(t, messages[1].Params.Message, "1041", `category: "" -> "Groceries"`)
+	if res := messages[2].Result.StructuredContent; res.Applied || res.ConfirmToken != "" {
+		t.Errorf("declined update = %+v, want not applied and no token", res)
+	}
+	if tx, _ := e.api.Transaction(1041); tx.CategoryID != nil {
+		t.Errorf("declined update changed 1041 to category %d", *tx.CategoryID)
+	}
+	if res := messages[5].Result.StructuredContent; !res.Applied {
+		t.Errorf("accepted mark_reviewed = %+v, want applied", res)
+	}
+	if tx, _ := e.api.Transaction(1038); tx.Status != "reviewed" {
+		t.Errorf("1038 status = %q, want reviewed", tx.Status)
+	}
+}
+
+func TestE2EMCPConfirmToken(t *testing.T) {
+	e := newE2E(t)
+	client, err := newClient(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newMCPServer(client, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	call := func(args string) (mcpWriteResult, error) {
+		t.Helper()
+		result, err := mcpUpdateTransaction(ctx, s, json.RawMessage(args))
+		if err != nil {
+			return mcpWriteResult{}, err
+		}
+		return result.(mcpWriteResult), nil
+	}
+
+	preview, err := call(`{"id":1041,"category":"Groceries"}`)
+	if err != nil || preview.Applied || preview.ConfirmToken == "" {
+		t.Fatalf("preview = %+v, %v", preview, err)
+	}
+	if tx, _ := e.api.Transaction(1041); tx.CategoryID != nil {
+		t.Fatal("preview changed the transaction")
+	}
+
+	for _, args := range []string{
+		`{"id":1041,"category":"Groceries","confirm_token":"made-up"}`,
+		`{"id":1041,"category":"Coffee Shops","confirm_token":"` + preview.ConfirmToken + `"}`,
+		`{"id":1041,"category":"Groceries","confirm":true}`,
+	} {
+		if _, err := call(args); err == nil {
+			t.Errorf("%s: expected an error", args)
+		}
+	}
+	other, err := newMCPServer(client, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mcpUpdateTransaction(ctx, other, json.RawMessage(`{"id":1041,"category":"Groceries","confirm_token":"`+preview.ConfirmToken+`"}`)); err == nil {
+		t.Error("another server accepted the token")
+	}
+
+	applied, err := call(`{"id":1041,"category":"Groceries","confirm_token":"` + preview.ConfirmToken + `"}`)
+	if err != nil || !applied.Applied {
+		t.Fatalf("apply = %+v, %v", applied, err)
+	}
+	if tx, _ := e.api.Transaction(1041); tx.CategoryID == nil || *tx.CategoryID != 2 {
+		t.Errorf("1041 category = %v, want Groceries", tx.CategoryID)
+	}
+	// The transaction changed, so the old token no longer applies.
+	if _, err := call(`{"id":1041,"category":"Groceries","confirm_token":"` + preview.ConfirmToken + `"}`); err == nil {
+		t.Error("a token was reused after the transaction changed")
+	}
+
+	result, err := mcpMarkReviewed(ctx, s, json.RawMessage(`{"ids":[1038,1039]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := result.(mcpWriteResult).ConfirmToken
+	if _, err := mcpMarkReviewed(ctx, s, json.RawMessage(`{"ids":[1038],"confirm_token":"`+token+`"}`)); err == nil {
+		t.Error("mark_reviewed token applied to other ids")
+	}
+	if _, err := mcpMarkReviewed(ctx, s, json.RawMessage(`{"ids":[1038,1039],"confirm_token":"`+token+`"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if tx, _ := e.api.Transaction(1039); tx.Status != "reviewed" {
+		t.Errorf("1039 status = %q, want reviewed", tx.Status)
+	}
+}
+
+func TestE2EServe(t *testing.T) {
+	e := newE2E(t)
+	client, err := newClient(e.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newAPIHandler(client, "secret", io.Discard))
+	defer ts.Close()
+
+	get := func(path, token string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := get("/api/categories", ""); status != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", status)
+	}
+	status, body := get("/api/transactions?start=2025-03-01&end=2025-03-31&status=unreviewed", "secret")
+	if status != http.StatusOK {
+		t.Fatalf("transactions: status %d: %s", status, body)
+	}
+	assertContains(t, body, `"id": 1041`, "Payment Thank You")
+
+	if status, _ := get("/api/transactions/999999", "secret"); status != http.StatusNotFound {
+		t.Errorf("missing transaction: status %d, want 404", status)
+	}
+	status, body = get("/api/reports/spending?start=2025-03-01&end=2025-03-31&group_by=category", "secret")
+	if status != http.StatusOK {
+		t.Fatalf("spending: status %d: %s", status, body)
+	}
+	assertContains(t, body, `"key": "Groceries"`)
+
+	e.api.Fail(http.MethodGet, "/tags", http.StatusInternalServerError, 5)
+	if status, _ := get("/api/tags", "secret"); status != http.StatusBadGateway {
+		t.Errorf("upstream failure: status %d, want 502", status)
+	}
+
+	// Without a token only loopback Host headers are answered.
+	open := httptest.NewServer(newAPIHandler(client, "", io.Discard))
+	defer open.Close()
+	for host, want := range map[string]int{
+		"localhost:8080":     http.StatusOK,
+		"127.0.0.1:8080":     http.StatusOK,
+		"[::1]:8080":         http.StatusOK,
+		"LOCALHOST.":         http.StatusOK,
+		"evil.example:8080":  http.StatusForbidden,
+		"evil.example":       http.StatusForbidden,
+		"192.168.1.10:8080":  http.StatusForbidden,
+		"localhost.evil.com": http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, open.URL+"/api/categories", nil)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Host %s: status %d, want %d", host, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestE2EDevMockServer(t *testing.T) {
+	e := newE2E(t)
+
+	if r := e.run("dev", "mock-server", "--page-size", "-1"); r.err == nil {
+		t.Error("expected an error for a negative --page-size")
+	}
+	if r := e.run("dev", "mock-server", "--fixtures", t.TempDir()+"/missing"); r.err == nil {
+		t.Error("expected an error for a missing fixture directory")
+	}
+}
+
+func TestE2EAPIErrors(t *testing.T) {
+	e := newE2E(t)
+
+	e.api.Fail(http.MethodGet, "/categories", http.StatusInternalServerError, 1)
+	r := e.run("category", "list")
+	if r.err == nil || !strings.Contains(r.err.Error(), "500") {
+		t.Errorf("err = %v, want the API's 500", r.err)
+	}
+
+	t.Setenv("LUNCHMONEY_API_KEY", "wrong")
+	r = e.run("category", "list")
+	if r.err == nil || !strings.Contains(r.err.Error(), "401") {
+		t.Errorf("err = %v, want a 401", r.err)
+	}
+}
+
+func TestE2ETrace(t *testing.T) {
+	e := newE2E(t)
+
+	r := e.run("--trace", "category", "list")
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	assertContains(t, r.stderr, "--> GET http://", "/v2/categories?format=flattened", "Authorization: Bearer REDACTED", "<-- 200 OK", `"name": "Groceries"`)
+	if strings.Contains(r.stderr, mockapi.DefaultAPIKey) {
+		t.Errorf("trace leaks the API key:\n%s", r.stderr)
+	}
+
+	path := t.TempDir() + "/trace.log"
+	e.ok("--trace="+path, "tx", "update", "1041", "--note", "traced")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, string(data), "--> PUT", "/v2/transactions/1041", `"notes": "traced"`)
+	if f := optionsFrom(e.root.Context()).traceFile; f == nil {
+		t.Error("--trace=FILE left no file to close")
+	} else if _, err := f.Write(nil); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("trace file still open after the command: write err = %v", err)
+	}
+}
+
+func TestE2ERecordReplay(t *testing.T) {
+	e := newE2E(t)
+	dir := t.TempDir() + "/cassettes"
+
+	listArgs := []string{"tx", "list", "--start", "2025-03-01", "--end", "2025-03-31", "--unreviewed"}
+	want := e.ok(append([]string{"--record", dir}, listArgs...)...)
+	e.ok("--record", dir, "tx", "update", "1041", "--note", "recorded")
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no cassettes recorded")
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(dir + "/" + f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), mockapi.DefaultAPIKey) {
+			t.Fatalf("%s contains the API key", f.Name())
+		}
+	}
+
+	// Replay needs neither the key nor the server.
+	t.Setenv("LUNCHMONEY_API_KEY", "")
+	t.Setenv("LUNCHMONEY_BASE_URL", "http://127.0.0.1:1/v2")
+	if got := e.ok(append([]string{"--replay", dir}, listArgs...)...); got != want {
+		t.Errorf("replayed output differs:\n%s\nwant:\n%s", got, want)
+	}
+	e.ok("--replay", dir, "tx", "update", "1041", "--note", "recorded")
+
+	if r := e.run("--replay", dir, "tx", "list", "--start", "2025-01-01", "--end", "2025-01-31"); r.err == nil || !strings.Contains(r.err.Error(), "no recorded response") {
+		t.Errorf("err = %v, want a missing cassette error", r.err)
+	}
+	if r := e.run("--record", dir, "--replay", dir, "category", "list"); r.err == nil {
+		t.Error("expected --record and --replay to conflict")
+	}
+}
+
+func TestE2ETxListStreams(t *testing.T) {
+	e := newE2E(t)
+	args := []string{"tx", "list", "--start", "2024-12-01", "--end", "2025-03-31", "--unreviewed"}
+
+	var batch []transactionView
+	e.okJSON(&batch, append(args, "--json")...)
+
+	out := e.ok(append(args, "--format", "ndjson")...)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != len(batch) {
+		t.Fatalf("ndjson has %d rows, json %d:\n%s", len(lines), len(batch), out)
+	}
+	var first transactionView
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.ID != 1036 {
+		t.Errorf("first ndjson row = %+v (%v), want 1036, the oldest", first, err)
+	}
+
+	out = e.ok(append(args, "--format", "csv")...)
+	assertContains(t, out, "id,date,description,category,amount", "1041,2025-03-27,Whole Foods,,-74.30,usd")
+
+	// CSV output can be edited and applied back.
+	edited := strings.Replace(out, "1041,2025-03-27,Whole Foods,,", "1041,2025-03-27,Whole Foods,Groceries,", 1)
+	if r := e.runWithInput(edited, "tx", "apply", "--format", "csv", "--yes"); r.err != nil {
+		t.Fatalf("apply: %v\n%s", r.err, r.stderr)
+	}
+	if tx, _ := e.api.Transaction(1041); tx.CategoryID == nil || *tx.CategoryID != 2 {
+		t.Errorf("1041 category = %v, want Groceries", tx.CategoryID)
+	}
+
+	if r := e.run(append(args, "--json", "--format", "csv")...); r.err == nil {
+		t.Error("expected --json and --format csv to conflict")
+	}
+}
+
+func TestE2ETxListStreamPairsTransfers(t *testing.T) {
+	e := newE2E(t)
+	config := `{"classification": {"pair_window_days": 3}}`
+	if err := os.WriteFile(os.Getenv(envConfigDir)+"/config.json", []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := e.ok("tx", "list", "--start", "2025-03-01", "--end", "2025-03-31", "--unreviewed", "--format", "ndjson")
+	types := map[int64]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var v transactionView
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatal(err)
+		}
+		types[v.ID] = v.Type
+	}
+	if types[1036] != txTypeTransfer || types[1037] != txTypeTransfer {
+		t.Errorf("types = %v, want 1036 and 1037 paired as transfers", types)
+	}
+	if types[1041] == txTypeTransfer {
+		t.Errorf("1041 classified as a transfer")
+	}
+}
+
+func TestE2ETimeout(t *testing.T) {
+	e := newE2E(t)
+	e.api.Latency = 200 * time.Millisecond
+
+	r := e.run("--timeout", "20ms", "category", "list")
+	if r.err == nil || ExitCode(r.err) != exitCodeTimeout {
+		t.Fatalf("err = %v (exit code %d), want a timeout with exit code %d", r.err, ExitCode(r.err), exitCodeTimeout)
+	}
+	if r := e.run("--timeout", "-1s", "category", "list"); r.err == nil || !strings.Contains(r.err.Error(), "invalid --timeout") {
+		t.Errorf("negative --timeout: err = %v", r.err)
+	}
+	e.api.Latency = 0
+	e.ok("--timeout", "5s", "category", "list")
+}
+
+func TestE2EInterruptedWrites(t *testing.T) {
+	e := newE2E(t)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	e.ctx = ctx
+	puts := 0
+	e.api.OnRequest = func(r *http.Request) {
+		// Interrupt while the second write is in flight.
+		if r.Method == http.MethodPut {
+			if puts++; puts == 2 {
+				cancel(fmt.Errorf("interrupt signal received: %w", context.Canceled))
+			}
+		}
+	}
+
+	r := e.run("tx", "mark-reviewed", "1038", "1039", "1041")
+	if ExitCode(r.err) != exitCodeInterrupted {
+		t.Fatalf("err = %v (exit code %d), want exit code %d", r.err, ExitCode(r.err), exitCodeInterrupted)
+	}
+	assertContains(t, r.err.Error(), "interrupt signal received: context canceled: 2 of 3 write(s) completed before stopping (1038, 1039)")
+	if puts != 2 {
+		t.Errorf("sent %d writes, want 2", puts)
+	}
+	for id, want := range map[int64]string{1038: "reviewed", 1039: "reviewed", 1041: "unreviewed"} {
+		if tx, _ := e.api.Transaction(id); tx.Status != want {
+			t.Errorf("transaction %d status = %q, want %q", id, tx.Status, want)
+		}
+	}
+
+	// Both completed writes were journaled and can be undone.
+	e.ctx = context.Background()
+	var entries []journalEntry
+	e.okJSON(&entries, "history", "--json")
+	if len(entries) != 1 || len(entries[0].Changes) != 2 {
+		t.Fatalf("history = %+v, want one entry with 2 changes", entries)
+	}
+
+	// A delete that completes as the signal arrives is still reported.
+	ctx, cancel = context.WithCancelCause(context.Background())
+	e.ctx = ctx
+	e.api.OnRequest = func(r *http.Request) {
+		if r.Method == http.MethodDelete {
+			cancel(fmt.Errorf("interrupt signal received: %w", context.Canceled))
+		}
+	}
+	r = e.run("tx", "duplicates", "--start", "2025-03-01", "--end", "2025-03-31", "--delete", "--yes")
+	if ExitCode(r.err) != exitCodeInterrupted {
+		t.Fatalf("duplicates --delete: err = %v, want exit code %d", r.err, exitCodeInterrupted)
+	}
+	assertContains(t, r.err.Error(), "1 of 1 write(s) completed before stopping (1039)")
+}
+
+// answerSecrets makes readSecret return answers in order.
+func answerSecrets(t *testing.T, answers ...string) {
+	t.Helper()
+	orig := readSecret
+	t.Cleanup(func() { readSecret = orig })
+	readSecret = func(prompt string) (string, error) {
+		if len(answers) == 0 {
+			t.Fatalf("unexpected prompt %q", prompt)
+		}
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	}
+}
+
+func TestE2EAuthEncryptedFile(t *testing.T) {
+	e := newE2E(t)
+	t.Setenv(lunchmoney.EnvAPIKey, "")
+	orig := credentialsWorkFactor
+	t.Cleanup(func() { credentialsWorkFactor = orig })
+	credentialsWorkFactor = 10
+
+	if r := e.run("category", "list"); r.err == nil || !strings.Contains(r.err.Error(), "lm auth login") {
+		t.Fatalf("without a key: err = %v", r.err)
+	}
+	if r := e.runWithInput("wrong-key", "auth", "login", "--stdin"); r.err == nil || !strings.Contains(r.err.Error(), "401") {
+		t.Fatalf("login with a bad key: err = %v", r.err)
+	}
+
+	answerSecrets(t, "hunter2", "hunter2")
+	r := e.runWithInput(mockapi.DefaultAPIKey+"\n", "auth", "login", "--stdin")
+	if r.err != nil {
+		t.Fatalf("login: %v\n%s", r.err, r.stderr)
+	}
+	path := os.Getenv(envConfigDir) + "/" + credentialsFile
+	assertContains(t, r.stdout, "Logged in to", "API key saved in "+path)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), mockapi.DefaultAPIKey) {
+		t.Fatal("the credentials file holds the key in plain text")
+	}
+	// It is a standard passphrase-encrypted age file.
+	identity, err := age.NewScryptIdentity("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := age.Decrypt(armor.NewReader(bytes.NewReader(raw)), identity)
+	if err != nil {
+		t.Fatalf("age cannot decrypt the credentials file: %v", err)
+	}
+	if key, _ := io.ReadAll(plain); string(key) != mockapi.DefaultAPIKey {
+		t.Fatalf("credentials file decrypts to %q", key)
+	}
+
+	answerSecrets(t, "hunter2")
+	var status authStatus
+	e.okJSON(&status, "auth", "status", "--json")
+	if status.Source != path || status.Email == "" {
+		t.Errorf("status = %+v, want the key from %s", status, path)
+	}
+
+	answerSecrets(t, "wrong")
+	if r := e.run("category", "list"); r.err == nil || !strings.Contains(r.err.Error(), "wrong passphrase") {
+		t.Errorf("wrong passphrase: err = %v", r.err)
+	}
+
+	assertContains(t, e.ok("auth", "logout"), "Removed the API key from "+path)
+	assertContains(t, e.ok("auth", "logout"), "No saved API key.")
+}
+
+func TestE2EAuthKeyring(t *testing.T) {
+	e := newE2E(t)
+	t.Setenv(lunchmoney.EnvAPIKey, "")
+
+	// A stand-in for libsecret's secret-tool that keeps the secret in a file.
+	bin := t.TempDir()
+	keyring := bin + "/keyring"
+	script := `#!/bin/sh
+case "$1" in
+store) cat > "` + keyring + `" ;;
+lookup) [ -s "` + keyring + `" ] && cat "` + keyring + `" || exit 1 ;;
+clear) rm -f "` + keyring + `" ;;
+esac
+`
+	if err := os.WriteFile(bin+"/secret-tool", []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/dev/null")
+
+	out := e.runWithInput(mockapi.DefaultAPIKey, "auth", "login", "--stdin")
+	if out.err != nil {
+		t.Fatalf("login: %v\n%s", out.err, out.stderr)
+	}
+	assertContains(t, out.stdout, "API key saved in the Secret Service keyring")
+	if raw, _ := os.ReadFile(keyring); string(raw) != mockapi.DefaultAPIKey {
+		t.Fatalf("keyring holds %q", raw)
+	}
+
+	e.ok("category", "list")
+	assertContains(t, e.ok("auth", "status"), "API key from the Secret Service keyring.")
+	assertContains(t, e.ok("auth", "logout"), "Removed the API key from the Secret Service keyring.")
+	if _, err := os.Stat(keyring); err == nil {
+		t.Error("logout left the key in the keyring")
+	}
+}
+
+func TestE2EAuthAPIKeyCommand(t *testing.T) {
+	e := newE2E(t)
+	t.Setenv(lunchmoney.EnvAPIKey, "")
+	cfg := `{"api_key_command": "echo ` + mockapi.DefaultAPIKey + `"}`
+	if err := os.WriteFile(os.Getenv(envConfigDir)+"/"+configFile, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	e.ok("category", "list")
+	assertContains(t, e.ok("auth", "status"), "API key from api_key_command.")
+
+	cfg = `{"api_key_command": "exit 3"}`
+	if err := os.WriteFile(os.Getenv(envConfigDir)+"/"+configFile, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if r := e.run("category", "list"); r.err == nil || !strings.Contains(r.err.Error(), "api_key_command failed") {
+		t.Errorf("failing command: err = %v", r.err)
+	}
+}
+
+func TestE2ECompletion(t *testing.T) {
+	e := newE2E(t)
+	orig, origFactor := unreviewedCompletionDays, credentialsWorkFactor
+	t.Cleanup(func() { unreviewedCompletionDays, credentialsWorkFactor = orig, origFactor })
+	unreviewedCompletionDays = 100 * 365
+
+	out := e.ok("__complete", "tx", "mark-reviewed", "1038", "")
+	assertContains(t, out, "1041\t", "1039\t")
+	if strings.Contains(out, "1038\t") || strings.Contains(out, "1040\t") {
+		t.Errorf("offered an ID already given or already reviewed:\n%s", out)
+	}
+	if strings.Contains(e.ok("__complete", "tx", "update", "1041", ""), "1039") {
+		t.Error("tx update completed a second ID")
+	}
+
+	// Served from the cache until lm changes something.
+	before := len(e.api.Requests())
+	assertContains(t, e.ok("__complete", "tx", "update", "--category-id", ""), "2\tGroceries (Food)")
+	assertContains(t, e.ok("__complete", "tx", "update", "--category", "gro"), "Groceries\tFood")
+	assertContains(t, e.ok("__complete", "tx", "update", "--tags", "work,va"), "work,vacation")
+	assertContains(t, e.ok("__complete", "forecast", "--account", "ch"), "Checking\tplaid:10")
+	if got := len(e.api.Requests()); got != before {
+		t.Errorf("completion made %d requests with a fresh cache", got-before)
+	}
+	e.ok("tx", "mark-reviewed", "1041")
+	if out := e.ok("__complete", "tx", "mark-reviewed", ""); strings.Contains(out, "1041\t") {
+		t.Errorf("1041 still offered after it was marked reviewed:\n%s", out)
+	}
+
+	// Another key is another budget: the cache is not reused for it.
+	t.Setenv(lunchmoney.EnvAPIKey, "other-budget-key")
+	before = len(e.api.Requests())
+	if out := e.ok("__complete", "tx", "update", ""); strings.Contains(out, "1039") {
+		t.Errorf("offered the previous key's transactions:\n%s", out)
+	}
+	if len(e.api.Requests()) == before {
+		t.Error("completion used the cache after the API key changed")
+	}
+
+	// A key that needs a passphrase gives no suggestions rather than a prompt.
+	t.Setenv(lunchmoney.EnvAPIKey, "")
+	t.Setenv(envCacheDir, t.TempDir())
+	credentialsWorkFactor = 10
+	store, err := newFileStore(func(bool) (string, error) { return "hunter2", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.set(mockapi.DefaultAPIKey); err != nil {
+		t.Fatal(err)
+	}
+	answerSecrets(t)
+	if out := e.ok("__complete", "tx", "update", ""); strings.Contains(out, "1039") {
+		t.Errorf("suggestions without an API key:\n%s", out)
+	}
+
+	assertContains(t, e.ok("completion", "bash"), "bash completion V2 for lm")
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// exitCodeTimeout is returned when --timeout expires, as timeout(1) does.
+	exitCodeTimeout = 124
+	// exitCodeInterrupted is the shell's code for a process stopped by SIGINT.
+	exitCodeInterrupted = 130
+)
+
+// exitError carries a specific process exit code, e.g. for commands whose
+// "failure" is a finding rather than an error.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string { return e.msg }
+
+// ExitCode returns the exit code for an error returned by the root command:
+// the code attached by the command, 124 or 130 for a command cut short by
+// --timeout or a signal, or 1.
+func ExitCode(err error) int {
+	var e *exitError
+	switch {
+	case errors.As(err, &e):
+		return e.code
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return exitCodeInterrupted
+	}
+	return 1
+}
+
+// timeoutError is the cause of a context cancelled by --timeout. It matches
+// context.DeadlineExceeded, as the cause of a signal matches
+// context.Canceled.
+type timeoutError time.Duration
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", time.Duration(e))
+}
+
+func (timeoutError) Is(target error) bool { return target == context.DeadlineExceeded }
+
+// stopped returns nil while ctx is live. Once Ctrl-C, SIGTERM or --timeout
+// has cancelled it, it returns the error to exit with, reporting which of a
+// command's total writes completed before it stopped; the rest were not
+// sent.
+func stopped(ctx context.Context, completed []int64, total int) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	code := exitCodeInterrupted
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		code = exitCodeTimeout
+	}
+	msg := context.Cause(ctx).Error()
+	if total > 0 {
+		msg += fmt.Sprintf(": %d of %d write(s) completed before stopping", len(completed), total)
+		if len(completed) > 0 {
+			msg += " (" + joinIDs(completed) + ")"
+		}
+	}
+	return &exitError{code: code, msg: msg}
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// txPredicate is one --where condition such as `payee~amazon` or
+// `amount<-100`.
+type txPredicate struct {
+	field string
+	op    string
+	value string
+}
+
+var whereOperators = []string{"!=", "!~", "<=", ">=", "=", "~", "<", ">"}
+
+var whereFields = map[string]bool{
+	"id":          true,
+	"date":        true,
+	"description": true,
+	"payee":       true,
+	"category":    true,
+	"group":       true,
+	"account":     true,
+	"institution": true,
+	"notes":       true,
+	"tags":        true,
+	"status":      true,
+	"type":        true,
+	"amount":      true,
+}
+
+func parseWhere(exprs []string) ([]txPredicate, error) {
+	preds := make([]txPredicate, 0, len(exprs))
+	for _, expr := range exprs {
+		idx := strings.IndexAny(expr, "!=~<>")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid --where %q (expected <field><op><value>, e.g. payee~amazon)", expr)
+		}
+
+		op := ""
+		for _, candidate := range whereOperators {
+			if strings.HasPrefix(expr[idx:], candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("invalid operator in --where %q", expr)
+		}
+
+		field := strings.ToLower(strings.TrimSpace(expr[:idx]))
+		if !whereFields[field] {
+			return nil, fmt.Errorf("unknown field %q in --where", field)
+		}
+		value := strings.TrimSpace(expr[idx+len(op):])
+
+		if field == "amount" {
+			if _, err := lunchmoney.ParseAmount(value); err != nil {
+				return nil, fmt.Errorf("--where amount needs a numeric value, got %q", value)
+			}
+		} else if field == "id" {
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("--where id needs an integer value, got %q", value)
+			}
+		} else if op == "<" || op == ">" || op == "<=" || op == ">=" {
+			if field != "date" {
+				return nil, fmt.Errorf("operator %s is only supported for amount, id and date", op)
+			}
+		}
+
+		preds = append(preds, txPredicate{field: field, op: op, value: value})
+	}
+	return preds, nil
+}
+
+// matchesWhere reports whether the view satisfies every predicate.
+func matchesWhere(v transactionView, preds []txPredicate) bool {
+	for _, p := range preds {
+		if !p.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p txPredicate) matches(v transactionView) bool {
+	switch p.field {
+	case "amount":
+		want, _ := lunchmoney.ParseAmount(p.value)
+		return compareOrdered(v.Amount, want, p.op)
+	case "id":
+		want, _ := strconv.ParseInt(p.value, 10, 64)
+		return compareOrdered(v.ID, want, p.op)
+	}
+
+	got := viewFieldString(v, p.field)
+	switch p.op {
+	case "=":
+		return strings.EqualFold(got, p.value)
+	case "!=":
+		return !strings.EqualFold(got, p.value)
+	case "~":
+		return strings.Contains(strings.ToLower(got), strings.ToLower(p.value))
+	case "!~":
+		return !strings.Contains(strings.ToLower(got), strings.ToLower(p.value))
+	default:
+		// Only dates reach here; YYYY-MM-DD compares chronologically.
+		return compareOrdered(got, p.value, p.op)
+	}
+}
+
+func compareOrdered[T lunchmoney.Amount | int64 | string](got, want T, op string) bool {
+	switch op {
+	case "=", "~":
+		return got == want
+	case "!=", "!~":
+		return got != want
+	case "<":
+		return got < want
+	case "<=":
+		return got <= want
+	case ">":
+		return got > want
+	case ">=":
+		return got >= want
+	}
+	return false
+}
+
+func viewFieldString(v transactionView, field string) string {
+	switch field {
+	case "date":
+		return v.Date
+	case "description", "payee":
+		return v.Description
+	case "category":
+		return v.Category
+	case "group":
+		return v.Group
+	case "account":
+		return v.Account
+	case "institution":
+		return v.Institution
+	case "notes":
+		return v.Notes
+	case "tags":
+		return v.Tags
+	case "status":
+		return v.Status
+	case "type":
+		return v.Type
+	}
+	return ""
+}
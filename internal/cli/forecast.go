@@ -0,0 +1,534 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const (
+	forecastSourceRecurringItem = "recurring_item"
+	forecastSourceDetected      = "detected"
+
+	// unassignedAccountKey collects events whose account is unknown, such as
+	// recurring items not tied to an account.
+	unassignedAccountKey = "unassigned"
+)
+
+// forecastInput is everything the forecast engine reads. It does no I/O, so a
+// JSON fixture with the same shape produces the same forecast every time.
+type forecastInput struct {
+	AsOf           string                     `json:"as_of"`
+	Months         int                        `json:"months"`
+	Threshold      *lunchmoney.Amount         `json:"threshold,omitempty"`
+	ManualAccounts []lunchmoney.ManualAccount `json:"manual_accounts"`
+	PlaidAccounts  []lunchmoney.PlaidAccount  `json:"plaid_accounts"`
+	RecurringItems []lunchmoney.RecurringItem `json:"recurring_items"`
+	Transactions   []lunchmoney.Transaction   `json:"transactions"`
+}
+
+// forecastEvent is one projected transaction. Amount uses the API sign
+// (positive is money out) in base currency.
+type forecastEvent struct {
+	Date    string            `json:"date"`
+	Account string            `json:"account"`
+	Payee   string            `json:"payee"`
+	Amount  lunchmoney.Amount `json:"amount"`
+	Source  string            `json:"source"`
+}
+
+type dailyBalance struct {
+	Date    string            `json:"date"`
+	Balance lunchmoney.Amount `json:"balance"`
+}
+
+// accountForecast tracks one account in base currency. Liability balances
+// are amounts owed, so their low point is the day the most is owed.
+type accountForecast struct {
+	Key       string            `json:"key"`
+	Name      string            `json:"name"`
+	Liability bool              `json:"liability"`
+	Start     lunchmoney.Amount `json:"start"`
+	End       lunchmoney.Amount `json:"end"`
+	Low       lunchmoney.Amount `json:"low"`
+	LowDate   string            `json:"low_date"`
+	BelowDate string            `json:"below_date,omitempty"`
+	Daily     []dailyBalance    `json:"daily"`
+}
+
+type monthForecast struct {
+	Month    string            `json:"month"`
+	Income   lunchmoney.Amount `json:"income"`
+	Expenses lunchmoney.Amount `json:"expenses"`
+	Net      lunchmoney.Amount `json:"net"`
+}
+
+type forecastResult struct {
+	Start    string            `json:"start"`
+	End      string            `json:"end"`
+	Currency string            `json:"currency"`
+	Accounts []accountForecast `json:"accounts"`
+	Months   []monthForecast   `json:"months"`
+	Events   []forecastEvent   `json:"events"`
+}
+
+func newForecastCmd() *cobra.Command {
+	var (
+		months      int
+		asOf        string
+		historyDays int
+		account     string
+		threshold   string
+		chart       bool
+		jsonOutput  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "forecast",
+		Short: "Project daily account balances from recurring items and detected recurring transactions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if months <= 0 {
+				return errors.New("--months must be positive")
+			}
+			if historyDays <= 0 {
+				return errors.New("--history-days must be positive")
+			}
+			if asOf == "" {
+				asOf = time.Now().Format("2006-01-02")
+			} else if _, err := time.Parse("2006-01-02", asOf); err != nil {
+				return fmt.Errorf("invalid --as-of %q (expected YYYY-MM-DD)", asOf)
+			}
+			in := forecastInput{AsOf: asOf, Months: months}
+			if threshold != "" {
+				amount, err := lunchmoney.ParseAmount(threshold)
+				if err != nil {
+					return fmt.Errorf("invalid --threshold %q: %w", threshold, err)
+				}
+				in.Threshold = &amount
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			result, err := fetchForecast(cmd.Context(), client, in, historyDays)
+			if err != nil {
+				return err
+			}
+			if account != "" {
+				if result.Accounts, err = filterForecastAccounts(result.Accounts, account); err != nil {
+					return err
+				}
+			}
+
+			if jsonOutput {
+				return printJSON(result)
+			}
+			printForecast(os.Stdout, result)
+			if chart {
+				for _, a := range result.Accounts {
+					fmt.Println()
+					printForecastChart(os.Stdout, a, result.Currency)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&months, "months", 3, "Months to project")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "Project from this date (YYYY-MM-DD) with today's balances, defaults to today")
+	cmd.Flags().IntVar(&historyDays, "history-days", 400, "Days of history scanned for recurring transactions")
+	cmd.Flags().StringVar(&account, "account", "", "Only show the account with this name or key (e.g. manual:12)")
+	_ = cmd.RegisterFlagCompletionFunc("account", completeNames(completionAccounts, false))
+	cmd.Flags().StringVar(&threshold, "threshold", "", "Report the first day each asset account drops below this balance")
+	cmd.Flags().BoolVar(&chart, "chart", false, "Draw an ASCII chart of each account's balance")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON, including daily balances and events")
+
+	return cmd
+}
+
+// fetchForecast fills in's accounts, recurring items and the last
+// historyDays of transactions from the API and runs the forecast.
+func fetchForecast(ctx context.Context, client *lunchmoney.Client, in forecastInput, historyDays int) (forecastResult, error) {
+	asOf, err := time.Parse("2006-01-02", in.AsOf)
+	if err != nil {
+		return forecastResult{}, fmt.Errorf("invalid as_of %q (expected YYYY-MM-DD)", in.AsOf)
+	}
+	me, err := client.GetMe(ctx)
+	if err != nil {
+		return forecastResult{}, err
+	}
+	if in.ManualAccounts, err = client.ListManualAccounts(ctx); err != nil {
+		return forecastResult{}, err
+	}
+	if in.PlaidAccounts, err = client.ListPlaidAccounts(ctx); err != nil {
+		return forecastResult{}, err
+	}
+	if in.RecurringItems, err = client.ListRecurringItems(ctx, "", ""); err != nil {
+		return forecastResult{}, err
+	}
+	in.Transactions, err = client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+		StartDate: asOf.AddDate(0, 0, -historyDays).Format("2006-01-02"),
+		EndDate:   in.AsOf,
+		Limit:     1000,
+	})
+	if err != nil {
+		return forecastResult{}, err
+	}
+
+	result, err := runForecast(in)
+	if err != nil {
+		return forecastResult{}, err
+	}
+	result.Currency = me.PrimaryCurrency
+	return result, nil
+}
+
+// addMonths moves t by n months, keeping the day of the month but clamping
+// it to the target month's last day: 31 January plus one month is
+// 28 February, not 3 March.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// runForecast projects balances from the day after AsOf through Months
+// months later. Every account starts at its current balance; each event then
+// moves its account, down for assets and up for liabilities when money goes
+// out.
+func runForecast(in forecastInput) (forecastResult, error) {
+	asOf, err := time.Parse("2006-01-02", in.AsOf)
+	if err != nil {
+		return forecastResult{}, fmt.Errorf("invalid as_of %q (expected YYYY-MM-DD)", in.AsOf)
+	}
+	if in.Months <= 0 {
+		return forecastResult{}, errors.New("months must be positive")
+	}
+	start := asOf.AddDate(0, 0, 1)
+	end := addMonths(asOf, in.Months)
+
+	accounts := forecastAccounts(in.ManualAccounts, in.PlaidAccounts)
+	events := forecastEvents(in.RecurringItems, in.Transactions, start, end)
+
+	byKey := make(map[string]int, len(accounts))
+	for i, a := range accounts {
+		byKey[a.Key] = i
+	}
+	eventsByDay := make(map[string][]forecastEvent)
+	for i := range events {
+		if _, ok := byKey[events[i].Account]; !ok {
+			if _, ok := byKey[unassignedAccountKey]; !ok {
+				byKey[unassignedAccountKey] = len(accounts)
+				accounts = append(accounts, accountForecast{Key: unassignedAccountKey, Name: "Unassigned"})
+			}
+			events[i].Account = unassignedAccountKey
+		}
+		eventsByDay[events[i].Date] = append(eventsByDay[events[i].Date], events[i])
+	}
+
+	balances := make([]lunchmoney.Amount, len(accounts))
+	for i := range accounts {
+		balances[i] = accounts[i].Start
+		accounts[i].Low = accounts[i].Start
+		accounts[i].LowDate = in.AsOf
+	}
+	months := make(map[string]*monthForecast)
+	var monthOrder []string
+
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		month := date[:7]
+		m, ok := months[month]
+		if !ok {
+			m = &monthForecast{Month: month}
+			months[month] = m
+			monthOrder = append(monthOrder, month)
+		}
+		for _, e := range eventsByDay[date] {
+			i := byKey[e.Account]
+			if accounts[i].Liability {
+				balances[i] += e.Amount
+			} else {
+				balances[i] -= e.Amount
+			}
+			if e.Amount.Sign() < 0 {
+				m.Income -= e.Amount
+			} else {
+				m.Expenses += e.Amount
+			}
+		}
+		for i := range accounts {
+			a := &accounts[i]
+			b := balances[i]
+			a.Daily = append(a.Daily, dailyBalance{Date: date, Balance: b})
+			if (a.Liability && b > a.Low) || (!a.Liability && b < a.Low) {
+				a.Low, a.LowDate = b, date
+			}
+			if in.Threshold != nil && !a.Liability && a.BelowDate == "" && b < *in.Threshold {
+				a.BelowDate = date
+			}
+		}
+	}
+
+	result := forecastResult{
+		Start:    start.Format("2006-01-02"),
+		End:      end.Format("2006-01-02"),
+		Accounts: accounts,
+		Events:   events,
+	}
+	for i := range result.Accounts {
+		result.Accounts[i].End = balances[i]
+	}
+	for _, month := range monthOrder {
+		m := months[month]
+		m.Net = m.Income - m.Expenses
+		result.Months = append(result.Months, *m)
+	}
+	return result, nil
+}
+
+// forecastAccounts lists active accounts with their balances in base
+// currency, sorted by name.
+func forecastAccounts(manual []lunchmoney.ManualAccount, plaid []lunchmoney.PlaidAccount) []accountForecast {
+	var accounts []accountForecast
+	manualNames := buildManualAccountLookup(manual)
+	for _, a := range manual {
+		if a.Status != "" && a.Status != "active" {
+			continue
+		}
+		accounts = append(accounts, accountForecast{
+			Key:       fmt.Sprintf("manual:%d", a.ID),
+			Name:      manualNames[a.ID].DisplayName,
+			Liability: a.Type == "credit" || a.Type == "loan" || a.Type == "other liability",
+			Start:     a.ToBase,
+		})
+	}
+	plaidNames := buildPlaidAccountLookup(plaid)
+	for _, a := range plaid {
+		if a.Status != "" && a.Status != "active" {
+			continue
+		}
+		accounts = append(accounts, accountForecast{
+			Key:       fmt.Sprintf("plaid:%d", a.ID),
+			Name:      plaidNames[a.ID].DisplayName,
+			Liability: a.Type == "credit" || a.Type == "loan",
+			Start:     a.ToBase,
+		})
+	}
+	sort.SliceStable(accounts, func(i, j int) bool {
+		if accounts[i].Name != accounts[j].Name {
+			return accounts[i].Name < accounts[j].Name
+		}
+		return accounts[i].Key < accounts[j].Key
+	})
+	return accounts
+}
+
+// forecastEvents schedules reviewed recurring items and the recurring series
+// detected in history that no reviewed item covers. A detected series that
+// is overdue but not stopped is expected on the first forecast day.
+func forecastEvents(items []lunchmoney.RecurringItem, transactions []lunchmoney.Transaction, start, end time.Time) []forecastEvent {
+	var events []forecastEvent
+	for _, item := range items {
+		if item.Status != "reviewed" {
+			continue
+		}
+		c := item.TransactionCriteria
+		payee := stringOrDefault(item.Overrides.Payee, stringOrDefault(c.Payee, stringOrDefault(item.Description, "")))
+		account := ""
+		if c.ManualAccountID != nil {
+			account = fmt.Sprintf("manual:%d", *c.ManualAccountID)
+		} else if c.PlaidAccountID != nil {
+			account = fmt.Sprintf("plaid:%d", *c.PlaidAccountID)
+		}
+		for _, day := range recurringItemDates(c, start, end) {
+			events = append(events, forecastEvent{
+				Date:    day.Format("2006-01-02"),
+				Account: account,
+				Payee:   payee,
+				Amount:  c.ToBase,
+				Source:  forecastSourceRecurringItem,
+			})
+		}
+	}
+
+	index := newRecurringItemIndex(items)
+	asOf := start.AddDate(0, 0, -1)
+	for _, s := range detectRecurring(transactions) {
+		if s.stopped(asOf) {
+			continue
+		}
+		if item, ok := index.match(s); ok && item.Status == "reviewed" {
+			continue
+		}
+		last := s.last()
+		for day := s.nextDate(); !day.After(end); day = s.cadence.next(day) {
+			date := day
+			if date.Before(start) {
+				date = start
+			}
+			events = append(events, forecastEvent{
+				Date:    date.Format("2006-01-02"),
+				Account: transactionAccountKey(last),
+				Payee:   s.payee,
+				Amount:  last.ToBase,
+				Source:  forecastSourceDetected,
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Date != events[j].Date {
+			return events[i].Date < events[j].Date
+		}
+		return events[i].Payee < events[j].Payee
+	})
+	return events
+}
+
+// recurringItemDates returns the occurrences of a recurring item between
+// start and end inclusive, counted from its anchor date so monthly items do
+// not drift after short months.
+func recurringItemDates(c lunchmoney.RecurringCriteria, start, end time.Time) []time.Time {
+	anchor, err := time.Parse("2006-01-02", c.AnchorDate)
+	if err != nil {
+		return nil
+	}
+	if c.EndDate != nil {
+		if last, err := time.Parse("2006-01-02", *c.EndDate); err == nil && last.Before(end) {
+			end = last
+		}
+	}
+	quantity := c.Quantity
+	if quantity <= 0 {
+		quantity = 1
+	}
+	step := func(n int) time.Time {
+		switch c.Granularity {
+		case "day":
+			return anchor.AddDate(0, 0, n*quantity)
+		case "week":
+			return anchor.AddDate(0, 0, 7*n*quantity)
+		case "year":
+			return addMonths(anchor, 12*n*quantity)
+		default:
+			return addMonths(anchor, n*quantity)
+		}
+	}
+	var dates []time.Time
+	for n := 0; ; n++ {
+		day := step(n)
+		if day.After(end) {
+			break
+		}
+		if !day.Before(start) {
+			dates = append(dates, day)
+		}
+	}
+	return dates
+}
+
+func filterForecastAccounts(accounts []accountForecast, query string) ([]accountForecast, error) {
+	var kept []accountForecast
+	for _, a := range accounts {
+		if a.Key == query || strings.EqualFold(a.Name, query) {
+			kept = append(kept, a)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no account named %q", query)
+	}
+	return kept, nil
+}
+
+func printForecast(out io.Writer, result forecastResult) {
+	fmt.Fprintf(out, "Forecast %s to %s\n\n", result.Start, result.End)
+	money := func(a lunchmoney.Amount) string { return formatAmountWithCurrency(a, result.Currency) }
+
+	w := newTabWriter(out)
+	fmt.Fprintln(w, "ACCOUNT\tNOW\tEND\tLOW POINT\tLOW DATE\tNOTE")
+	for _, a := range result.Accounts {
+		note := ""
+		switch {
+		case a.BelowDate != "":
+			note = "below threshold on " + a.BelowDate
+		case a.Liability:
+			note = "owed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Name, money(a.Start), money(a.End), money(a.Low), a.LowDate, note)
+	}
+	_ = w.Flush()
+
+	fmt.Fprintln(out)
+	w = newTabWriter(out)
+	fmt.Fprintln(w, "MONTH\tINCOME\tEXPENSES\tNET")
+	for _, m := range result.Months {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Month, money(m.Income), money(m.Expenses), money(m.Net))
+	}
+	_ = w.Flush()
+}
+
+const (
+	forecastChartWidth  = 60
+	forecastChartHeight = 10
+)
+
+// printForecastChart draws one account's balance as a column chart, one
+// column per day or per group of days when the range is wider than the
+// chart.
+func printForecastChart(out io.Writer, a accountForecast, currency string) {
+	if len(a.Daily) == 0 {
+		return
+	}
+	width := min(len(a.Daily), forecastChartWidth)
+	columns := make([]float64, width)
+	for c := range columns {
+		columns[c] = a.Daily[c*len(a.Daily)/width].Balance.Float64()
+	}
+	low, high := columns[0], columns[0]
+	for _, v := range columns {
+		low, high = min(low, v), max(high, v)
+	}
+
+	fmt.Fprintln(out, a.Name)
+	labels := []string{
+		lunchmoney.AmountFromFloat(high).Format(currency),
+		lunchmoney.AmountFromFloat(low).Format(currency),
+	}
+	labelWidth := max(len(labels[0]), len(labels[1]))
+	for row := forecastChartHeight - 1; row >= 0; row-- {
+		label := ""
+		switch row {
+		case forecastChartHeight - 1:
+			label = labels[0]
+		case 0:
+			label = labels[1]
+		}
+		var line strings.Builder
+		for _, v := range columns {
+			level := 0
+			if high > low {
+				level = int((v - low) / (high - low) * float64(forecastChartHeight-1))
+			}
+			if level >= row {
+				line.WriteByte('#')
+			} else {
+				line.WriteByte(' ')
+			}
+		}
+		fmt.Fprintf(out, "%*s |%s\n", labelWidth, label, strings.TrimRight(line.String(), " "))
+	}
+	first, last := a.Daily[0].Date, a.Daily[len(a.Daily)-1].Date
+	fmt.Fprintf(out, "%*s +%s\n", labelWidth, "", strings.Repeat("-", width))
+	fmt.Fprintf(out, "%*s  %s%*s\n", labelWidth, "", first, max(width-len(first), len(last)+1), last)
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func loadForecastFixture(t *testing.T) forecastInput {
+	t.Helper()
+	data, err := os.ReadFile("testdata/forecast.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var in forecastInput
+	if err := json.Unmarshal(data, &in); err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func TestRunForecast(t *testing.T) {
+	result, err := runForecast(loadForecastFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Start != "2026-01-16" || result.End != "2026-03-15" {
+		t.Fatalf("range = %s..%s, want 2026-01-16..2026-03-15", result.Start, result.End)
+	}
+
+	amount := func(s string) lunchmoney.Amount {
+		a, err := lunchmoney.ParseAmount(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	wantAccounts := []struct {
+		key       string
+		end       string
+		low       string
+		lowDate   string
+		belowDate string
+	}{
+		{key: "manual:1", end: "6000", low: "-500", lowDate: "2026-01-16", belowDate: "2026-01-16"},
+		{key: "plaid:2", end: "230.98", low: "230.98", lowDate: "2026-03-10"},
+	}
+	if len(result.Accounts) != len(wantAccounts) {
+		t.Fatalf("got %d accounts, want %d: %+v", len(result.Accounts), len(wantAccounts), result.Accounts)
+	}
+	for i, want := range wantAccounts {
+		got := result.Accounts[i]
+		if got.Key != want.key {
+			t.Errorf("account %d = %s, want %s", i, got.Key, want.key)
+			continue
+		}
+		if got.End != amount(want.end) || got.Low != amount(want.low) || got.LowDate != want.lowDate || got.BelowDate != want.belowDate {
+			t.Errorf("%s: end %s low %s on %s below %q, want end %s low %s on %s below %q",
+				got.Key, got.End, got.Low, got.LowDate, got.BelowDate, want.end, want.low, want.lowDate, want.belowDate)
+		}
+		if len(got.Daily) != 59 {
+			t.Errorf("%s: %d daily balances, want 59", got.Key, len(got.Daily))
+		}
+	}
+
+	wantMonths := []struct {
+		month, income, expenses, net string
+	}{
+		{"2026-01", "2000", "1500", "500"},
+		{"2026-02", "4000", "1515.49", "2484.51"},
+		{"2026-03", "2000", "15.49", "1984.51"},
+	}
+	if len(result.Months) != len(wantMonths) {
+		t.Fatalf("got %d months, want %d", len(result.Months), len(wantMonths))
+	}
+	for i, want := range wantMonths {
+		got := result.Months[i]
+		if got.Month != want.month || got.Income != amount(want.income) || got.Expenses != amount(want.expenses) || got.Net != amount(want.net) {
+			t.Errorf("month %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestRunForecastIsDeterministic(t *testing.T) {
+	in := loadForecastFixture(t)
+	first, err := runForecast(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		again, err := runForecast(loadForecastFixture(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, _ := json.Marshal(first)
+		b, _ := json.Marshal(again)
+		if string(a) != string(b) {
+			t.Fatal("forecast changed between runs")
+		}
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	for _, tt := range []struct {
+		from   string
+		months int
+		want   string
+	}{
+		{"2026-01-31", 1, "2026-02-28"},
+		{"2028-01-31", 1, "2028-02-29"},
+		{"2026-05-31", 1, "2026-06-30"},
+		{"2026-01-31", 2, "2026-03-31"},
+		{"2026-11-30", 3, "2027-02-28"},
+		{"2026-01-15", 1, "2026-02-15"},
+		{"2028-02-29", 12, "2029-02-28"},
+	} {
+		from, _ := time.Parse("2006-01-02", tt.from)
+		if got := addMonths(from, tt.months).Format("2006-01-02"); got != tt.want {
+			t.Errorf("addMonths(%s, %d) = %s, want %s", tt.from, tt.months, got, tt.want)
+		}
+	}
+}
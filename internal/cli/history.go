@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func newHistoryCmd() *cobra.Command {
+	var (
+		limit      int
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history [<entry>]",
+		Short: "Show the local journal of changes made by lm",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readJournal()
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 1 {
+				entry, err := findJournalEntry(entries, args[0])
+				if err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printJSON(entry)
+				}
+				printJournalEntry(entry, undoneBy(entries)[entry.ID])
+				return nil
+			}
+
+			if limit > 0 && len(entries) > limit {
+				entries = entries[len(entries)-limit:]
+			}
+			if jsonOutput {
+				return printJSON(entries)
+			}
+			if len(entries) == 0 {
+				fmt.Println("No history recorded.")
+				return nil
+			}
+
+			undone := undoneBy(entries)
+			w := newTabWriter(os.Stdout)
+			fmt.Fprintln(w, "ENTRY\tTIME\tTRANSACTIONS\tSTATUS\tCOMMAND")
+			for i := len(entries) - 1; i >= 0; i-- {
+				e := entries[i]
+				status := ""
+				if by, ok := undone[e.ID]; ok {
+					status = fmt.Sprintf("undone by %d", by)
+				} else if e.UndoOf != 0 {
+					status = fmt.Sprintf("undo of %d", e.UndoOf)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04"), len(e.Changes), status, e.Command)
+			}
+			_ = w.Flush()
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 20, "Maximum number of entries to list (0 for all)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON")
+
+	return cmd
+}
+
+func newUndoCmd() *cobra.Command {
+	var (
+		force bool
+		yes   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "undo [<entry>]",
+		Short: "Restore the values changed by a journal entry (default: the latest)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readJournal()
+			if err != nil {
+				return err
+			}
+			undone := undoneBy(entries)
+
+			var entry journalEntry
+			if len(args) == 1 {
+				entry, err = findJournalEntry(entries, args[0])
+				if err != nil {
+					return err
+				}
+			} else {
+				found := false
+				for i := len(entries) - 1; i >= 0; i-- {
+					if _, ok := undone[entries[i].ID]; !ok && entries[i].UndoOf == 0 {
+						entry, found = entries[i], true
+						break
+					}
+				}
+				if !found {
+					return errors.New("nothing to undo")
+				}
+			}
+			if by, ok := undone[entry.ID]; ok {
+				return fmt.Errorf("entry %d was already undone by entry %d", entry.ID, by)
+			}
+			if err := undoable(entry); err != nil {
+				return err
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			// Transactions the entry grouped are only checked through their
+			// group, and fetched again once it is ungrouped.
+			grouped := make(map[int64]bool)
+			for _, change := range entry.Changes {
+				if change.Action == journalGroup {
+					for _, id := range change.Children {
+						grouped[id] = true
+					}
+				}
+			}
+			currents := make([]lunchmoney.Transaction, len(entry.Changes))
+			var conflicts []string
+			for i, change := range entry.Changes {
+				if grouped[change.TxID] {
+					currents[i] = change.After
+					continue
+				}
+				current, err := client.GetTransaction(ctx, change.TxID)
+				if err != nil {
+					return fmt.Errorf("failed to fetch transaction %d: %w", change.TxID, err)
+				}
+				if current.UpdatedAt != change.After.UpdatedAt {
+					conflicts = append(conflicts, fmt.Sprintf("transaction %d was modified at %s after entry %d", change.TxID, current.UpdatedAt, entry.ID))
+				}
+				currents[i] = current
+			}
+			if len(conflicts) > 0 && !force {
+				for _, c := range conflicts {
+					fmt.Fprintln(os.Stderr, c)
+				}
+				return errors.New("refusing to undo over newer changes (use --force to overwrite them)")
+			}
+
+			w := newTabWriter(os.Stdout)
+			fmt.Fprintln(w, "TX\tFIELD\tCURRENT\tRESTORED")
+			for i, change := range entry.Changes {
+				if change.Action == journalGroup {
+					fmt.Fprintf(w, "%d\t(ungroup)\t%q\t%s\n", change.TxID, change.After.Payee, joinIDs(change.Children))
+					continue
+				}
+				for _, field := range change.Fields {
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", change.TxID, field, journalFieldValue(currents[i], field), journalFieldValue(change.Before, field))
+				}
+			}
+			_ = w.Flush()
+
+			if !yes && !client.DryRun() {
+				ok, err := confirm(cmd.Context(), fmt.Sprintf("Undo entry %d (%s)?", entry.ID, entry.Command))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errors.New("aborted")
+				}
+			}
+
+			undo := journalEntry{Command: commandLine(), UndoOf: entry.ID}
+			groups := 0
+			for i := len(entry.Changes) - 1; i >= 0; i-- {
+				change := entry.Changes[i]
+				if change.Action != journalGroup {
+					continue
+				}
+				if err := client.UngroupTransactions(ctx, change.TxID); err != nil {
+					recordJournalEntry(ctx, undo)
+					return fmt.Errorf("failed to ungroup transaction %d: %w", change.TxID, err)
+				}
+				undo.Changes = append(undo.Changes, journalChange{TxID: change.TxID, Action: journalUngroup, Children: change.Children, Before: currents[i]})
+				groups++
+			}
+			// A dry run left the group in place, so the recorded values stand
+			// in for the ungrouped transactions.
+			for i, change := range entry.Changes {
+				if !grouped[change.TxID] || client.DryRun() {
+					continue
+				}
+				current, err := client.GetTransaction(ctx, change.TxID)
+				if err != nil {
+					recordJournalEntry(ctx, undo)
+					return fmt.Errorf("failed to fetch ungrouped transaction %d: %w", change.TxID, err)
+				}
+				currents[i] = current
+			}
+
+			var (
+				updates []lunchmoney.BulkTransactionUpdate
+				indexes []int
+			)
+			for i, change := range entry.Changes {
+				if change.Action != "" {
+					continue
+				}
+				updates = append(updates, lunchmoney.BulkTransactionUpdate{
+					ID:                change.TxID,
+					Current:           &currents[i],
+					TransactionUpdate: lunchmoney.RestoreUpdate(change.Before, change.Fields),
+				})
+				indexes = append(indexes, i)
+			}
+			updated, errs := client.UpdateTransactions(ctx, updates)
+			afterByID := make(map[int64]lunchmoney.Transaction, len(updated))
+			for _, tx := range updated {
+				afterByID[tx.ID] = tx
+			}
+
+			var failures []string
+			restored := 0
+			for j, i := range indexes {
+				change := entry.Changes[i]
+				if errs[j] != nil {
+					if !errors.Is(errs[j], lunchmoney.ErrNotSent) {
+						failures = append(failures, fmt.Sprintf("transaction %d: %v", change.TxID, errs[j]))
+					}
+					continue
+				}
+				undo.Changes = append(undo.Changes, journalChange{
+					TxID:   change.TxID,
+					Fields: updates[j].Fields(),
+					Before: currents[i],
+					After:  afterByID[change.TxID],
+				})
+				restored++
+			}
+			recordJournalEntry(ctx, undo)
+			if err := stopped(ctx, changedIDs(undo.Changes), len(entry.Changes)); err != nil {
+				return err
+			}
+
+			if len(failures) > 0 {
+				for _, f := range failures {
+					fmt.Fprintln(os.Stderr, f)
+				}
+				return fmt.Errorf("restored %d of %d transaction(s)", restored, len(updates))
+			}
+			if groups > 0 {
+				fmt.Printf("Undid entry %d (%d group(s) ungrouped, %d transaction(s) restored).\n", entry.ID, groups, restored)
+				return nil
+			}
+			fmt.Printf("Undid entry %d (%d transaction(s) restored).\n", entry.ID, restored)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Undo even if transactions changed since the entry")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Undo without asking for confirmation")
+
+	return cmd
+}
+
+func findJournalEntry(entries []journalEntry, raw string) (journalEntry, error) {
+	id, err := strconv.Atoi(raw)
+	if err != nil || id <= 0 {
+		return journalEntry{}, fmt.Errorf("invalid journal entry %q", raw)
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return journalEntry{}, fmt.Errorf("journal entry %d not found", id)
+}
+
+func printJournalEntry(e journalEntry, undoneByID int) {
+	fmt.Printf("Entry %d at %s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Command: %s\n", e.Command)
+	if e.UndoOf != 0 {
+		fmt.Printf("Undo of entry %d\n", e.UndoOf)
+	}
+	if undoneByID != 0 {
+		fmt.Printf("Undone by entry %d\n", undoneByID)
+	}
+	fmt.Println()
+
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "TX\tFIELD\tBEFORE\tAFTER")
+	for _, c := range e.Changes {
+		switch c.Action {
+		case journalDelete:
+			b := c.Before
+			fmt.Fprintf(w, "%d\t(deleted)\t%s %q %s\t\n", c.TxID, b.Date, b.Payee, b.Amount.Format(b.Currency))
+			continue
+		case journalGroup:
+			fmt.Fprintf(w, "%d\t(group)\t%s\t%q\n", c.TxID, joinIDs(c.Children), c.After.Payee)
+			continue
+		case journalUngroup:
+			fmt.Fprintf(w, "%d\t(ungroup)\t%q\t%s\n", c.TxID, c.Before.Payee, joinIDs(c.Children))
+			continue
+		}
+		for _, field := range c.Fields {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", c.TxID, field, journalFieldValue(c.Before, field), journalFieldValue(c.After, field))
+		}
+	}
+	_ = w.Flush()
+}
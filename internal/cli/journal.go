@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const journalFile = "journal.ndjson"
+
+// journalEntry records one mutating command so it can be reviewed with
+// `lm history` and reverted with `lm undo`.
+type journalEntry struct {
+	ID      int             `json:"id"`
+	Time    time.Time       `json:"time"`
+	Command string          `json:"command"`
+	UndoOf  int             `json:"undo_of,omitempty"`
+	Changes []journalChange `json:"changes"`
+}
+
+// journalChange holds the full transaction before and after a write along
+// with the API fields that were sent. Action is empty for field updates;
+// a delete keeps only Before, a group records the new parent as TxID and
+// After, with the grouped transactions in Children, and an ungroup keeps
+// the removed parent as Before.
+type journalChange struct {
+	TxID     int64                  `json:"tx_id"`
+	Action   string                 `json:"action,omitempty"`
+	Fields   []string               `json:"fields"`
+	Children []int64                `json:"children,omitempty"`
+	Before   lunchmoney.Transaction `json:"before"`
+	After    lunchmoney.Transaction `json:"after"`
+}
+
+// journalDelete, journalGroup and journalUngroup mark changes that deleted,
+// grouped or ungrouped transactions. `lm undo` reverses a group by
+// ungrouping it; deletes and ungroups cannot be reversed.
+const (
+	journalDelete  = "delete"
+	journalGroup   = "group"
+	journalUngroup = "ungroup"
+)
+
+func journalPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, journalFile), nil
+}
+
+func readJournal() ([]journalEntry, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e journalEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("corrupt journal entry in %s: %w", path, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func appendJournal(entry journalEntry) (journalEntry, error) {
+	path, err := journalPath()
+	if err != nil {
+		return entry, err
+	}
+	entries, err := readJournal()
+	if err != nil {
+		return entry, err
+	}
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return entry, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return entry, err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return entry, err
+}
+
+// recordMutation journals completed writes. A journal failure does not undo
+// the write, so it is reported as a warning rather than an error. Nothing is
+// recorded in dry-run mode.
+func recordMutation(ctx context.Context, changes []journalChange) {
+	recordJournalEntry(ctx, journalEntry{Command: commandLine(), Changes: changes})
+}
+
+func recordJournalEntry(ctx context.Context, entry journalEntry) {
+	if len(entry.Changes) == 0 || optionsFrom(ctx).dryRun {
+		return
+	}
+	clearCompletionCache()
+	if _, err := appendJournal(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record undo journal entry: %v\n", err)
+	}
+}
+
+func commandLine() string {
+	parts := []string{"lm"}
+	for _, arg := range os.Args[1:] {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// journalFieldValue renders a transaction's API field as compact JSON.
+func journalFieldValue(tx lunchmoney.Transaction, field string) string {
+	if field == "additional_tag_ids" {
+		field = "tag_ids"
+	}
+	raw, err := json.Marshal(tx)
+	if err != nil {
+		return ""
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return ""
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "null"
+	}
+	return string(value)
+}
+
+// undoable reports why lm undo cannot reverse an entry, if it cannot.
+func undoable(e journalEntry) error {
+	for _, c := range e.Changes {
+		switch c.Action {
+		case journalDelete:
+			return fmt.Errorf("entry %d deleted transaction %d, which cannot be restored (`lm history %d` shows what it was)", e.ID, c.TxID, e.ID)
+		case journalUngroup:
+			return fmt.Errorf("entry %d ungrouped transactions %s from %d, which lm cannot regroup", e.ID, joinIDs(c.Children), c.TxID)
+		}
+	}
+	return nil
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// undoneBy maps entry IDs to the ID of the entry that undid them.
+func undoneBy(entries []journalEntry) map[int]int {
+	undone := make(map[int]int)
+	for _, e := range entries {
+		if e.UndoOf != 0 {
+			undone[e.UndoOf] = e.ID
+		}
+	}
+	return undone
+}
+
+// changedIDs lists the transactions in changes, e.g. to report which writes
+// completed.
+func changedIDs(changes []journalChange) []int64 {
+	ids := make([]int64, len(changes))
+	for i, c := range changes {
+		ids[i] = c.TxID
+	}
+	return ids
+}
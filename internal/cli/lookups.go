@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// txLookups holds the reference data needed to turn raw transactions into
+// transactionViews and to resolve names given on the command line.
+type txLookups struct {
+	categories   []lunchmoney.Category
+	tags         []lunchmoney.Tag
+	categoryByID map[int64]categoryMeta
+	tagByID      map[int64]string
+	manual       map[int64]accountMeta
+	plaid        map[int64]accountMeta
+	baseCurrency string
+	classifier   txClassifier
+	// transferPairs is set by views when pairing is enabled.
+	transferPairs map[int64]int64
+}
+
+// loadTxLookups fetches the user and every lookup list concurrently.
+func loadTxLookups(ctx context.Context, client *lunchmoney.Client) (txLookups, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return txLookups{}, err
+	}
+	classifier, err := newTxClassifier(cfg.Classification)
+	if err != nil {
+		return txLookups{}, err
+	}
+
+	var (
+		me             lunchmoney.User
+		categories     []lunchmoney.Category
+		tags           []lunchmoney.Tag
+		manualAccounts []lunchmoney.ManualAccount
+		plaidAccounts  []lunchmoney.PlaidAccount
+	)
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		me, err = client.GetMe(ctx)
+		return err
+	})
+	g.Go(func() (err error) {
+		categories, err = client.ListCategories(ctx)
+		return err
+	})
+	g.Go(func() (err error) {
+		tags, err = client.ListTags(ctx)
+		return err
+	})
+	g.Go(func() (err error) {
+		manualAccounts, err = client.ListManualAccounts(ctx)
+		return err
+	})
+	g.Go(func() (err error) {
+		plaidAccounts, err = client.ListPlaidAccounts(ctx)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return txLookups{}, err
+	}
+
+	return txLookups{
+		categories:   categories,
+		tags:         tags,
+		categoryByID: buildCategoryLookup(categories),
+		tagByID:      buildTagLookup(tags),
+		manual:       buildManualAccountLookup(manualAccounts),
+		plaid:        buildPlaidAccountLookup(plaidAccounts),
+		baseCurrency: me.PrimaryCurrency,
+		classifier:   classifier,
+	}, nil
+}
+
+func (l txLookups) view(tx lunchmoney.Transaction) transactionView {
+	_, paired := l.transferPairs[tx.ID]
+	txType := l.classifier.classify(tx, l.categoryByID, paired)
+	return toTransactionView(tx, l.categoryByID, l.tagByID, l.manual, l.plaid, l.baseCurrency, txType)
+}
+
+// views converts a batch of transactions. Unlike view, it can pair opposite
+// transfer legs within the batch when classification.pair_window_days is set.
+func (l txLookups) views(txs []lunchmoney.Transaction) []transactionView {
+	if l.classifier.pairDays > 0 {
+		l.transferPairs = findTransferPairs(txs, l.classifier.pairDays)
+	}
+	views := make([]transactionView, 0, len(txs))
+	for _, tx := range txs {
+		views = append(views, l.view(tx))
+	}
+	return views
+}
+
+func buildTagLookup(tags []lunchmoney.Tag) map[int64]string {
+	lookup := make(map[int64]string, len(tags))
+	for _, t := range tags {
+		lookup[t.ID] = t.Name
+	}
+	return lookup
+}
@@ -0,0 +1,421 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// mcpProtocolVersions lists the Model Context Protocol revisions the server
+// speaks, newest first.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+func newMCPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Model Context Protocol server for agents",
+	}
+	cmd.AddCommand(newMCPServeCmd())
+	return cmd
+}
+
+func newMCPServeCmd() *cobra.Command {
+	var (
+		readOnly    bool
+		allowWrites bool
+		yes         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve Lunch Money tools over stdio using the Model Context Protocol",
+		Long: `Serve Lunch Money tools over stdio using the Model Context Protocol.
+
+Only tools that read data are served unless --allow-writes is set. With
+it, the write tools (update_transaction, mark_reviewed) ask the user to
+approve each change through the client when the client supports
+elicitation. Other clients get a preview and a confirm_token first, and the
+change is applied when the tool is called again with that token; the agent
+can make that call on its own, so --allow-writes is the user's approval.
+--yes applies writes without either step.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if readOnly && allowWrites {
+				return errors.New("--read-only and --allow-writes cannot be used together")
+			}
+			if yes && !allowWrites {
+				return errors.New("--yes requires --allow-writes")
+			}
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			server, err := newMCPServer(client, !allowWrites, yes)
+			if err != nil {
+				return err
+			}
+			return server.serve(cmd.Context(), os.Stdin, os.Stdout)
+		},
+	}
+
+	cmd.Flags().BoolVar(&readOnly, "read-only", false, "Only expose tools that read data")
+	cmd.Flags().BoolVar(&allowWrites, "allow-writes", false, "Also expose the tools that change transactions")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply writes without asking the user or returning a preview first")
+	_ = cmd.Flags().MarkDeprecated("read-only", "the server is read-only unless --allow-writes is set")
+
+	return cmd
+}
+
+type mcpServer struct {
+	client      *lunchmoney.Client
+	readOnly    bool
+	skipConfirm bool
+	// canElicit is set when the client advertised the elicitation
+	// capability, so writes can ask the user directly.
+	canElicit bool
+	// tokenKey signs the confirm tokens handed out with previews. It is
+	// random per process, so tokens cannot be made up or reused later.
+	tokenKey []byte
+
+	// in and enc are the stdio streams while serving; queued holds client
+	// messages read while waiting for an elicitation answer.
+	in      *bufio.Reader
+	enc     *json.Encoder
+	queued  [][]byte
+	elicits int
+}
+
+func newMCPServer(client *lunchmoney.Client, readOnly, skipConfirm bool) (*mcpServer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &mcpServer{client: client, readOnly: readOnly, skipConfirm: skipConfirm, tokenKey: key}, nil
+}
+
+// confirmToken is the HMAC of a tool name and the exact change it
+// previewed, including the transactions' updated_at, so a token only
+// applies the change the user was shown.
+func (s *mcpServer) confirmToken(tool string, change any) (string, error) {
+	raw, err := json.Marshal(change)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, s.tokenKey)
+	mac.Write([]byte(tool))
+	mac.Write([]byte{0})
+	mac.Write(raw)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// approve decides whether a write tool applies change. It asks the user
+// through elicitation when the client supports it; otherwise a call
+// without a token gets a preview with the token for change, and a token
+// that does not match this change is an error. When the change is not
+// applied, the returned result is the tool's answer.
+func (s *mcpServer) approve(ctx context.Context, tool string, change any, token string, preview []mcpChange) (mcpWriteResult, bool, error) {
+	if s.skipConfirm {
+		return mcpWriteResult{}, true, nil
+	}
+	if s.canElicit {
+		accepted, err := s.elicit(ctx, describeMCPChanges(tool, preview))
+		if err != nil {
+			return mcpWriteResult{}, false, err
+		}
+		if !accepted {
+			return mcpWriteResult{Message: "Not applied: the user did not approve the change.", Changes: preview}, false, nil
+		}
+		return mcpWriteResult{}, true, nil
+	}
+
+	want, err := s.confirmToken(tool, change)
+	if err != nil {
+		return mcpWriteResult{}, false, err
+	}
+	if token == "" {
+		return mcpWriteResult{
+			Message:      fmt.Sprintf("Not applied. Show this change to the user and, once they approve, call %s again with the same arguments and this confirm_token.", tool),
+			ConfirmToken: want,
+			Changes:      preview,
+		}, false, nil
+	}
+	if !hmac.Equal([]byte(token), []byte(want)) {
+		return mcpWriteResult{}, false, errors.New("confirm_token does not match this change (the arguments differ from the preview or the transactions changed since); call without confirm_token to preview it again")
+	}
+	return mcpWriteResult{}, true, nil
+}
+
+// elicit sends an elicitation/create request asking the user to approve
+// message and reads until the client answers it. Client requests that
+// arrive meanwhile are queued for serve. It reports whether the user
+// accepted.
+func (s *mcpServer) elicit(ctx context.Context, message string) (bool, error) {
+	if s.in == nil {
+		return false, errors.New("elicitation is only available while serving")
+	}
+	s.elicits++
+	id := fmt.Sprintf(`"lm-elicit-%d"`, s.elicits)
+	err := s.enc.Encode(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      json.RawMessage(id),
+		"method":  "elicitation/create",
+		"params": map[string]any{
+			"message":         message,
+			"requestedSchema": map[string]any{"type": "object", "properties": map[string]any{}},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		line, err := s.in.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var msg struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+				Result struct {
+					Action string `json:"action"`
+				} `json:"result"`
+				Error *rpcError `json:"error"`
+			}
+			switch {
+			case json.Unmarshal(line, &msg) != nil || msg.Method != "":
+				s.queued = append(s.queued, line)
+			case string(msg.ID) == id && msg.Error != nil:
+				return false, fmt.Errorf("asking the user to approve the change: %s", msg.Error.Message)
+			case string(msg.ID) == id:
+				return msg.Result.Action == "accept", nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return false, errors.New("the client closed the connection before the user answered")
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// serve handles newline-delimited JSON-RPC messages until in is closed.
+// Requests are answered in order; notifications get no reply.
+func (s *mcpServer) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.in = bufio.NewReader(in)
+	s.enc = json.NewEncoder(out)
+	for {
+		var (
+			line []byte
+			err  error
+		)
+		if len(s.queued) > 0 {
+			line, s.queued = s.queued[0], s.queued[1:]
+		} else {
+			line, err = s.in.ReadBytes('\n')
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if resp := s.handle(ctx, line); resp != nil {
+				if err := s.enc.Encode(resp); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			if len(s.queued) > 0 {
+				continue
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *mcpServer) handle(ctx context.Context, line []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: "parse error: " + err.Error()}}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID == nil {
+			return nil
+		}
+		return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}}
+	}
+
+	result, err := s.dispatch(ctx, req)
+	if req.ID == nil {
+		return nil
+	}
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, rpcErr
+	}
+	return resp
+}
+
+func (s *mcpServer) dispatch(ctx context.Context, req rpcRequest) (any, error) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+			Capabilities    struct {
+				Elicitation json.RawMessage `json:"elicitation"`
+			} `json:"capabilities"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		// Elicitation arrived in the 2025-06-18 revision.
+		s.canElicit = version == "2025-06-18" && len(params.Capabilities.Elicitation) > 0 && string(params.Capabilities.Elicitation) != "null"
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": "lm", "version": buildVersion()},
+			"instructions":    s.instructions(),
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.tools()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid tools/call params: " + err.Error()}
+		}
+		return s.callTool(ctx, params.Name, params.Arguments)
+	}
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+func (s *mcpServer) instructions() string {
+	text := "Tools for a Lunch Money budget. Amounts in transaction results are signed from the budget's view: negative is money out. Dates are YYYY-MM-DD."
+	switch {
+	case s.readOnly:
+		text += " The server is read-only."
+	case s.skipConfirm:
+		text += " Write tools apply changes immediately."
+	case s.canElicit:
+		text += " Write tools ask the user to approve each change before applying it."
+	default:
+		text += " Write tools return a preview and a confirm_token first; calling again with the same arguments plus that token applies the change. Show the user the preview and ask before applying it."
+	}
+	return text
+}
+
+func (s *mcpServer) tools() []mcpTool {
+	tools := make([]mcpTool, 0, len(mcpTools))
+	for _, t := range mcpTools {
+		if s.readOnly && !t.Annotations.ReadOnlyHint {
+			continue
+		}
+		tools = append(tools, t)
+	}
+	return tools
+}
+
+// callTool runs a tool. Failures inside the tool are returned as an error
+// result so the model can see and correct them; only unknown tools are
+// protocol errors.
+func (s *mcpServer) callTool(ctx context.Context, name string, args json.RawMessage) (any, error) {
+	idx := slices.IndexFunc(s.tools(), func(t mcpTool) bool { return t.Name == name })
+	if idx < 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", name)}
+	}
+	if len(bytes.TrimSpace(args)) == 0 || bytes.Equal(bytes.TrimSpace(args), []byte("null")) {
+		args = json.RawMessage("{}")
+	}
+
+	result, err := s.tools()[idx].call(ctx, s, args)
+	if err != nil {
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}, nil
+	}
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": string(text)}},
+		"structuredContent": result,
+	}, nil
+}
+
+// decodeToolArgs rejects unknown arguments so typos are reported instead of
+// silently ignored.
+func decodeToolArgs(args json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
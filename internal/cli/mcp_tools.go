@@ -0,0 +1,583 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+type mcpTool struct {
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	InputSchema  map[string]any     `json:"inputSchema"`
+	OutputSchema map[string]any     `json:"outputSchema,omitempty"`
+	Annotations  mcpToolAnnotations `json:"annotations"`
+
+	call func(ctx context.Context, s *mcpServer, args json.RawMessage) (any, error)
+}
+
+type mcpToolAnnotations struct {
+	ReadOnlyHint    bool `json:"readOnlyHint"`
+	DestructiveHint bool `json:"destructiveHint"`
+	IdempotentHint  bool `json:"idempotentHint"`
+}
+
+// Tool results. Output schemas are generated from these types, so the
+// transaction and category shapes always match `lm tx list --json` and
+// `lm category list --json`.
+type (
+	mcpTransactionList struct {
+		Transactions []transactionView `json:"transactions"`
+		Count        int               `json:"count"`
+		Truncated    bool              `json:"truncated"`
+	}
+	mcpTransaction struct {
+		Transaction transactionView `json:"transaction"`
+	}
+	mcpCategoryList struct {
+		Categories []categoryView `json:"categories"`
+	}
+	mcpTagList struct {
+		Tags []tagView `json:"tags"`
+	}
+	mcpAccountList struct {
+		Accounts []accountView `json:"accounts"`
+	}
+	mcpWriteResult struct {
+		Applied      bool        `json:"applied"`
+		Message      string      `json:"message"`
+		ConfirmToken string      `json:"confirm_token,omitempty"`
+		Changes      []mcpChange `json:"changes"`
+	}
+	mcpChange struct {
+		ID     int64           `json:"id"`
+		Before transactionView `json:"before"`
+		After  transactionView `json:"after"`
+	}
+)
+
+var mcpTools = []mcpTool{
+	{
+		Name:        "list_transactions",
+		Description: "List transactions in a date range with resolved category, account, tags and type (expense, income or transfer), newest first.",
+		InputSchema: objectSchema(map[string]any{
+			"start_date": stringSchema("First date (YYYY-MM-DD)"),
+			"end_date":   stringSchema("Last date (YYYY-MM-DD), defaults to today"),
+			"status":     enumSchema("Review status to include, defaults to all", "all", "reviewed", "unreviewed"),
+			"type":       enumSchema("Only transactions of this type", txTypeExpense, txTypeIncome, txTypeTransfer),
+			"where": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Filters like `payee~amazon`, `amount<-100` or `category=Groceries`, all of which must match. Fields: " + strings.Join(sortedWhereFields(), ", ") + ". Operators: = != ~ !~ < <= > >=.",
+			},
+			"limit": map[string]any{"type": "integer", "minimum": 1, "maximum": 1000, "description": "Maximum transactions to return, defaults to 100"},
+		}, "start_date"),
+		OutputSchema: jsonSchemaFor(reflect.TypeFor[mcpTransactionList]()),
+		Annotations:  mcpToolAnnotations{ReadOnlyHint: true, IdempotentHint: true},
+		call:         mcpListTransactions,
+	},
+	{
+		Name:         "get_transaction",
+		Description:  "Get one transaction by ID.",
+		InputSchema:  objectSchema(map[string]any{"id": idSchema("Transaction ID")}, "id"),
+		OutputSchema: jsonSchemaFor(reflect.TypeFor[mcpTransaction]()),
+		Annotations:  mcpToolAnnotations{ReadOnlyHint: true, IdempotentHint: true},
+		call:         mcpGetTransaction,
+	},
+	{
+		Name:         "list_categories",
+		Description:  "List assignable categories with their group.",
+		InputSchema:  objectSchema(map[string]any{}),
+		OutputSchema: jsonSchemaFor(reflect.TypeFor[mcpCategoryList]()),
+		Annotations:  mcpToolAnnotations{ReadOnlyHint: true, IdempotentHint: true},
+		call:         mcpListCategories,
+	},
+	{
+		Name:         "list_tags",
+		Description:  "List tags.",
+		InputSchema:  objectSchema(map[string]any{}),
+		OutputSchema: jsonSchemaFor(reflect.TypeFor[mcpTagList]()),
+		Annotations:  mcpToolAnnotations{ReadOnlyHint: true, IdempotentHint: true},
+		call:         mcpListTags,
+	},
+	{
+		Name:         "list_accounts",
+		Description:  "List manual and Plaid accounts with balances in their own currency.",
+		InputSchema:  objectSchema(map[string]any{}),
+		OutputSchema: jsonSchemaFor(reflect.TypeFor[mcpAccountList]()),
+		Annotations:  mcpToolAnnotations{ReadOnlyHint: true, IdempotentHint: true},
+		call:         mcpListAccounts,
+	},
+	{
+		Name:        "update_transaction",
+		Description: "Change a transaction's category, notes or tags. The user is asked to approve the change if the client supports elicitation; otherwise a call without confirm_token only previews it and returns the token to apply it.",
+		InputSchema: objectSchema(map[string]any{
+			"id":             idSchema("Transaction ID"),
+			"category":       stringSchema("Category name or ID"),
+			"clear_category": map[string]any{"type": "boolean", "description": "Remove the category"},
+			"notes":          stringSchema("New notes"),
+			"clear_notes":    map[string]any{"type": "boolean", "description": "Remove the notes"},
+			"tags":           map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Replace tags (names or IDs)"},
+			"add_tags":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Add tags (names or IDs)"},
+			"clear_tags":     map[string]any{"type": "boolean", "description": "Remove all tags"},
+			"confirm_token":  confirmSchema(),
+		}, "id"),
+		OutputSchema: jsonSchemaFor(reflect.TypeFor[mcpWriteResult]()),
+		Annotations:  mcpToolAnnotations{IdempotentHint: true},
+		call:         mcpUpdateTransaction,
+	},
+	{
+		Name:        "mark_reviewed",
+		Description: "Mark transactions as reviewed. The user is asked to approve the change if the client supports elicitation; otherwise a call without confirm_token only previews it and returns the token to apply it.",
+		InputSchema: objectSchema(map[string]any{
+			"ids":           map[string]any{"type": "array", "items": idSchema(""), "minItems": 1, "description": "Transaction IDs"},
+			"confirm_token": confirmSchema(),
+		}, "ids"),
+		OutputSchema: jsonSchemaFor(reflect.TypeFor[mcpWriteResult]()),
+		Annotations:  mcpToolAnnotations{IdempotentHint: true},
+		call:         mcpMarkReviewed,
+	},
+}
+
+func mcpListTransactions(ctx context.Context, s *mcpServer, raw json.RawMessage) (any, error) {
+	var args struct {
+		StartDate string   `json:"start_date"`
+		EndDate   string   `json:"end_date"`
+		Status    string   `json:"status"`
+		Type      string   `json:"type"`
+		Where     []string `json:"where"`
+		Limit     int      `json:"limit"`
+	}
+	if err := decodeToolArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.EndDate == "" {
+		args.EndDate = time.Now().Format("2006-01-02")
+	}
+	if err := validateDateRange(args.StartDate, args.EndDate); err != nil {
+		return nil, err
+	}
+	switch args.Status {
+	case "all":
+		args.Status = ""
+	case "", "reviewed", "unreviewed":
+	default:
+		return nil, fmt.Errorf("invalid status %q (expected all, reviewed or unreviewed)", args.Status)
+	}
+	switch args.Type {
+	case "", txTypeExpense, txTypeIncome, txTypeTransfer:
+	default:
+		return nil, fmt.Errorf("invalid type %q (expected expense, income or transfer)", args.Type)
+	}
+	preds, err := parseWhere(args.Where)
+	if err != nil {
+		return nil, err
+	}
+	if args.Limit == 0 {
+		args.Limit = 100
+	}
+	if args.Limit < 0 || args.Limit > 1000 {
+		return nil, errors.New("limit must be between 1 and 1000")
+	}
+
+	transactions, err := s.client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+		StartDate: args.StartDate,
+		EndDate:   args.EndDate,
+		Status:    args.Status,
+		Limit:     1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lookups, err := loadTxLookups(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]transactionView, 0, len(transactions))
+	for _, v := range lookups.views(transactions) {
+		if args.Type != "" && v.Type != args.Type {
+			continue
+		}
+		if !matchesWhere(v, preds) {
+			continue
+		}
+		views = append(views, v)
+	}
+	sortTransactionsNewestFirst(views)
+
+	result := mcpTransactionList{Count: len(views)}
+	if len(views) > args.Limit {
+		views = views[:args.Limit]
+		result.Truncated = true
+	}
+	result.Transactions = views
+	return result, nil
+}
+
+func mcpGetTransaction(ctx context.Context, s *mcpServer, raw json.RawMessage) (any, error) {
+	var args struct {
+		ID int64 `json:"id"`
+	}
+	if err := decodeToolArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	tx, err := s.client.GetTransaction(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	lookups, err := loadTxLookups(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+	return mcpTransaction{Transaction: lookups.view(tx)}, nil
+}
+
+func mcpListCategories(ctx context.Context, s *mcpServer, raw json.RawMessage) (any, error) {
+	if err := decodeToolArgs(raw, &struct{}{}); err != nil {
+		return nil, err
+	}
+	categories, err := s.client.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mcpCategoryList{Categories: toCategoryViews(categories)}, nil
+}
+
+func mcpListTags(ctx context.Context, s *mcpServer, raw json.RawMessage) (any, error) {
+	if err := decodeToolArgs(raw, &struct{}{}); err != nil {
+		return nil, err
+	}
+	tags, err := s.client.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mcpTagList{Tags: toTagViews(tags)}, nil
+}
+
+func mcpListAccounts(ctx context.Context, s *mcpServer, raw json.RawMessage) (any, error) {
+	if err := decodeToolArgs(raw, &struct{}{}); err != nil {
+		return nil, err
+	}
+	manual, err := s.client.ListManualAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plaid, err := s.client.ListPlaidAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mcpAccountList{Accounts: toAccountViews(manual, plaid)}, nil
+}
+
+func mcpUpdateTransaction(ctx context.Context, s *mcpServer, raw json.RawMessage) (any, error) {
+	var args struct {
+		ID            int64    `json:"id"`
+		Category      *string  `json:"category"`
+		ClearCategory bool     `json:"clear_category"`
+		Notes         *string  `json:"notes"`
+		ClearNotes    bool     `json:"clear_notes"`
+		Tags          []string `json:"tags"`
+		AddTags       []string `json:"add_tags"`
+		ClearTags     bool     `json:"clear_tags"`
+		ConfirmToken  string   `json:"confirm_token"`
+	}
+	if err := decodeToolArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.ID <= 0 {
+		return nil, errors.New("id must be a positive integer")
+	}
+	if args.Category != nil && args.ClearCategory {
+		return nil, errors.New("category and clear_category cannot be used together")
+	}
+	if args.Notes != nil && args.ClearNotes {
+		return nil, errors.New("notes and clear_notes cannot be used together")
+	}
+	tagOptions := 0
+	for _, set := range []bool{args.Tags != nil, args.AddTags != nil, args.ClearTags} {
+		if set {
+			tagOptions++
+		}
+	}
+	if tagOptions > 1 {
+		return nil, errors.New("tags, add_tags and clear_tags cannot be used together")
+	}
+
+	lookups, err := loadTxLookups(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+	var update lunchmoney.TransactionUpdate
+	if args.Category != nil {
+		id, err := resolveCategoryID(lookups.categories, *args.Category)
+		if err != nil {
+			return nil, err
+		}
+		update.CategoryID = &id
+	}
+	update.ClearCategory = args.ClearCategory
+	if args.Notes != nil {
+		if strings.TrimSpace(*args.Notes) == "" {
+			return nil, errors.New("notes cannot be empty (use clear_notes to remove them)")
+		}
+		update.Notes = args.Notes
+	}
+	update.ClearNotes = args.ClearNotes
+	if args.Tags != nil {
+		if update.TagIDs, err = resolveTagIDs(lookups.tags, args.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if args.AddTags != nil {
+		if update.AdditionalTagIDs, err = resolveTagIDs(lookups.tags, args.AddTags); err != nil {
+			return nil, err
+		}
+	}
+	update.ClearTags = args.ClearTags
+	if len(updatedFieldNames(update)) == 0 {
+		return nil, errors.New("nothing to update: set category, notes or tags")
+	}
+
+	before, err := s.client.GetTransaction(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	change := struct {
+		ID        int64
+		UpdatedAt string
+		Update    lunchmoney.TransactionUpdate
+	}{args.ID, before.UpdatedAt, update}
+	preview := []mcpChange{{ID: before.ID, Before: lookups.view(before), After: lookups.view(previewUpdate(before, update))}}
+	result, apply, err := s.approve(ctx, "update_transaction", change, args.ConfirmToken, preview)
+	if err != nil {
+		return nil, err
+	}
+	if !apply {
+		return result, nil
+	}
+
+	after, err := s.client.UpdateTransaction(ctx, args.ID, update)
+	if err != nil {
+		return nil, err
+	}
+	recordMutation(ctx, []journalChange{{TxID: args.ID, Fields: update.Fields(), Before: before, After: after}})
+	return mcpWriteResult{
+		Applied: true,
+		Message: fmt.Sprintf("Updated transaction %d (%s).", args.ID, strings.Join(updatedFieldNames(update), ", ")),
+		Changes: []mcpChange{{ID: args.ID, Before: lookups.view(before), After: lookups.view(after)}},
+	}, nil
+}
+
+func mcpMarkReviewed(ctx context.Context, s *mcpServer, raw json.RawMessage) (any, error) {
+	var args struct {
+		IDs          []int64 `json:"ids"`
+		ConfirmToken string  `json:"confirm_token"`
+	}
+	if err := decodeToolArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	if len(args.IDs) == 0 {
+		return nil, errors.New("ids must list at least one transaction")
+	}
+	for _, id := range args.IDs {
+		if id <= 0 {
+			return nil, fmt.Errorf("invalid transaction id %d", id)
+		}
+	}
+
+	lookups, err := loadTxLookups(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+	befores := make(map[int64]lunchmoney.Transaction, len(args.IDs))
+	for _, id := range args.IDs {
+		tx, err := s.client.GetTransaction(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		befores[id] = tx
+	}
+
+	type reviewed struct {
+		ID        int64
+		UpdatedAt string
+	}
+	change := make([]reviewed, len(args.IDs))
+	for i, id := range args.IDs {
+		change[i] = reviewed{id, befores[id].UpdatedAt}
+	}
+	preview := make([]mcpChange, 0, len(args.IDs))
+	for _, id := range args.IDs {
+		after := befores[id]
+		after.Status = "reviewed"
+		preview = append(preview, mcpChange{ID: id, Before: lookups.view(befores[id]), After: lookups.view(after)})
+	}
+	result, apply, err := s.approve(ctx, "mark_reviewed", change, args.ConfirmToken, preview)
+	if err != nil {
+		return nil, err
+	}
+	if !apply {
+		return result, nil
+	}
+
+	updated, err := s.client.MarkReviewed(ctx, args.IDs)
+	changes := make([]journalChange, 0, len(updated))
+	// changes is an array in the output schema, so it must not be null.
+	result = mcpWriteResult{Applied: true, Changes: []mcpChange{}}
+	for _, tx := range updated {
+		changes = append(changes, journalChange{TxID: tx.ID, Fields: []string{"status"}, Before: befores[tx.ID], After: tx})
+		result.Changes = append(result.Changes, mcpChange{ID: tx.ID, Before: lookups.view(befores[tx.ID]), After: lookups.view(tx)})
+	}
+	recordMutation(ctx, changes)
+	if err != nil {
+		return nil, err
+	}
+	result.Message = fmt.Sprintf("Marked %d transaction(s) as reviewed.", len(updated))
+	return result, nil
+}
+
+// previewUpdate applies the category, notes and tag parts of an update to a
+// copy of tx, for showing what a write would do.
+func previewUpdate(tx lunchmoney.Transaction, u lunchmoney.TransactionUpdate) lunchmoney.Transaction {
+	switch {
+	case u.CategoryID != nil:
+		tx.CategoryID = u.CategoryID
+	case u.ClearCategory:
+		tx.CategoryID = nil
+	}
+	switch {
+	case u.Notes != nil:
+		tx.Notes = u.Notes
+	case u.ClearNotes:
+		tx.Notes = nil
+	}
+	switch {
+	case u.TagIDs != nil:
+		tx.TagIDs = u.TagIDs
+	case u.AdditionalTagIDs != nil:
+		tags := slices.Clone(tx.TagIDs)
+		for _, id := range u.AdditionalTagIDs {
+			if !slices.Contains(tags, id) {
+				tags = append(tags, id)
+			}
+		}
+		tx.TagIDs = tags
+	case u.ClearTags:
+		tx.TagIDs = nil
+	}
+	return tx
+}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringSchema(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func enumSchema(description string, values ...string) map[string]any {
+	return map[string]any{"type": "string", "enum": values, "description": description}
+}
+
+func idSchema(description string) map[string]any {
+	schema := map[string]any{"type": "integer", "minimum": 1}
+	if description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+func confirmSchema() map[string]any {
+	return map[string]any{"type": "string", "description": "The confirm_token returned by the preview of this exact change. Pass it once the user has seen the preview and agreed."}
+}
+
+// describeMCPChanges is the approval prompt for a write: one line per
+// transaction and one per field that changes.
+func describeMCPChanges(tool string, changes []mcpChange) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Allow %s to change %d transaction(s)?", tool, len(changes))
+	for _, c := range changes {
+		fmt.Fprintf(&b, "\n%d  %s  %s  %s", c.ID, c.Before.Date, c.Before.Description, c.Before.Amount.Format(c.Before.Currency))
+		for _, f := range []struct{ name, before, after string }{
+			{"category", c.Before.Category, c.After.Category},
+			{"notes", c.Before.Notes, c.After.Notes},
+			{"tags", c.Before.Tags, c.After.Tags},
+			{"status", c.Before.Status, c.After.Status},
+		} {
+			if f.before != f.after {
+				fmt.Fprintf(&b, "\n  %s: %q -> %q", f.name, f.before, f.after)
+			}
+		}
+	}
+	return b.String()
+}
+
+func sortedWhereFields() []string {
+	fields := make([]string, 0, len(whereFields))
+	for f := range whereFields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// jsonSchemaFor describes the JSON encoding of t. Fields tagged omitempty
+// are optional; everything else is required.
+func jsonSchemaFor(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[lunchmoney.Amount]() {
+		return map[string]any{"type": "number"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchemaFor(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any, t.NumField())
+		required := []string{}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = jsonSchemaFor(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	}
+	return map[string]any{}
+}
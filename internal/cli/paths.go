@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const envConfigDir = "LM_CONFIG_DIR"
+
+// configDir is where lm keeps local state such as the undo journal. It
+// defaults to <user config dir>/lm and can be overridden with LM_CONFIG_DIR.
+func configDir() (string, error) {
+	if dir := os.Getenv(envConfigDir); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "lm"), nil
+}
+
+const envCacheDir = "LM_CACHE_DIR"
+
+// cacheDir is where lm keeps data it can fetch again, such as completion
+// suggestions. It defaults to <user cache dir>/lm and can be overridden with
+// LM_CACHE_DIR.
+func cacheDir() (string, error) {
+	if dir := os.Getenv(envCacheDir); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "lm"), nil
+}
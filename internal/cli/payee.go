@@ -0,0 +1,551 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// payeeAlias maps raw payees matching a case-insensitive regular expression
+// to a canonical name. Aliases are read from payee_aliases in config.json.
+type payeeAlias struct {
+	Match string `json:"match"`
+	Name  string `json:"name"`
+}
+
+type payeeSummary struct {
+	Payee    string            `json:"payee"`
+	Count    int               `json:"count"`
+	Total    lunchmoney.Amount `json:"total"`
+	Currency string            `json:"currency"`
+	LastDate string            `json:"last_date"`
+}
+
+// payeeCluster is a group of raw payees that should share one name.
+type payeeCluster struct {
+	Name     string         `json:"name"`
+	Source   string         `json:"source"`
+	Count    int            `json:"count"`
+	Variants []payeeVariant `json:"variants"`
+	Renames  []int64        `json:"renames"`
+
+	txs []lunchmoney.Transaction
+}
+
+type payeeVariant struct {
+	Payee string `json:"payee"`
+	Count int    `json:"count"`
+}
+
+func newPayeeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "payee",
+		Short: "Payee operations",
+	}
+	cmd.AddCommand(newPayeeListCmd())
+	cmd.AddCommand(newPayeeNormalizeCmd())
+	return cmd
+}
+
+func newPayeeListCmd() *cobra.Command {
+	var (
+		startDate  string
+		endDate    string
+		raw        bool
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List distinct payees with transaction counts and totals",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endDate == "" {
+				endDate = time.Now().Format("2006-01-02")
+			}
+			if err := validateDateRange(startDate, endDate); err != nil {
+				return err
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			me, err := client.GetMe(ctx)
+			if err != nil {
+				return err
+			}
+			summaries, err := summarizePayees(client.IterTransactions(ctx, lunchmoney.ListTransactionsParams{
+				StartDate: startDate,
+				EndDate:   endDate,
+				Limit:     1000,
+			}), raw, me.PrimaryCurrency)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(summaries)
+			}
+			printPayeeSummaries(summaries)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
+	cmd.Flags().BoolVar(&raw, "raw", false, "Group by the original payee name from the source instead of the current payee")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON")
+	_ = cmd.MarkFlagRequired("start")
+
+	return cmd
+}
+
+func newPayeeNormalizeCmd() *cobra.Command {
+	var (
+		startDate  string
+		endDate    string
+		apply      bool
+		yes        bool
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "normalize",
+		Short: "Suggest canonical payee names and optionally rename matching transactions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endDate == "" {
+				endDate = time.Now().Format("2006-01-02")
+			}
+			if err := validateDateRange(startDate, endDate); err != nil {
+				return err
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			aliases, err := compilePayeeAliases(cfg.PayeeAliases)
+			if err != nil {
+				return err
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			transactions, err := client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+				StartDate: startDate,
+				EndDate:   endDate,
+				Limit:     1000,
+			})
+			if err != nil {
+				return err
+			}
+
+			clusters := clusterPayees(transactions, aliases)
+			if jsonOutput {
+				return printJSON(clusters)
+			}
+			if len(clusters) == 0 {
+				fmt.Println("All payees are already normalized.")
+				return nil
+			}
+			printPayeeClusters(clusters)
+
+			total := 0
+			for _, c := range clusters {
+				total += len(c.Renames)
+			}
+			fmt.Printf("%d transaction(s) to rename across %d payee(s).\n", total, len(clusters))
+			if !apply {
+				return nil
+			}
+
+			if !yes && !client.DryRun() {
+				ok, err := confirm(cmd.Context(), fmt.Sprintf("Rename %d transaction(s)?", total))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+			return renamePayees(ctx, client, clusters)
+		},
+	}
+
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Rename transactions to the suggested payees")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation prompt for --apply")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output suggestions as JSON")
+	cmd.MarkFlagsMutuallyExclusive("apply", "json")
+	_ = cmd.MarkFlagRequired("start")
+
+	return cmd
+}
+
+// summarizePayees consumes transactions as they stream in, keeping only one
+// running summary per payee.
+func summarizePayees(transactions iter.Seq2[lunchmoney.Transaction, error], raw bool, baseCurrency string) ([]payeeSummary, error) {
+	byPayee := make(map[string]*payeeSummary)
+	for tx, err := range transactions {
+		if err != nil {
+			return nil, err
+		}
+		name := tx.Payee
+		if raw {
+			name = rawPayee(tx)
+		}
+		s, ok := byPayee[name]
+		if !ok {
+			s = &payeeSummary{Payee: name, Currency: baseCurrency}
+			byPayee[name] = s
+		}
+		s.Count++
+		s.Total += tx.ToBase.Neg()
+		if tx.Date > s.LastDate {
+			s.LastDate = tx.Date
+		}
+	}
+
+	summaries := make([]payeeSummary, 0, len(byPayee))
+	for _, s := range byPayee {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		return summaries[i].Payee < summaries[j].Payee
+	})
+	return summaries, nil
+}
+
+// rawPayee is the name from the source (bank, CSV), falling back to the
+// current payee for transactions that predate original_name.
+func rawPayee(tx lunchmoney.Transaction) string {
+	if tx.OriginalName != nil && strings.TrimSpace(*tx.OriginalName) != "" {
+		return *tx.OriginalName
+	}
+	return tx.Payee
+}
+
+type compiledPayeeAlias struct {
+	re   *regexp.Regexp
+	name string
+}
+
+func compilePayeeAliases(aliases []payeeAlias) ([]compiledPayeeAlias, error) {
+	compiled := make([]compiledPayeeAlias, 0, len(aliases))
+	for i, a := range aliases {
+		if a.Match == "" || strings.TrimSpace(a.Name) == "" {
+			return nil, fmt.Errorf("payee alias %d: match and name are required", i+1)
+		}
+		re, err := regexp.Compile("(?i)" + a.Match)
+		if err != nil {
+			return nil, fmt.Errorf("payee alias %d: invalid match pattern: %w", i+1, err)
+		}
+		compiled = append(compiled, compiledPayeeAlias{re: re, name: a.Name})
+	}
+	return compiled, nil
+}
+
+// clusterPayees groups transactions by alias or by the cleaned raw payee,
+// folding a name the bank cut short into the full one ("blue bottle cof"
+// into "blue bottle coffee"), and picks a canonical name per group: the
+// alias, the name the user most often gave the group's transactions, or the
+// cleaned raw payee in title case. Names that differ in any other word, such
+// as "zelle payment to john" and "zelle payment to mary", stay apart. Only
+// groups with transactions to rename are returned.
+func clusterPayees(transactions []lunchmoney.Transaction, aliases []compiledPayeeAlias) []payeeCluster {
+	byKey := make(map[string]*payeeCluster)
+	var order []string
+	for _, tx := range transactions {
+		raw := rawPayee(tx)
+		key, name, source := "", "", ""
+		for _, a := range aliases {
+			if a.re.MatchString(raw) || a.re.MatchString(tx.Payee) {
+				key, name, source = "alias:"+a.name, a.name, "alias"
+				break
+			}
+		}
+		if key == "" {
+			cleaned := cleanPayee(raw)
+			if cleaned == "" {
+				continue
+			}
+			key = cleaned
+		}
+
+		c, ok := byKey[key]
+		if !ok {
+			c = &payeeCluster{Name: name, Source: source}
+			byKey[key] = c
+			order = append(order, key)
+		}
+		c.txs = append(c.txs, tx)
+	}
+
+	// Fold each truncated name into the most common full name it starts.
+	for _, key := range order {
+		if strings.HasPrefix(key, "alias:") {
+			continue
+		}
+		var target string
+		for _, other := range order {
+			if other == key || strings.HasPrefix(other, "alias:") || byKey[other] == nil || !truncatedPayee(key, other) {
+				continue
+			}
+			if target == "" || len(byKey[other].txs) > len(byKey[target].txs) {
+				target = other
+			}
+		}
+		if target != "" {
+			byKey[target].txs = append(byKey[target].txs, byKey[key].txs...)
+			byKey[key] = nil
+		}
+	}
+
+	clusters := make([]payeeCluster, 0, len(byKey))
+	for _, key := range order {
+		c := byKey[key]
+		if c == nil {
+			continue
+		}
+		if c.Source == "" {
+			c.Name, c.Source = canonicalPayee(c.txs)
+		}
+
+		variants := make(map[string]int)
+		for _, tx := range c.txs {
+			variants[tx.Payee]++
+			if tx.Payee != c.Name {
+				c.Renames = append(c.Renames, tx.ID)
+			}
+		}
+		if len(c.Renames) == 0 {
+			continue
+		}
+		for payee, n := range variants {
+			c.Variants = append(c.Variants, payeeVariant{Payee: payee, Count: n})
+		}
+		sort.Slice(c.Variants, func(i, j int) bool {
+			if c.Variants[i].Count != c.Variants[j].Count {
+				return c.Variants[i].Count > c.Variants[j].Count
+			}
+			return c.Variants[i].Payee < c.Variants[j].Payee
+		})
+		c.Count = len(c.txs)
+		clusters = append(clusters, *c)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].Renames) > len(clusters[j].Renames)
+	})
+	return clusters
+}
+
+// truncatedPayee reports whether the cleaned name short is full cut off
+// mid-word: the same words, except that short's last word is a shorter
+// prefix (of at least three letters) of full's word in that place.
+func truncatedPayee(short, full string) bool {
+	s, f := strings.Fields(short), strings.Fields(full)
+	if len(s) == 0 || len(s) != len(f) {
+		return false
+	}
+	last := len(s) - 1
+	for i := range last {
+		if s[i] != f[i] {
+			return false
+		}
+	}
+	return utf8.RuneCountInString(s[last]) >= 3 && len(s[last]) < len(f[last]) && strings.HasPrefix(f[last], s[last])
+}
+
+// canonicalPayee prefers a name the user already chose (a payee that differs
+// from the raw name), then a payee that is already the cleaned name, and
+// otherwise title-cases the most common cleaned name.
+func canonicalPayee(txs []lunchmoney.Transaction) (string, string) {
+	renamed := make(map[string]int)
+	cleaned := make(map[string]int)
+	for _, tx := range txs {
+		if tx.Payee != "" && tx.Payee != rawPayee(tx) {
+			renamed[tx.Payee]++
+		}
+		cleaned[cleanPayee(rawPayee(tx))]++
+	}
+	if name := mostCommon(renamed); name != "" {
+		return name, "existing"
+	}
+
+	// Keep a payee that is already clean rather than re-casing it.
+	best := mostCommon(cleaned)
+	current := make(map[string]int)
+	for _, tx := range txs {
+		if strings.EqualFold(strings.TrimSpace(tx.Payee), best) {
+			current[tx.Payee]++
+		}
+	}
+	if name := mostCommon(current); name != "" {
+		return name, "existing"
+	}
+	return titleCase(best), "cleaned"
+}
+
+func mostCommon(counts map[string]int) string {
+	best, bestCount := "", 0
+	for name, n := range counts {
+		if n > bestCount || (n == bestCount && name < best) {
+			best, bestCount = name, n
+		}
+	}
+	return best
+}
+
+var (
+	// payeeProcessorPrefix matches card processor and wallet prefixes such as
+	// "SQ *", "TST* ", "PAYPAL *" and "POS DEBIT".
+	payeeProcessorPrefix = regexp.MustCompile(`^(?:(?:sq|sqc|tst|sp|pp|paypal|dd|ic|py|gglpay|google|apple ?pay|cke|bt|fs|pos|ach|debit|purchase)\s*\*\s*|(?:pos(?: debit| purchase)?|debit card purchase|checkcard(?: \d+)?|recurring (?:payment|debit)|purchase authorized on [\d/]+)\s+)`)
+	payeeNonWord         = regexp.MustCompile(`[^\p{L}\p{N}&' ]+`)
+	payeeHasDigit        = regexp.MustCompile(`\p{N}`)
+	usStateCodes         = map[string]bool{
+		"al": true, "ak": true, "az": true, "ar": true, "ca": true, "co": true, "ct": true, "de": true,
+		"dc": true, "fl": true, "ga": true, "hi": true, "id": true, "il": true, "in": true, "ia": true,
+		"ks": true, "ky": true, "la": true, "me": true, "md": true, "ma": true, "mi": true, "mn": true,
+		"ms": true, "mo": true, "mt": true, "ne": true, "nv": true, "nh": true, "nj": true, "nm": true,
+		"ny": true, "nc": true, "nd": true, "oh": true, "ok": true, "or": true, "pa": true, "ri": true,
+		"sc": true, "sd": true, "tn": true, "tx": true, "ut": true, "vt": true, "va": true, "wa": true,
+		"wv": true, "wi": true, "wy": true,
+	}
+)
+
+// cleanPayee lowercases a raw payee and strips processor prefixes, store and
+// reference numbers, and a trailing "CITY ST" location:
+// "SQ *BLUE BOTTLE COF 1234 OAKLAND CA" becomes "blue bottle cof".
+func cleanPayee(raw string) string {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	for {
+		stripped := payeeProcessorPrefix.ReplaceAllString(s, "")
+		if stripped == s {
+			break
+		}
+		s = strings.TrimSpace(stripped)
+	}
+	// Anything after a "*" is a processor reference, e.g. "amazon.com*2k4".
+	if before, _, ok := strings.Cut(s, "*"); ok && strings.TrimSpace(before) != "" {
+		s = before
+	}
+	s = payeeNonWord.ReplaceAllString(s, " ")
+
+	words := make([]string, 0, 8)
+	for _, w := range strings.Fields(s) {
+		if !payeeHasDigit.MatchString(w) {
+			words = append(words, w)
+		}
+	}
+	// Drop "CITY ST" only when a name of at least two words remains.
+	if n := len(words); n >= 4 && usStateCodes[words[n-1]] {
+		words = words[:n-2]
+	} else if n >= 3 && usStateCodes[words[n-1]] {
+		words = words[:n-1]
+	}
+	return strings.Join(words, " ")
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToTitle(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// renamePayees bulk-updates payee only; original_name is never sent, so the
+// source name is preserved.
+func renamePayees(ctx context.Context, client *lunchmoney.Client, clusters []payeeCluster) error {
+	var updates []lunchmoney.BulkTransactionUpdate
+	befores := make(map[int64]lunchmoney.Transaction)
+	for _, c := range clusters {
+		name := c.Name
+		for _, tx := range c.txs {
+			if tx.Payee == name {
+				continue
+			}
+			updates = append(updates, lunchmoney.BulkTransactionUpdate{ID: tx.ID, Current: &tx, TransactionUpdate: lunchmoney.TransactionUpdate{Payee: &name}})
+			befores[tx.ID] = tx
+		}
+	}
+
+	updated, errs := client.UpdateTransactions(ctx, updates)
+	afterByID := make(map[int64]lunchmoney.Transaction, len(updated))
+	for _, tx := range updated {
+		afterByID[tx.ID] = tx
+	}
+	changes := make([]journalChange, 0, len(updates))
+	failed := 0
+	for i, err := range errs {
+		id := updates[i].ID
+		if err != nil {
+			failed++
+			if !errors.Is(err, lunchmoney.ErrNotSent) {
+				fmt.Fprintf(os.Stderr, "failed to rename transaction %d: %v\n", id, err)
+			}
+			continue
+		}
+		changes = append(changes, journalChange{TxID: id, Fields: []string{"payee"}, Before: befores[id], After: afterByID[id]})
+	}
+	recordMutation(ctx, changes)
+	if err := stopped(ctx, changedIDs(changes), len(updates)); err != nil {
+		return err
+	}
+
+	if client.DryRun() {
+		fmt.Printf("Would rename %d transaction(s).\n", len(changes))
+	} else {
+		fmt.Printf("Renamed %d transaction(s).\n", len(changes))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d transaction(s) failed to update", failed)
+	}
+	return nil
+}
+
+func printPayeeSummaries(summaries []payeeSummary) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "PAYEE\tCOUNT\tTOTAL\tLAST_DATE")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", s.Payee, s.Count, formatAmountWithCurrency(s.Total, s.Currency), s.LastDate)
+	}
+	_ = w.Flush()
+}
+
+func printPayeeClusters(clusters []payeeCluster) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "NAME\tSOURCE\tTXS\tRENAMES\tVARIANTS")
+	for _, c := range clusters {
+		variants := make([]string, 0, len(c.Variants))
+		for _, v := range c.Variants {
+			variants = append(variants, fmt.Sprintf("%s (%d)", v.Payee, v.Count))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", c.Name, c.Source, c.Count, len(c.Renames), strings.Join(variants, "; "))
+	}
+	_ = w.Flush()
+}
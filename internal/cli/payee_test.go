@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"maps"
+	"testing"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func TestClusterPayees(t *testing.T) {
+	var txs []lunchmoney.Transaction
+	add := func(payees ...string) {
+		for _, p := range payees {
+			txs = append(txs, lunchmoney.Transaction{ID: int64(len(txs) + 1), Payee: p})
+		}
+	}
+	add(
+		"ZELLE PAYMENT TO JOHN", "ZELLE PAYMENT TO JOHN", "ZELLE PAYMENT TO MARY",
+		"Online Transfer To Savings", "Online Transfer To Savings", "ONLINE TRANSFER FROM CHECKING",
+		"SQ *BLUE BOTTLE COF 1234 OAKLAND CA", "BLUE BOTTLE COFFEE", "Blue Bottle Coffee", "Blue Bottle Coffee",
+		"CAFÉ ROUGE 22",
+	)
+
+	renamed := map[int64]string{}
+	for _, c := range clusterPayees(txs, nil) {
+		for _, id := range c.Renames {
+			renamed[id] = c.Name
+		}
+	}
+	want := map[int64]string{
+		7:  "Blue Bottle Coffee",
+		8:  "Blue Bottle Coffee",
+		11: "Café Rouge",
+	}
+	if !maps.Equal(renamed, want) {
+		t.Errorf("renames = %v, want %v", renamed, want)
+	}
+}
+
+func TestTitleCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"blue bottle":  "Blue Bottle",
+		"éclair cafe":  "Éclair Cafe",
+		"ça va":        "Ça Va",
+		"":             "",
+		"  two  gaps ": "Two Gaps",
+	} {
+		if got := titleCase(in); got != want {
+			t.Errorf("titleCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// confirm asks a yes/no question on the controlling terminal. The terminal is
+// used instead of stdin so commands that read their input from a pipe can
+// still prompt; without a terminal the answer is no. If ctx is cancelled
+// while waiting, e.g. by Ctrl-C, confirm returns an error at once.
+func confirm(ctx context.Context, question string) (bool, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, fmt.Errorf("cannot prompt for confirmation without a terminal (use --yes)")
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "%s [y/N] ", question)
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := bufio.NewReader(tty).ReadString('\n')
+		answers <- answer
+	}()
+	var answer string
+	select {
+	case answer = <-answers:
+	case <-ctx.Done():
+		fmt.Fprintln(tty)
+		return false, stopped(ctx, nil, 0)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// readSecret reads a line from the controlling terminal with echo turned
+// off. It is a variable so tests can answer without a terminal.
+var readSecret = func(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("cannot prompt for %s without a terminal", strings.TrimSuffix(prompt, ": "))
+	}
+	defer tty.Close()
+
+	if err := stty(tty, "-echo"); err != nil {
+		return "", fmt.Errorf("cannot turn off terminal echo: %w", err)
+	}
+	defer func() {
+		_ = stty(tty, "echo")
+		fmt.Fprintln(tty)
+	}()
+	// Restore echo on Ctrl-C too: main's signal handler keeps the process
+	// alive, so the deferred calls still run.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+
+	fmt.Fprint(tty, prompt)
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(tty).ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		return strings.TrimRight(line, "\r\n"), nil
+	case sig := <-interrupts:
+		return "", &exitError{code: exitCodeInterrupted, msg: sig.String() + " signal received"}
+	}
+}
+
+func stty(tty *os.File, args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
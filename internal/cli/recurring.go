@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// recurringCadence describes one supported billing interval.
+type recurringCadence struct {
+	name          string
+	minDays       float64
+	maxDays       float64
+	perYear       float64
+	years, months int
+	days          int
+}
+
+var recurringCadences = []recurringCadence{
+	{name: "weekly", minDays: 6, maxDays: 8, perYear: 52, days: 7},
+	{name: "biweekly", minDays: 13, maxDays: 16, perYear: 26, days: 14},
+	{name: "monthly", minDays: 26, maxDays: 35, perYear: 12, months: 1},
+	{name: "quarterly", minDays: 85, maxDays: 97, perYear: 4, months: 3},
+	{name: "annual", minDays: 350, maxDays: 380, perYear: 1, years: 1},
+}
+
+func (c recurringCadence) next(t time.Time) time.Time {
+	return t.AddDate(c.years, c.months, c.days)
+}
+
+func (c recurringCadence) nominalDays() float64 {
+	return 365.25 / c.perYear
+}
+
+// recurringSeries is a run of charges (or deposits) from one payee at a
+// regular cadence. Charges are oldest first and use the API sign (positive is
+// money out); a series never mixes signs.
+type recurringSeries struct {
+	key     string
+	payee   string
+	cadence recurringCadence
+	charges []lunchmoney.Transaction
+}
+
+// income reports whether the series is money coming in, e.g. a paycheck.
+func (s recurringSeries) income() bool {
+	return s.charges[0].ToBase.Sign() < 0
+}
+
+func (s recurringSeries) last() lunchmoney.Transaction {
+	return s.charges[len(s.charges)-1]
+}
+
+func (s recurringSeries) previous() lunchmoney.Transaction {
+	return s.charges[len(s.charges)-2]
+}
+
+func (s recurringSeries) lastDate() time.Time {
+	d, _ := time.Parse("2006-01-02", s.last().Date)
+	return d
+}
+
+func (s recurringSeries) nextDate() time.Time {
+	return s.cadence.next(s.lastDate())
+}
+
+// stopped reports whether the charge is more than half a period overdue.
+func (s recurringSeries) stopped(asOf time.Time) bool {
+	overdue := asOf.Sub(s.lastDate()).Hours() / 24
+	return overdue > 1.5*s.cadence.nominalDays()
+}
+
+// priceChange is the latest charge minus the one before it, in API sign.
+func (s recurringSeries) priceChange() lunchmoney.Amount {
+	return s.last().ToBase - s.previous().ToBase
+}
+
+func (s recurringSeries) annualized() lunchmoney.Amount {
+	return lunchmoney.AmountFromFloat(s.last().ToBase.Float64() * s.cadence.perYear)
+}
+
+// detectRecurring groups transactions by cleaned payee and direction and
+// keeps the groups whose gaps fit one cadence and whose amounts stay within
+// half of their median. Weekly to quarterly series need three charges; annual
+// ones need two.
+func detectRecurring(transactions []lunchmoney.Transaction) []recurringSeries {
+	type group struct {
+		key string
+		in  bool
+	}
+	byPayee := make(map[group][]lunchmoney.Transaction)
+	for _, tx := range transactions {
+		if tx.ToBase.Sign() == 0 {
+			continue
+		}
+		key := cleanPayee(rawPayee(tx))
+		if key == "" {
+			continue
+		}
+		g := group{key: key, in: tx.ToBase.Sign() < 0}
+		byPayee[g] = append(byPayee[g], tx)
+	}
+
+	var series []recurringSeries
+	for g, txs := range byPayee {
+		sort.SliceStable(txs, func(i, j int) bool { return txs[i].Date < txs[j].Date })
+		txs = oneChargePerDay(txs)
+		if len(txs) < 2 {
+			continue
+		}
+
+		gaps := make([]float64, 0, len(txs)-1)
+		for i := 1; i < len(txs); i++ {
+			a, errA := time.Parse("2006-01-02", txs[i-1].Date)
+			b, errB := time.Parse("2006-01-02", txs[i].Date)
+			if errA != nil || errB != nil {
+				continue
+			}
+			gaps = append(gaps, b.Sub(a).Hours()/24)
+		}
+		if len(gaps) == 0 {
+			continue
+		}
+
+		median := medianFloat(gaps)
+		cadence, ok := cadenceFor(median)
+		if !ok || (cadence.name != "annual" && len(txs) < 3) {
+			continue
+		}
+		regular := 0
+		for _, g := range gaps {
+			if g >= cadence.minDays && g <= cadence.maxDays {
+				regular++
+			}
+		}
+		if float64(regular) < 0.75*float64(len(gaps)) {
+			continue
+		}
+
+		amounts := make([]float64, len(txs))
+		for i, tx := range txs {
+			amounts[i] = tx.ToBase.Abs().Float64()
+		}
+		medianAmount := medianFloat(amounts)
+		consistent := true
+		for _, a := range amounts {
+			if math.Abs(a-medianAmount) > 0.5*medianAmount {
+				consistent = false
+				break
+			}
+		}
+		if !consistent {
+			continue
+		}
+
+		series = append(series, recurringSeries{
+			key:     g.key,
+			payee:   txs[len(txs)-1].Payee,
+			cadence: cadence,
+			charges: txs,
+		})
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].key != series[j].key {
+			return series[i].key < series[j].key
+		}
+		return !series[i].income()
+	})
+	return series
+}
+
+// oneChargePerDay keeps the first charge of each day so a same-day refund
+// and re-charge does not look like a daily cadence.
+func oneChargePerDay(txs []lunchmoney.Transaction) []lunchmoney.Transaction {
+	out := txs[:0:0]
+	for _, tx := range txs {
+		if len(out) > 0 && out[len(out)-1].Date == tx.Date {
+			continue
+		}
+		out = append(out, tx)
+	}
+	return out
+}
+
+func cadenceFor(days float64) (recurringCadence, bool) {
+	for _, c := range recurringCadences {
+		if days >= c.minDays && days <= c.maxDays {
+			return c, true
+		}
+	}
+	return recurringCadence{}, false
+}
+
+func medianFloat(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
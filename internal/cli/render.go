@@ -5,23 +5,35 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+
+	"lunchmoney-cli/internal/lunchmoney"
 )
 
 type transactionView struct {
-	ID          int64   `json:"id"`
-	Date        string  `json:"date"`
-	Description string  `json:"description"`
-	Category    string  `json:"category"`
-	Amount      float64 `json:"amount"`
-	Account     string  `json:"account"`
-	Institution string  `json:"institution"`
-	Group       string  `json:"group"`
-	Type        string  `json:"type"`
-	Notes       string  `json:"notes"`
-	Tags        string  `json:"tags"`
-	Status      string  `json:"status"`
-	IsPending   bool    `json:"is_pending"`
+	ID               int64             `json:"id"`
+	Date             string            `json:"date"`
+	Description      string            `json:"description"`
+	Category         string            `json:"category"`
+	Amount           lunchmoney.Amount `json:"amount"`
+	Currency         string            `json:"currency"`
+	OriginalAmount   lunchmoney.Amount `json:"original_amount"`
+	OriginalCurrency string            `json:"original_currency"`
+	BaseAmount       lunchmoney.Amount `json:"base_amount"`
+	BaseCurrency     string            `json:"base_currency"`
+	Account          string            `json:"account"`
+	Institution      string            `json:"institution"`
+	Group            string            `json:"group"`
+	Type             string            `json:"type"`
+	Notes            string            `json:"notes"`
+	Tags             string            `json:"tags"`
+	Status           string            `json:"status"`
+	IsPending        bool              `json:"is_pending"`
+
+	Suggestion           string  `json:"suggestion,omitempty"`
+	SuggestionConfidence float64 `json:"suggestion_confidence,omitempty"`
 }
 
 type categoryView struct {
@@ -32,36 +44,83 @@ type categoryView struct {
 	ExcludeFromTotals bool   `json:"exclude_from_totals"`
 }
 
+type tagView struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// accountView describes a manual or Plaid account. Key is the form used by
+// transactionAccountKey, e.g. "manual:12".
+type accountView struct {
+	Key         string            `json:"key"`
+	ID          int64             `json:"id"`
+	Source      string            `json:"source"`
+	Name        string            `json:"name"`
+	Institution string            `json:"institution"`
+	Type        string            `json:"type"`
+	Balance     lunchmoney.Amount `json:"balance"`
+	Currency    string            `json:"currency"`
+	Status      string            `json:"status"`
+}
+
 func printJSON(v any) error {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
 	return enc.Encode(v)
 }
 
-func printTransactionsTable(transactions []transactionView) {
+// printTotals prints the base-currency total followed by a per-currency
+// breakdown of original amounts with their base equivalents.
+func printTotals(transactions []transactionView) {
 	if len(transactions) == 0 {
-		fmt.Println("No transactions found.")
 		return
 	}
 
-	w := newTabWriter(os.Stdout)
-	fmt.Fprintln(w, "DATE\tID\tDESCRIPTION\tCATEGORY\tNOTE\tAMOUNT\tACCOUNT\tSTATUS\tPENDING")
+	type bucket struct {
+		original lunchmoney.Amount
+		base     lunchmoney.Amount
+	}
+	var (
+		total        lunchmoney.Amount
+		baseCurrency string
+		buckets      = map[string]*bucket{}
+	)
 	for _, tx := range transactions {
-		fmt.Fprintf(
-			w,
-			"%s\t%d\t%s\t%s\t%s\t%.2f\t%s\t%s\t%t\n",
-			tx.Date,
-			tx.ID,
-			tx.Description,
-			tx.Category,
-			tx.Notes,
-			tx.Amount,
-			tx.Account,
-			tx.Status,
-			tx.IsPending,
-		)
+		total += tx.BaseAmount
+		baseCurrency = tx.BaseCurrency
+		b, ok := buckets[tx.OriginalCurrency]
+		if !ok {
+			b = &bucket{}
+			buckets[tx.OriginalCurrency] = b
+		}
+		b.original += tx.OriginalAmount
+		b.base += tx.BaseAmount
 	}
-	_ = w.Flush()
+
+	currencies := make([]string, 0, len(buckets))
+	for c := range buckets {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+
+	fmt.Println()
+	fmt.Printf("Total: %s\n", formatAmountWithCurrency(total, baseCurrency))
+	if len(currencies) > 1 || (len(currencies) == 1 && currencies[0] != baseCurrency) {
+		w := newTabWriter(os.Stdout)
+		for _, c := range currencies {
+			b := buckets[c]
+			if c == baseCurrency {
+				fmt.Fprintf(w, "  %s\n", formatAmountWithCurrency(b.original, c))
+				continue
+			}
+			fmt.Fprintf(w, "  %s\t(%s)\n", formatAmountWithCurrency(b.original, c), formatAmountWithCurrency(b.base, baseCurrency))
+		}
+		_ = w.Flush()
+	}
+}
+
+func formatAmountWithCurrency(amount lunchmoney.Amount, currency string) string {
+	return strings.TrimSpace(amount.Format(currency) + " " + strings.ToUpper(currency))
 }
 
 func printCategoriesTable(categories []categoryView) {
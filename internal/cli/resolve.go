@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// resolveCategoryID accepts a category name (case-insensitive) or numeric ID
+// and returns the matching assignable category ID. Groups and archived
+// categories cannot be assigned to transactions and are never matched.
+func resolveCategoryID(categories []lunchmoney.Category, value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("category cannot be empty")
+	}
+
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		for _, c := range categories {
+			if c.ID == id && !c.IsGroup && !c.Archived {
+				return id, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown category id %d", id)
+	}
+
+	var matches []lunchmoney.Category
+	for _, c := range categories {
+		if c.IsGroup || c.Archived {
+			continue
+		}
+		if strings.EqualFold(c.Name, value) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("unknown category %q", value)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		ids := make([]string, 0, len(matches))
+		for _, c := range matches {
+			ids = append(ids, strconv.FormatInt(c.ID, 10))
+		}
+		return 0, fmt.Errorf("category %q is ambiguous (ids %s); use the id instead", value, strings.Join(ids, ", "))
+	}
+}
+
+// resolveTagIDs accepts tag names (case-insensitive) or numeric IDs.
+func resolveTagIDs(tags []lunchmoney.Tag, values []string) ([]int64, error) {
+	ids := make([]int64, 0, len(values))
+	for _, raw := range values {
+		value := strings.TrimSpace(raw)
+		if value == "" {
+			continue
+		}
+		id, err := resolveTagID(tags, value)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func resolveTagID(tags []lunchmoney.Tag, value string) (int64, error) {
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		for _, t := range tags {
+			if t.ID == id {
+				return id, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown tag id %d", id)
+	}
+	for _, t := range tags {
+		if strings.EqualFold(t.Name, value) {
+			return t.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown tag %q", value)
+}
@@ -1,18 +1,70 @@
 package cli
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"fmt"
+	"os"
 
+	"github.com/spf13/cobra"
+)
+
+// NewRootCmd builds the lm command tree. The root owns the global options;
+// run it with Execute so they reach its subcommands.
 func NewRootCmd() *cobra.Command {
+	opts := &globalOptions{}
 	rootCmd := &cobra.Command{
 		Use:           "lm",
 		Short:         "Lunch Money CLI",
 		SilenceUsage:  true,
 		SilenceErrors: true,
 	}
-	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.SetContext(withOptions(context.Background(), opts))
+	rootCmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "Print write requests instead of sending them (reads still happen)")
+	rootCmd.PersistentFlags().StringVar(&opts.trace, "trace", "", "Log API requests and responses to stderr, or to a file with --trace=FILE")
+	rootCmd.PersistentFlags().Lookup("trace").NoOptDefVal = "-"
+	rootCmd.PersistentFlags().StringVar(&opts.record, "record", "", "Save API requests and responses as cassette files in this directory")
+	rootCmd.PersistentFlags().StringVar(&opts.replay, "replay", "", "Answer API requests from cassette files in this directory instead of the network")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.PersistentFlags().DurationVar(&opts.timeout, "timeout", 0, "Stop the command after this long, e.g. 30s or 5m (0 for no limit)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if opts.timeout < 0 {
+			return fmt.Errorf("invalid --timeout %s (expected a positive duration)", opts.timeout)
+		}
+		if opts.timeout > 0 {
+			var ctx context.Context
+			ctx, opts.cancelTimeout = context.WithTimeoutCause(cmd.Context(), opts.timeout, timeoutError(opts.timeout))
+			cmd.SetContext(ctx)
+		}
+		return nil
+	}
 
+	rootCmd.AddCommand(newAuthCmd())
 	rootCmd.AddCommand(newTxCmd())
 	rootCmd.AddCommand(newCategoryCmd())
+	rootCmd.AddCommand(newTransfersCmd())
+	rootCmd.AddCommand(newPayeeCmd())
+	rootCmd.AddCommand(newAlertsCmd())
+	rootCmd.AddCommand(newSubscriptionsCmd())
+	rootCmd.AddCommand(newForecastCmd())
+	rootCmd.AddCommand(newMCPCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newDevCmd())
+	rootCmd.AddCommand(newHistoryCmd())
+	rootCmd.AddCommand(newUndoCmd())
 
 	return rootCmd
 }
+
+// Execute runs root with ctx and then releases what the command set up,
+// whether or not it failed.
+func Execute(ctx context.Context, root *cobra.Command) error {
+	opts := optionsFrom(root.Context())
+	err := root.ExecuteContext(withOptions(ctx, opts))
+	if closeErr := opts.finish(); err == nil && closeErr != nil {
+		err = fmt.Errorf("closing the --trace file: %w", closeErr)
+	}
+	if opts.dryRunOut != nil && opts.dryRunOut.wrote {
+		fmt.Fprintln(os.Stderr, "Dry run: no changes were sent.")
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// secretStore keeps the API key saved by `lm auth login`. Its Name
+// describes the store for messages, e.g. "the Secret Service keyring".
+type secretStore interface {
+	lunchmoney.KeyProvider
+	set(key string) error
+	// remove deletes the stored key; it returns lunchmoney.ErrNoAPIKey if
+	// there was none.
+	remove() error
+}
+
+// keyringStore keeps the key in the freedesktop Secret Service (GNOME
+// Keyring, KWallet) through libsecret's secret-tool, so the key is never on
+// a command line.
+type keyringStore struct{}
+
+var keyringAttributes = []string{"service", "lunchmoney-cli", "account", "default"}
+
+// keyringAvailable reports whether secret-tool is installed and there is a
+// session bus to reach the Secret Service on.
+func keyringAvailable() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (keyringStore) Name() string { return "the Secret Service keyring" }
+
+func (keyringStore) APIKey() (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", append([]string{"lookup"}, keyringAttributes...)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	key := strings.TrimSpace(stdout.String())
+	// secret-tool exits 1 without output when nothing matches.
+	if key == "" && (err == nil || stderr.Len() == 0) {
+		return "", lunchmoney.ErrNoAPIKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret-tool lookup: %s", strings.TrimSpace(stderr.String()))
+	}
+	return key, nil
+}
+
+func (keyringStore) set(key string) error {
+	var stderr bytes.Buffer
+	args := append([]string{"store", "--label", "Lunch Money API key"}, keyringAttributes...)
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = strings.NewReader(key)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("secret-tool store: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (s keyringStore) remove() error {
+	if _, err := s.APIKey(); err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", append([]string{"clear"}, keyringAttributes...)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("secret-tool clear: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+const credentialsFile = "credentials.age"
+
+// credentialsWorkFactor is the scrypt work factor (log2 N) for a new
+// credentials file, age's default of about a second.
+var credentialsWorkFactor = 18
+
+// fileStore keeps the key in the config directory for systems without a
+// keyring, as an armored age file encrypted to a passphrase (scrypt), so
+// `age -d` can read it too. passphrase is asked for when needed; confirm is
+// true when a new passphrase is being chosen.
+type fileStore struct {
+	path       string
+	passphrase func(confirm bool) (string, error)
+}
+
+func newFileStore(passphrase func(confirm bool) (string, error)) (fileStore, error) {
+	dir, err := configDir()
+	if err != nil {
+		return fileStore{}, err
+	}
+	return fileStore{path: filepath.Join(dir, credentialsFile), passphrase: passphrase}, nil
+}
+
+func (s fileStore) Name() string { return s.path }
+
+func (s fileStore) APIKey() (string, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", lunchmoney.ErrNoAPIKey
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	passphrase, err := s.passphrase(false)
+	if err != nil {
+		return "", err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return "", err
+	}
+	r, err := age.Decrypt(armor.NewReader(f), identity)
+	if errors.Is(err, age.ErrIncorrectIdentity) {
+		return "", fmt.Errorf("cannot decrypt %s: wrong passphrase?", s.path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid credentials file %s: %w", s.path, err)
+	}
+	key, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt %s: %w", s.path, err)
+	}
+	return string(key), nil
+}
+
+func (s fileStore) set(key string) error {
+	passphrase, err := s.passphrase(true)
+	if err != nil {
+		return err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+	recipient.SetWorkFactor(credentialsWorkFactor)
+
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, key); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := aw.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, buf.Bytes(), 0o600)
+}
+
+func (s fileStore) remove() error {
+	err := os.Remove(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lunchmoney.ErrNoAPIKey
+	}
+	return err
+}
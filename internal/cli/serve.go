@@ -0,0 +1,557 @@
+package cli
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const envServeToken = "LM_SERVE_TOKEN"
+
+// spendingRow is one line of the spending report. Amounts are base currency
+// and positive: Expenses is money out, Income money in.
+type spendingRow struct {
+	Key      string            `json:"key"`
+	Expenses lunchmoney.Amount `json:"expenses"`
+	Income   lunchmoney.Amount `json:"income"`
+	Net      lunchmoney.Amount `json:"net"`
+	Count    int               `json:"count"`
+}
+
+type spendingReport struct {
+	Start    string        `json:"start"`
+	End      string        `json:"end"`
+	GroupBy  string        `json:"group_by"`
+	Currency string        `json:"currency"`
+	Rows     []spendingRow `json:"rows"`
+	Total    spendingRow   `json:"total"`
+}
+
+func newServeCmd() *cobra.Command {
+	var (
+		addr  string
+		token string
+		quiet bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve read-only REST endpoints with the same JSON as the CLI",
+		Long: `Serve read-only REST endpoints with the same JSON as the CLI.
+
+Endpoints:
+  GET /api/transactions?start=YYYY-MM-DD[&end=][&status=][&type=][&where=...][&currency=]
+  GET /api/transactions/{id}
+  GET /api/categories
+  GET /api/tags
+  GET /api/accounts
+  GET /api/reports/spending?start=YYYY-MM-DD[&end=][&group_by=category|group|account|type|payee]
+  GET /api/reports/subscriptions[?end=]
+  GET /api/reports/forecast[?months=3]
+
+When a token is set (--token or ` + envServeToken + `), requests must send
+"Authorization: Bearer <token>". Without one, the server only listens on
+loopback addresses and only answers requests whose Host is localhost or a
+loopback IP, so web pages cannot reach it through DNS rebinding.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				token = os.Getenv(envServeToken)
+			}
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return fmt.Errorf("invalid --addr %q: %w", addr, err)
+			}
+			if token == "" && !isLoopbackHost(host) {
+				return fmt.Errorf("refusing to listen on %s without a token; set --token or %s", addr, envServeToken)
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			var logOut io.Writer = os.Stderr
+			if quiet {
+				logOut = io.Discard
+			}
+			handler := newAPIHandler(client, token, logOut)
+
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			return serveHTTP(cmd.Context(), listener, handler)
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8080", "Address to listen on")
+	cmd.Flags().StringVar(&token, "token", "", "Require this bearer token (defaults to $"+envServeToken+")")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Do not log requests to stderr")
+
+	return cmd
+}
+
+// serveHTTP runs until ctx is cancelled, then gives in-flight requests a few
+// seconds to finish.
+func serveHTTP(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", listener.Addr())
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(listener) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Fprintln(os.Stderr, "Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type apiHandler struct {
+	client *lunchmoney.Client
+}
+
+func newAPIHandler(client *lunchmoney.Client, token string, logOut io.Writer) http.Handler {
+	h := &apiHandler{client: client}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/transactions", h.transactions)
+	mux.HandleFunc("GET /api/transactions/{id}", h.transaction)
+	mux.HandleFunc("GET /api/categories", h.categories)
+	mux.HandleFunc("GET /api/tags", h.tags)
+	mux.HandleFunc("GET /api/accounts", h.accounts)
+	mux.HandleFunc("GET /api/reports/spending", h.spendingReport)
+	mux.HandleFunc("GET /api/reports/subscriptions", h.subscriptionsReport)
+	mux.HandleFunc("GET /api/reports/forecast", h.forecastReport)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not found")
+	})
+
+	var handler http.Handler = mux
+	if token != "" {
+		handler = requireToken(token, handler)
+	} else {
+		handler = requireLoopbackHost(handler)
+	}
+	return logRequests(logOut, handler)
+}
+
+// requireLoopbackHost guards a server without a token against DNS
+// rebinding: a web page can point its own name at 127.0.0.1, but the
+// browser still sends that name as the Host header.
+func requireLoopbackHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !isLoopbackHost(host) {
+			writeAPIError(w, http.StatusForbidden, fmt.Sprintf("host %q is not allowed without a token; use localhost or a loopback address", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether host (without a port) is localhost or a
+// loopback IP.
+func isLoopbackHost(host string) bool {
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(out io.Writer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		fmt.Fprintf(out, "%s %s %s %d %s\n", start.Format(time.RFC3339), r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeAPIJSON(w, status, map[string]string{"error": msg})
+}
+
+// badRequest marks errors caused by the query rather than the upstream API.
+type badRequest struct{ error }
+
+// fail maps an error to a status: query errors are 400, upstream 404s stay
+// 404 and any other API failure is a 502.
+func fail(w http.ResponseWriter, err error) {
+	var bad badRequest
+	switch {
+	case errors.As(err, &bad):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	case lunchmoney.IsNotFound(err):
+		writeAPIError(w, http.StatusNotFound, err.Error())
+	default:
+		writeAPIError(w, http.StatusBadGateway, err.Error())
+	}
+}
+
+// queryDateRange reads start (required) and end (default today).
+func queryDateRange(r *http.Request) (string, string, error) {
+	start := r.URL.Query().Get("start")
+	end := r.URL.Query().Get("end")
+	if start == "" {
+		return "", "", badRequest{errors.New("start is required (YYYY-MM-DD)")}
+	}
+	if end == "" {
+		end = time.Now().Format("2006-01-02")
+	}
+	if err := validateDateRange(start, end); err != nil {
+		return "", "", badRequest{err}
+	}
+	return start, end, nil
+}
+
+// queryListParams reads the date range and status (all, reviewed or
+// unreviewed) for a transaction listing.
+func queryListParams(r *http.Request) (lunchmoney.ListTransactionsParams, error) {
+	start, end, err := queryDateRange(r)
+	if err != nil {
+		return lunchmoney.ListTransactionsParams{}, err
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "all":
+		status = ""
+	case "reviewed", "unreviewed":
+	default:
+		return lunchmoney.ListTransactionsParams{}, badRequest{fmt.Errorf("invalid status %q (expected all, reviewed or unreviewed)", status)}
+	}
+	return lunchmoney.ListTransactionsParams{StartDate: start, EndDate: end, Status: status, Limit: 1000}, nil
+}
+
+// listViews fetches and classifies transactions for the query's date range
+// and status, newest first.
+func (h *apiHandler) listViews(r *http.Request) ([]transactionView, txLookups, error) {
+	params, err := queryListParams(r)
+	if err != nil {
+		return nil, txLookups{}, err
+	}
+	transactions, err := h.client.ListTransactions(r.Context(), params)
+	if err != nil {
+		return nil, txLookups{}, err
+	}
+	lookups, err := loadTxLookups(r.Context(), h.client)
+	if err != nil {
+		return nil, txLookups{}, err
+	}
+	views := lookups.views(transactions)
+	sortTransactionsNewestFirst(views)
+	return views, lookups, nil
+}
+
+func (h *apiHandler) transactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	txType := q.Get("type")
+	switch txType {
+	case "", txTypeExpense, txTypeIncome, txTypeTransfer:
+	default:
+		fail(w, badRequest{fmt.Errorf("invalid type %q (expected expense, income or transfer)", txType)})
+		return
+	}
+	currencyMode := q.Get("currency")
+	if currencyMode == "" {
+		currencyMode = currencyBase
+	}
+	if currencyMode != currencyBase && currencyMode != currencyOriginal {
+		fail(w, badRequest{fmt.Errorf("invalid currency %q (expected base or original)", currencyMode)})
+		return
+	}
+	preds, err := parseWhere(q["where"])
+	if err != nil {
+		fail(w, badRequest{err})
+		return
+	}
+
+	all, _, err := h.listViews(r)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	views := make([]transactionView, 0, len(all))
+	for _, v := range all {
+		if (txType == "" || v.Type == txType) && matchesWhere(v, preds) {
+			views = append(views, v)
+		}
+	}
+	applyCurrencyMode(views, currencyMode)
+	writeAPIJSON(w, http.StatusOK, views)
+}
+
+func (h *apiHandler) transaction(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTxID(r.PathValue("id"))
+	if err != nil {
+		fail(w, badRequest{err})
+		return
+	}
+	tx, err := h.client.GetTransaction(r.Context(), id)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	lookups, err := loadTxLookups(r.Context(), h.client)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, lookups.view(tx))
+}
+
+func (h *apiHandler) categories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.client.ListCategories(r.Context())
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	views := toCategoryViews(categories)
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Group != views[j].Group {
+			return views[i].Group < views[j].Group
+		}
+		return views[i].Name < views[j].Name
+	})
+	writeAPIJSON(w, http.StatusOK, views)
+}
+
+func (h *apiHandler) tags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.client.ListTags(r.Context())
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, toTagViews(tags))
+}
+
+func (h *apiHandler) accounts(w http.ResponseWriter, r *http.Request) {
+	manual, err := h.client.ListManualAccounts(r.Context())
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	plaid, err := h.client.ListPlaidAccounts(r.Context())
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, toAccountViews(manual, plaid))
+}
+
+func (h *apiHandler) spendingReport(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "category"
+	}
+	switch groupBy {
+	case "category", "group", "account", "type", "payee":
+	default:
+		fail(w, badRequest{fmt.Errorf("invalid group_by %q (expected category, group, account, type or payee)", groupBy)})
+		return
+	}
+	params, err := queryListParams(r)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	lookups, err := loadTxLookups(r.Context(), h.client)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	// Totals are kept per key, so the transactions are streamed rather
+	// than held in memory.
+	summary := newSpendingSummary(lookups, groupBy)
+	err = lookups.eachView(h.client.IterTransactions(r.Context(), params), func(_ lunchmoney.Transaction, v transactionView) error {
+		summary.add(v)
+		return nil
+	})
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	report := summary.report()
+	report.Start, report.End = params.StartDate, params.EndDate
+	writeAPIJSON(w, http.StatusOK, report)
+}
+
+// spendingSummary totals base amounts per key. Transfers and categories
+// excluded from totals are left out unless grouping by type.
+type spendingSummary struct {
+	groupBy  string
+	currency string
+	excluded map[string]bool
+	rows     map[string]*spendingRow
+	total    spendingRow
+}
+
+func newSpendingSummary(lookups txLookups, groupBy string) *spendingSummary {
+	s := &spendingSummary{
+		groupBy:  groupBy,
+		currency: lookups.baseCurrency,
+		excluded: make(map[string]bool),
+		rows:     make(map[string]*spendingRow),
+		total:    spendingRow{Key: "total"},
+	}
+	for _, c := range lookups.categoryByID {
+		if c.ExcludeFromTotals {
+			s.excluded[c.Name] = true
+		}
+	}
+	return s
+}
+
+func (s *spendingSummary) add(v transactionView) {
+	if s.groupBy != "type" && (v.Type == txTypeTransfer || s.excluded[v.Category]) {
+		return
+	}
+	key := viewFieldString(v, s.groupBy)
+	if key == "" {
+		key = "(none)"
+	}
+	row, ok := s.rows[key]
+	if !ok {
+		row = &spendingRow{Key: key}
+		s.rows[key] = row
+	}
+	addSpending(row, v.BaseAmount)
+	addSpending(&s.total, v.BaseAmount)
+}
+
+// addSpending adds a view amount, which is signed from the budget's side:
+// negative is money out.
+func addSpending(row *spendingRow, amount lunchmoney.Amount) {
+	if amount.Sign() < 0 {
+		row.Expenses -= amount
+	} else {
+		row.Income += amount
+	}
+	row.Net += amount
+	row.Count++
+}
+
+func (s *spendingSummary) report() spendingReport {
+	report := spendingReport{GroupBy: s.groupBy, Currency: s.currency, Total: s.total}
+	report.Rows = make([]spendingRow, 0, len(s.rows))
+	for _, row := range s.rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].Expenses != report.Rows[j].Expenses {
+			return report.Rows[i].Expenses > report.Rows[j].Expenses
+		}
+		return report.Rows[i].Key < report.Rows[j].Key
+	})
+	return report
+}
+
+func (h *apiHandler) subscriptionsReport(w http.ResponseWriter, r *http.Request) {
+	end := r.URL.Query().Get("end")
+	if end == "" {
+		end = time.Now().Format("2006-01-02")
+	}
+	asOf, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		fail(w, badRequest{fmt.Errorf("invalid end %q (expected YYYY-MM-DD)", end)})
+		return
+	}
+
+	ctx := r.Context()
+	transactions, err := h.client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+		StartDate: asOf.AddDate(0, -13, 0).Format("2006-01-02"),
+		EndDate:   end,
+		Limit:     1000,
+	})
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	items, err := h.client.ListRecurringItems(ctx, "", "")
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	me, err := h.client.GetMe(ctx)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, detectSubscriptions(transactions, items, asOf, me.PrimaryCurrency))
+}
+
+func (h *apiHandler) forecastReport(w http.ResponseWriter, r *http.Request) {
+	months := 3
+	if raw := r.URL.Query().Get("months"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > 24 {
+			fail(w, badRequest{fmt.Errorf("invalid months %q (expected 1 to 24)", raw)})
+			return
+		}
+		months = n
+	}
+	in := forecastInput{AsOf: time.Now().Format("2006-01-02"), Months: months}
+	result, err := fetchForecast(r.Context(), h.client, in, 400)
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// eachView classifies transactions as they arrive from seq and calls fn with
+// each one and its view. seq should be in date order. When transfer pairing
+// is on, transactions are held back only while a later one could still pair
+// with them, so memory is bounded by pair_window_days rather than the range.
+func (l txLookups) eachView(seq iter.Seq2[lunchmoney.Transaction, error], fn func(lunchmoney.Transaction, transactionView) error) error {
+	if l.classifier.pairDays <= 0 {
+		for tx, err := range seq {
+			if err != nil {
+				return err
+			}
+			if err := fn(tx, l.view(tx)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	l.transferPairs = make(map[int64]int64)
+	var held []lunchmoney.Transaction
+
+	// emit pairs everything held, then passes on the transactions dated
+	// before cutoff (all of them when cutoff is empty). Only pairs involving
+	// an emitted transaction are final; the rest are decided again later
+	// with more transactions in view.
+	emit := func(cutoff string) error {
+		var open []lunchmoney.Transaction
+		for _, tx := range held {
+			if _, paired := l.transferPairs[tx.ID]; !paired {
+				open = append(open, tx)
+			}
+		}
+		ready := func(tx lunchmoney.Transaction) bool { return cutoff == "" || tx.Date < cutoff }
+		byID := make(map[int64]lunchmoney.Transaction, len(open))
+		for _, tx := range open {
+			byID[tx.ID] = tx
+		}
+		for a, b := range findTransferPairs(open, l.classifier.pairDays) {
+			if ready(byID[a]) || ready(byID[b]) {
+				l.transferPairs[a] = b
+			}
+		}
+
+		kept := held[:0]
+		for _, tx := range held {
+			if !ready(tx) {
+				kept = append(kept, tx)
+				continue
+			}
+			if err := fn(tx, l.view(tx)); err != nil {
+				return err
+			}
+			delete(l.transferPairs, tx.ID)
+		}
+		held = kept
+		return nil
+	}
+
+	for tx, err := range seq {
+		if err != nil {
+			return err
+		}
+		held = append(held, tx)
+		date, err := time.Parse("2006-01-02", tx.Date)
+		if err != nil {
+			continue
+		}
+		cutoff := date.AddDate(0, 0, -l.classifier.pairDays).Format("2006-01-02")
+		if held[0].Date < cutoff {
+			if err := emit(cutoff); err != nil {
+				return err
+			}
+		}
+	}
+	return emit("")
+}
+
+// viewWriter writes transaction views one at a time as they are produced.
+type viewWriter interface {
+	write(v transactionView) error
+	close() error
+}
+
+func newViewWriter(w io.Writer, format string, withSuggestions bool) viewWriter {
+	if format == "csv" {
+		return &csvViewWriter{w: csv.NewWriter(w), withSuggestions: withSuggestions}
+	}
+	bw := bufio.NewWriter(w)
+	return &ndjsonViewWriter{bw: bw, enc: json.NewEncoder(bw)}
+}
+
+type ndjsonViewWriter struct {
+	bw  *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *ndjsonViewWriter) write(v transactionView) error { return w.enc.Encode(v) }
+func (w *ndjsonViewWriter) close() error                  { return w.bw.Flush() }
+
+// transactionCSVColumns match the JSON field names, so the output can be
+// edited and fed back to `lm tx apply`.
+var transactionCSVColumns = []string{
+	"id", "date", "description", "category", "amount", "currency",
+	"original_amount", "original_currency", "base_amount", "base_currency",
+	"account", "institution", "group", "type", "notes", "tags", "status", "is_pending",
+}
+
+type csvViewWriter struct {
+	w               *csv.Writer
+	withSuggestions bool
+	wroteHeader     bool
+}
+
+// writeHeader writes the header before the first row, or on close when
+// there were no rows.
+func (w *csvViewWriter) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+	header := transactionCSVColumns
+	if w.withSuggestions {
+		header = append(header[:len(header):len(header)], "suggestion", "suggestion_confidence")
+	}
+	return w.w.Write(header)
+}
+
+func (w *csvViewWriter) write(v transactionView) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	row := []string{
+		strconv.FormatInt(v.ID, 10), v.Date, v.Description, v.Category,
+		v.Amount.Format(v.Currency), v.Currency,
+		v.OriginalAmount.Format(v.OriginalCurrency), v.OriginalCurrency,
+		v.BaseAmount.Format(v.BaseCurrency), v.BaseCurrency,
+		v.Account, v.Institution, v.Group, v.Type, v.Notes, v.Tags, v.Status,
+		strconv.FormatBool(v.IsPending),
+	}
+	if w.withSuggestions {
+		confidence := ""
+		if v.Suggestion != "" {
+			confidence = fmt.Sprintf("%.2f", v.SuggestionConfidence)
+		}
+		row = append(row, v.Suggestion, confidence)
+	}
+	return w.w.Write(row)
+}
+
+func (w *csvViewWriter) close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const (
+	coveredYes       = "yes"
+	coveredSuggested = "suggested"
+	coveredNo        = "no"
+)
+
+// subscriptionView is a detected recurring charge. Amounts are spending in
+// base currency, positive for money out.
+type subscriptionView struct {
+	Payee           string            `json:"payee"`
+	Cadence         string            `json:"cadence"`
+	Amount          lunchmoney.Amount `json:"amount"`
+	PreviousAmount  lunchmoney.Amount `json:"previous_amount"`
+	Annualized      lunchmoney.Amount `json:"annualized"`
+	Currency        string            `json:"currency"`
+	Charges         int               `json:"charges"`
+	FirstDate       string            `json:"first_date"`
+	LastDate        string            `json:"last_date"`
+	NextDate        string            `json:"next_date"`
+	Covered         string            `json:"covered"`
+	RecurringItemID *int64            `json:"recurring_item_id"`
+	Stopped         bool              `json:"stopped"`
+	PriceChanged    bool              `json:"price_changed"`
+}
+
+func newSubscriptionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "subscriptions",
+		Short: "Work with recurring charges inferred from history",
+	}
+	cmd.AddCommand(newSubscriptionsDetectCmd())
+	return cmd
+}
+
+func newSubscriptionsDetectCmd() *cobra.Command {
+	var (
+		startDate     string
+		endDate       string
+		uncoveredOnly bool
+		jsonOutput    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "detect",
+		Short: "Infer subscriptions from transaction history and compare them with recurring items",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endDate == "" {
+				endDate = time.Now().Format("2006-01-02")
+			}
+			if startDate == "" {
+				end, err := time.Parse("2006-01-02", endDate)
+				if err != nil {
+					return fmt.Errorf("invalid --end %q (expected YYYY-MM-DD)", endDate)
+				}
+				startDate = end.AddDate(0, -13, 0).Format("2006-01-02")
+			}
+			if err := validateDateRange(startDate, endDate); err != nil {
+				return err
+			}
+			asOf, _ := time.Parse("2006-01-02", endDate)
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			transactions, err := client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+				StartDate: startDate,
+				EndDate:   endDate,
+				Limit:     1000,
+			})
+			if err != nil {
+				return err
+			}
+			items, err := client.ListRecurringItems(ctx, "", "")
+			if err != nil {
+				return err
+			}
+			me, err := client.GetMe(ctx)
+			if err != nil {
+				return err
+			}
+
+			subs := detectSubscriptions(transactions, items, asOf, me.PrimaryCurrency)
+			if uncoveredOnly {
+				kept := subs[:0]
+				for _, s := range subs {
+					if s.Covered != coveredYes {
+						kept = append(kept, s)
+					}
+				}
+				subs = kept
+			}
+
+			if jsonOutput {
+				return printJSON(subs)
+			}
+			if len(subs) == 0 {
+				fmt.Println("No subscriptions detected.")
+				return nil
+			}
+			printSubscriptions(subs)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&startDate, "start", "", "Start of the history to scan (YYYY-MM-DD), defaults to 13 months before --end")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
+	cmd.Flags().BoolVar(&uncoveredOnly, "uncovered", false, "Only show subscriptions without a reviewed recurring item")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON")
+
+	return cmd
+}
+
+// detectSubscriptions turns recurring charges into views, matching each one
+// to a recurring item. Recurring income is left out.
+func detectSubscriptions(transactions []lunchmoney.Transaction, items []lunchmoney.RecurringItem, asOf time.Time, currency string) []subscriptionView {
+	index := newRecurringItemIndex(items)
+	series := detectRecurring(transactions)
+	subs := make([]subscriptionView, 0, len(series))
+	for _, s := range series {
+		if s.income() {
+			continue
+		}
+		last := s.last()
+		previous := s.previous()
+		change := s.priceChange()
+
+		view := subscriptionView{
+			Payee:          s.payee,
+			Cadence:        s.cadence.name,
+			Amount:         last.ToBase,
+			PreviousAmount: previous.ToBase,
+			Annualized:     s.annualized().Round(currency),
+			Currency:       currency,
+			Charges:        len(s.charges),
+			FirstDate:      s.charges[0].Date,
+			LastDate:       last.Date,
+			NextDate:       s.nextDate().Format("2006-01-02"),
+			Covered:        coveredNo,
+			Stopped:        s.stopped(asOf),
+			PriceChanged:   change.Abs().Float64() >= 0.01*previous.ToBase.Float64(),
+		}
+		if item, ok := index.match(s); ok {
+			id := item.ID
+			view.RecurringItemID = &id
+			view.Covered = coveredSuggested
+			if item.Status == "reviewed" {
+				view.Covered = coveredYes
+			}
+		}
+		subs = append(subs, view)
+	}
+
+	sort.SliceStable(subs, func(i, j int) bool {
+		if subs[i].Stopped != subs[j].Stopped {
+			return !subs[i].Stopped
+		}
+		return subs[i].Annualized > subs[j].Annualized
+	})
+	return subs
+}
+
+func printSubscriptions(subs []subscriptionView) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "PAYEE\tCADENCE\tAMOUNT\tANNUAL\tCHARGES\tLAST\tNEXT\tCOVERED\tFLAGS")
+	var total lunchmoney.Amount
+	currency := ""
+	for _, s := range subs {
+		var flags []string
+		if s.Stopped {
+			flags = append(flags, "stopped")
+		} else {
+			total += s.Annualized
+		}
+		if s.PriceChanged {
+			pct := 100 * (s.Amount - s.PreviousAmount).Float64() / s.PreviousAmount.Float64()
+			flags = append(flags, fmt.Sprintf("price %+.0f%% (was %s)", pct, s.PreviousAmount.Format(s.Currency)))
+		}
+		if s.Covered == coveredNo {
+			flags = append(flags, "not tracked")
+		}
+		currency = s.Currency
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			s.Payee,
+			s.Cadence,
+			formatAmountWithCurrency(s.Amount, s.Currency),
+			formatAmountWithCurrency(s.Annualized, s.Currency),
+			s.Charges,
+			s.LastDate,
+			s.NextDate,
+			s.Covered,
+			strings.Join(flags, ", "),
+		)
+	}
+	_ = w.Flush()
+	fmt.Printf("\nActive subscriptions cost %s per year.\n", formatAmountWithCurrency(total, currency))
+}
+
+// recurringItemIndex finds the recurring item behind a detected series.
+type recurringItemIndex struct {
+	byID    map[int64]lunchmoney.RecurringItem
+	byPayee map[string]lunchmoney.RecurringItem
+}
+
+func newRecurringItemIndex(items []lunchmoney.RecurringItem) recurringItemIndex {
+	index := recurringItemIndex{
+		byID:    make(map[int64]lunchmoney.RecurringItem, len(items)),
+		byPayee: make(map[string]lunchmoney.RecurringItem),
+	}
+	for _, item := range items {
+		index.byID[item.ID] = item
+		for _, payee := range []*string{item.TransactionCriteria.Payee, item.Overrides.Payee} {
+			if payee == nil {
+				continue
+			}
+			key := cleanPayee(*payee)
+			// Prefer reviewed items when a payee has both.
+			if existing, ok := index.byPayee[key]; key != "" && (!ok || existing.Status != "reviewed") {
+				index.byPayee[key] = item
+			}
+		}
+	}
+	return index
+}
+
+// match looks for the recurring_id on the series' charges, newest first, then
+// falls back to the cleaned payee.
+func (x recurringItemIndex) match(s recurringSeries) (lunchmoney.RecurringItem, bool) {
+	for i := len(s.charges) - 1; i >= 0; i-- {
+		if id := s.charges[i].RecurringID; id != nil {
+			if item, ok := x.byID[*id]; ok {
+				return item, true
+			}
+		}
+	}
+	if item, ok := x.byPayee[s.key]; ok {
+		return item, true
+	}
+	item, ok := x.byPayee[cleanPayee(s.payee)]
+	return item, ok
+}
@@ -0,0 +1,523 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const (
+	// suggestModelFile is named per API key (see keyFingerprint), so one
+	// budget's history never suggests categories for another.
+	suggestModelFile = "suggest_model-%s.json"
+	// suggestModelMaxAge is how long lm tx suggest reuses a trained model
+	// before fetching reviewed history again.
+	suggestModelMaxAge = 7 * 24 * time.Hour
+)
+
+// Feature weights. Payee evidence decides the confidence; account, amount
+// and weekday only break ties between categories.
+const (
+	suggestWeightPayee   = 3.0
+	suggestWeightTokens  = 1.0
+	suggestWeightAccount = 0.3
+	suggestWeightAmount  = 0.2
+	suggestWeightWeekday = 0.1
+)
+
+// suggestModel counts, for every feature of reviewed transactions, how often
+// each category was used. It is trained and evaluated locally.
+type suggestModel struct {
+	TrainedAt    string                     `json:"trained_at"`
+	Start        string                     `json:"start"`
+	End          string                     `json:"end"`
+	Transactions int                        `json:"transactions"`
+	Features     map[string]map[int64]int64 `json:"features"`
+}
+
+type categorySuggestion struct {
+	ID          int64             `json:"id"`
+	Date        string            `json:"date"`
+	Description string            `json:"description"`
+	Amount      lunchmoney.Amount `json:"amount"`
+	Currency    string            `json:"currency"`
+	CategoryID  int64             `json:"category_id"`
+	Category    string            `json:"category"`
+	Confidence  float64           `json:"confidence"`
+	Reason      string            `json:"reason"`
+}
+
+func newTxSuggestCmd() *cobra.Command {
+	var (
+		startDate     string
+		endDate       string
+		historyStart  string
+		retrain       bool
+		apply         bool
+		minConfidence float64
+		yes           bool
+		jsonOutput    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggest categories for unreviewed transactions from your reviewed history",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endDate == "" {
+				endDate = time.Now().Format("2006-01-02")
+			}
+			if err := validateDateRange(startDate, endDate); err != nil {
+				return err
+			}
+			if minConfidence < 0 || minConfidence > 1 {
+				return errors.New("--min-confidence must be between 0 and 1")
+			}
+			if historyStart == "" {
+				historyStart = time.Now().AddDate(-1, 0, 0).Format("2006-01-02")
+			}
+			if _, err := time.Parse("2006-01-02", historyStart); err != nil {
+				return fmt.Errorf("invalid --history-start %q (expected YYYY-MM-DD)", historyStart)
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			model, err := loadSuggestModel(ctx)
+			if err != nil {
+				return err
+			}
+			// An explicit --history-start that differs from the cached
+			// model's asks for different training data. The default moves
+			// every day, so it only applies once the model is stale.
+			otherHistory := model != nil && cmd.Flags().Changed("history-start") && model.Start != historyStart
+			if retrain || model == nil || model.stale() || otherHistory {
+				model, err = trainSuggestModelFromAPI(ctx, client, historyStart)
+				if err != nil {
+					return err
+				}
+				if err := saveSuggestModel(ctx, model); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: failed to cache suggestion model: %v\n", err)
+				}
+			}
+
+			transactions, err := client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+				StartDate: startDate,
+				EndDate:   endDate,
+				Status:    "unreviewed",
+				Limit:     1000,
+			})
+			if err != nil {
+				return err
+			}
+			lookups, err := loadTxLookups(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			suggestions := make([]categorySuggestion, 0, len(transactions))
+			byID := make(map[int64]lunchmoney.Transaction, len(transactions))
+			for _, tx := range transactions {
+				s, ok := model.suggest(tx, lookups.categoryByID)
+				if !ok {
+					continue
+				}
+				if tx.CategoryID != nil && *tx.CategoryID == s.CategoryID {
+					continue
+				}
+				s.Amount = tx.ToBase.Neg()
+				s.Currency = lookups.baseCurrency
+				suggestions = append(suggestions, s)
+				byID[tx.ID] = tx
+			}
+			sort.SliceStable(suggestions, func(i, j int) bool {
+				return suggestions[i].Confidence > suggestions[j].Confidence
+			})
+
+			if jsonOutput {
+				return printJSON(suggestions)
+			}
+			if len(suggestions) == 0 {
+				fmt.Println("No suggestions.")
+				return nil
+			}
+			printSuggestions(suggestions)
+			if !apply {
+				return nil
+			}
+
+			var updates []lunchmoney.BulkTransactionUpdate
+			for _, s := range suggestions {
+				if s.Confidence < minConfidence {
+					continue
+				}
+				id := s.CategoryID
+				current := byID[s.ID]
+				updates = append(updates, lunchmoney.BulkTransactionUpdate{ID: s.ID, Current: &current, TransactionUpdate: lunchmoney.TransactionUpdate{CategoryID: &id}})
+			}
+			if len(updates) == 0 {
+				fmt.Printf("No suggestions at or above confidence %.2f.\n", minConfidence)
+				return nil
+			}
+			if !yes && !client.DryRun() {
+				ok, err := confirm(cmd.Context(), fmt.Sprintf("Apply %d suggestion(s) with confidence >= %.2f?", len(updates), minConfidence))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+
+			updated, errs := client.UpdateTransactions(ctx, updates)
+			afterByID := make(map[int64]lunchmoney.Transaction, len(updated))
+			for _, tx := range updated {
+				afterByID[tx.ID] = tx
+			}
+			changes := make([]journalChange, 0, len(updates))
+			failed := 0
+			for i, err := range errs {
+				id := updates[i].ID
+				if err != nil {
+					failed++
+					if !errors.Is(err, lunchmoney.ErrNotSent) {
+						fmt.Fprintf(os.Stderr, "failed to categorize transaction %d: %v\n", id, err)
+					}
+					continue
+				}
+				changes = append(changes, journalChange{TxID: id, Fields: []string{"category_id"}, Before: byID[id], After: afterByID[id]})
+			}
+			recordMutation(ctx, changes)
+			if err := stopped(ctx, changedIDs(changes), len(updates)); err != nil {
+				return err
+			}
+
+			if client.DryRun() {
+				fmt.Printf("Would categorize %d transaction(s).\n", len(changes))
+			} else {
+				fmt.Printf("Categorized %d transaction(s).\n", len(changes))
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d transaction(s) failed to update", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date of unreviewed transactions (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
+	cmd.Flags().StringVar(&historyStart, "history-start", "", "Train on reviewed transactions since this date (default one year ago)")
+	cmd.Flags().BoolVar(&retrain, "retrain", false, "Retrain the model even if a recent one is cached")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Set the suggested category on transactions at or above --min-confidence")
+	cmd.Flags().Float64Var(&minConfidence, "min-confidence", 0.9, "Minimum confidence for --apply")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation prompt for --apply")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output suggestions as JSON")
+	cmd.MarkFlagsMutuallyExclusive("apply", "json")
+	_ = cmd.MarkFlagRequired("start")
+
+	return cmd
+}
+
+// addSuggestions fills the suggestion fields of views from the cached model.
+// It never trains, so listing stays a single fetch; without a cached model
+// (see lm tx suggest) views are left as they are.
+func addSuggestions(ctx context.Context, views []transactionView, transactions []lunchmoney.Transaction, lookups txLookups) {
+	suggest := loadSuggester(ctx, lookups)
+	if suggest == nil {
+		return
+	}
+	for i, tx := range transactions {
+		suggest(tx, &views[i])
+	}
+}
+
+// loadSuggester returns a function that fills one view's suggestion from the
+// cached model, or nil when there is no model.
+func loadSuggester(ctx context.Context, lookups txLookups) func(lunchmoney.Transaction, *transactionView) {
+	model, err := loadSuggestModel(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return nil
+	}
+	if model == nil {
+		return nil
+	}
+	return func(tx lunchmoney.Transaction, v *transactionView) {
+		s, ok := model.suggest(tx, lookups.categoryByID)
+		if !ok || (tx.CategoryID != nil && *tx.CategoryID == s.CategoryID) {
+			return
+		}
+		v.Suggestion = s.Category
+		v.SuggestionConfidence = s.Confidence
+	}
+}
+
+func trainSuggestModelFromAPI(ctx context.Context, client *lunchmoney.Client, start string) (*suggestModel, error) {
+	end := time.Now().Format("2006-01-02")
+	reviewed, err := client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+		StartDate: start,
+		EndDate:   end,
+		Status:    "reviewed",
+		Limit:     1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+	model := trainSuggestModel(reviewed)
+	model.TrainedAt = time.Now().UTC().Format(time.RFC3339)
+	model.Start = start
+	model.End = end
+	return model, nil
+}
+
+// trainSuggestModel counts categories per feature over categorized
+// transactions.
+func trainSuggestModel(transactions []lunchmoney.Transaction) *suggestModel {
+	model := &suggestModel{Features: make(map[string]map[int64]int64)}
+	for _, tx := range transactions {
+		if tx.CategoryID == nil {
+			continue
+		}
+		model.Transactions++
+		for _, f := range suggestFeatures(tx) {
+			counts, ok := model.Features[f.key]
+			if !ok {
+				counts = make(map[int64]int64)
+				model.Features[f.key] = counts
+			}
+			counts[*tx.CategoryID]++
+		}
+	}
+	return model
+}
+
+type suggestFeature struct {
+	kind string
+	key  string
+}
+
+// suggestFeatures extracts the cleaned payee, each payee word, the account,
+// a signed order-of-magnitude amount bucket and the weekday.
+func suggestFeatures(tx lunchmoney.Transaction) []suggestFeature {
+	var features []suggestFeature
+	payee := cleanPayee(rawPayee(tx))
+	if payee != "" {
+		features = append(features, suggestFeature{"payee", "payee:" + payee})
+		seen := make(map[string]bool)
+		for _, word := range strings.Fields(payee) {
+			if len(word) < 3 || seen[word] {
+				continue
+			}
+			seen[word] = true
+			features = append(features, suggestFeature{"token", "token:" + word})
+		}
+	}
+	if account := transactionAccountKey(tx); account != "" {
+		features = append(features, suggestFeature{"account", "account:" + account})
+	}
+	features = append(features, suggestFeature{"amount", "amount:" + amountBucket(tx.ToBase)})
+	if date, err := time.Parse("2006-01-02", tx.Date); err == nil {
+		features = append(features, suggestFeature{"weekday", "weekday:" + date.Weekday().String()[:3]})
+	}
+	return features
+}
+
+// amountBucket groups amounts by sign and power of ten: "out:10-100".
+func amountBucket(a lunchmoney.Amount) string {
+	dir := "out"
+	if a.Sign() < 0 {
+		dir = "in"
+	}
+	v := a.Abs().Float64()
+	if v < 1 {
+		return dir + ":0-1"
+	}
+	low := math.Pow(10, math.Floor(math.Log10(v)))
+	return fmt.Sprintf("%s:%g-%g", dir, low, low*10)
+}
+
+// suggest scores every category seen with the transaction's features. The
+// confidence is the share of payee evidence (exact payee, else payee words)
+// for the winning category, discounted by how often the payee was seen; with
+// no payee evidence it is at most 0.5.
+func (m *suggestModel) suggest(tx lunchmoney.Transaction, categories map[int64]categoryMeta) (categorySuggestion, bool) {
+	scores := make(map[int64]float64)
+	strong := make(map[int64]float64)
+	var (
+		strongWeight float64
+		weakWeight   float64
+		support      int64
+		reason       string
+		tokenDists   []map[int64]int64
+	)
+
+	addDist := func(counts map[int64]int64, weight float64, isStrong bool) {
+		var total int64
+		for _, n := range counts {
+			total += n
+		}
+		for cat, n := range counts {
+			if _, ok := categories[cat]; !ok {
+				continue
+			}
+			share := weight * float64(n) / float64(total)
+			scores[cat] += share
+			if isStrong {
+				strong[cat] += share
+			}
+		}
+	}
+
+	features := suggestFeatures(tx)
+	tokens := 0
+	for _, f := range features {
+		if f.kind == "token" {
+			tokens++
+		}
+	}
+
+	for _, f := range features {
+		counts, ok := m.Features[f.key]
+		if !ok {
+			continue
+		}
+		switch f.kind {
+		case "payee":
+			addDist(counts, suggestWeightPayee, true)
+			strongWeight += suggestWeightPayee
+			for _, n := range counts {
+				support += n
+			}
+			reason = fmt.Sprintf("payee seen %d time(s)", support)
+		case "token":
+			tokenDists = append(tokenDists, counts)
+		case "account":
+			addDist(counts, suggestWeightAccount, false)
+			weakWeight += suggestWeightAccount
+		case "amount":
+			addDist(counts, suggestWeightAmount, false)
+			weakWeight += suggestWeightAmount
+		case "weekday":
+			addDist(counts, suggestWeightWeekday, false)
+			weakWeight += suggestWeightWeekday
+		}
+	}
+
+	if len(tokenDists) > 0 {
+		// Unknown words dilute the evidence, so one shared word out of
+		// several is weak.
+		perToken := suggestWeightTokens / float64(tokens)
+		var tokenSupport int64
+		for _, counts := range tokenDists {
+			addDist(counts, perToken, true)
+			for _, n := range counts {
+				tokenSupport = max(tokenSupport, n)
+			}
+		}
+		strongWeight += suggestWeightTokens
+		if reason == "" {
+			support = tokenSupport
+			reason = "similar payee words"
+		}
+	}
+
+	best, bestScore := int64(0), -1.0
+	for cat, score := range scores {
+		if score > bestScore || (score == bestScore && cat < best) {
+			best, bestScore = cat, score
+		}
+	}
+	if bestScore <= 0 {
+		return categorySuggestion{}, false
+	}
+
+	var confidence float64
+	if strongWeight > 0 {
+		confidence = strong[best] / strongWeight * float64(support) / float64(support+1)
+	} else {
+		confidence = 0.5 * scores[best] / weakWeight
+		reason = "account, amount and weekday only"
+	}
+
+	return categorySuggestion{
+		ID:          tx.ID,
+		Date:        tx.Date,
+		Description: tx.Payee,
+		CategoryID:  best,
+		Category:    categories[best].Name,
+		Confidence:  math.Round(confidence*100) / 100,
+		Reason:      reason,
+	}, true
+}
+
+func (m *suggestModel) stale() bool {
+	trained, err := time.Parse(time.RFC3339, m.TrainedAt)
+	return err != nil || time.Since(trained) > suggestModelMaxAge
+}
+
+func suggestModelPath(fingerprint string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf(suggestModelFile, fingerprint)), nil
+}
+
+// loadSuggestModel returns the cached model, or nil when none is cached.
+func loadSuggestModel(ctx context.Context) (*suggestModel, error) {
+	path, err := suggestModelPath(keyFingerprint(currentKeyIdentity(ctx)))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var model suggestModel
+	if err := json.Unmarshal(raw, &model); err != nil {
+		return nil, fmt.Errorf("invalid suggestion model %s (delete it or use --retrain): %w", path, err)
+	}
+	return &model, nil
+}
+
+func saveSuggestModel(ctx context.Context, model *suggestModel) error {
+	path, err := suggestModelPath(keyFingerprint(currentKeyIdentity(ctx)))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o600)
+}
+
+func printSuggestions(suggestions []categorySuggestion) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "ID\tDATE\tDESCRIPTION\tAMOUNT\tSUGGESTION\tCONFIDENCE\tREASON")
+	for _, s := range suggestions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%.2f\t%s\n", s.ID, s.Date, s.Description, formatAmountWithCurrency(s.Amount, s.Currency), s.Category, s.Confidence, s.Reason)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,353 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+
+	// defaultMaxWidth keeps long payees and notes from pushing the amounts
+	// off narrow terminals.
+	defaultMaxWidth = 40
+
+	ansiReset = "\x1b[0m"
+	ansiDim   = "2"
+	ansiRed   = "31"
+)
+
+// txColumn is a column lm tx list can show. amount is set for money
+// columns, which are right-aligned and colored red when negative; wrap is
+// set for free text that --max-width truncates.
+type txColumn struct {
+	name    string
+	header  string
+	wrap    bool
+	value   func(tx transactionView) string
+	amount  func(tx transactionView) (lunchmoney.Amount, bool)
+	compare func(a, b transactionView) int
+}
+
+var txColumns = []txColumn{
+	{name: "date", header: "DATE", value: func(tx transactionView) string { return tx.Date }},
+	{
+		name:    "id",
+		header:  "ID",
+		value:   func(tx transactionView) string { return strconv.FormatInt(tx.ID, 10) },
+		compare: func(a, b transactionView) int { return cmp.Compare(a.ID, b.ID) },
+	},
+	{name: "description", header: "DESCRIPTION", wrap: true, value: func(tx transactionView) string { return tx.Description }},
+	{name: "category", header: "CATEGORY", value: func(tx transactionView) string { return tx.Category }},
+	{name: "group", header: "GROUP", value: func(tx transactionView) string { return tx.Group }},
+	{name: "notes", header: "NOTE", wrap: true, value: func(tx transactionView) string { return tx.Notes }},
+	{name: "tags", header: "TAGS", wrap: true, value: func(tx transactionView) string { return tx.Tags }},
+	{
+		name:   "amount",
+		header: "AMOUNT",
+		amount: func(tx transactionView) (lunchmoney.Amount, bool) { return tx.Amount, true },
+		value:  func(tx transactionView) string { return tx.Amount.Format(tx.Currency) },
+	},
+	{name: "currency", header: "CURRENCY", value: func(tx transactionView) string { return strings.ToUpper(tx.Currency) }},
+	{
+		name:   "original",
+		header: "ORIGINAL",
+		amount: func(tx transactionView) (lunchmoney.Amount, bool) {
+			return tx.OriginalAmount, tx.OriginalCurrency != tx.BaseCurrency
+		},
+		value: func(tx transactionView) string {
+			return formatAmountWithCurrency(tx.OriginalAmount, tx.OriginalCurrency)
+		},
+	},
+	{
+		name:   "base",
+		header: "BASE",
+		amount: func(tx transactionView) (lunchmoney.Amount, bool) {
+			return tx.BaseAmount, tx.OriginalCurrency != tx.BaseCurrency
+		},
+		value: func(tx transactionView) string {
+			return formatAmountWithCurrency(tx.BaseAmount, tx.BaseCurrency)
+		},
+	},
+	{name: "account", header: "ACCOUNT", value: func(tx transactionView) string { return tx.Account }},
+	{name: "institution", header: "INSTITUTION", value: func(tx transactionView) string { return tx.Institution }},
+	{name: "type", header: "TYPE", value: func(tx transactionView) string { return tx.Type }},
+	{name: "status", header: "STATUS", value: func(tx transactionView) string { return tx.Status }},
+	{name: "pending", header: "PENDING", value: func(tx transactionView) string { return strconv.FormatBool(tx.IsPending) }},
+	{
+		name:   "suggestion",
+		header: "SUGGESTION",
+		value: func(tx transactionView) string {
+			if tx.Suggestion == "" {
+				return ""
+			}
+			return fmt.Sprintf("%s (%.2f)", tx.Suggestion, tx.SuggestionConfidence)
+		},
+		compare: func(a, b transactionView) int { return cmp.Compare(a.SuggestionConfidence, b.SuggestionConfidence) },
+	},
+}
+
+// txColumnAliases accepts the names people reach for first.
+var txColumnAliases = map[string]string{
+	"payee": "description",
+	"note":  "notes",
+}
+
+func txColumnNames() []string {
+	names := make([]string, len(txColumns))
+	for i, c := range txColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+func lookupTxColumn(name, flag string) (txColumn, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := txColumnAliases[name]; ok {
+		name = alias
+	}
+	for _, c := range txColumns {
+		if c.name == name {
+			return c, nil
+		}
+	}
+	return txColumn{}, fmt.Errorf("invalid %s %q (expected one of %s)", flag, name, strings.Join(txColumnNames(), ", "))
+}
+
+// defaultTxColumns is the table lm tx list has always printed: AMOUNT in
+// the selected currency, the other amount for foreign-currency
+// transactions, and suggestions when there are any.
+func defaultTxColumns(transactions []transactionView, mode string) []string {
+	other := "original"
+	if mode == currencyOriginal {
+		other = "base"
+	}
+	names := []string{"date", "id", "description", "category", "notes", "amount", "currency", other, "account", "status", "pending"}
+	if slices.ContainsFunc(transactions, func(tx transactionView) bool { return tx.Suggestion != "" }) {
+		names = append(names, "suggestion")
+	}
+	return names
+}
+
+func parseTxColumns(names []string) ([]txColumn, error) {
+	columns := make([]txColumn, 0, len(names))
+	for _, name := range names {
+		c, err := lookupTxColumn(name, "--columns")
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// sortTransactions orders transactions by a column, ascending, keeping
+// the current order among equal values. Amounts and IDs compare as
+// numbers and text without regard to case.
+func sortTransactions(transactions []transactionView, column txColumn) {
+	compare := column.compare
+	switch {
+	case compare != nil:
+	case column.amount != nil:
+		compare = func(a, b transactionView) int {
+			x, _ := column.amount(a)
+			y, _ := column.amount(b)
+			return cmp.Compare(x, y)
+		}
+	default:
+		compare = func(a, b transactionView) int {
+			return strings.Compare(strings.ToLower(column.value(a)), strings.ToLower(column.value(b)))
+		}
+	}
+	slices.SortStableFunc(transactions, compare)
+}
+
+type tableOptions struct {
+	columns  []string
+	maxWidth int
+	color    bool
+}
+
+// printTransactionsTable prints AMOUNT in the currency selected by mode
+// ("base" or "original"). Without opts.columns, foreign-currency
+// transactions also show the other amount in a second column.
+func printTransactionsTable(transactions []transactionView, mode string, opts tableOptions) error {
+	if len(transactions) == 0 {
+		fmt.Println("No transactions found.")
+		return nil
+	}
+	names := opts.columns
+	if len(names) == 0 {
+		names = defaultTxColumns(transactions, mode)
+	}
+	columns, err := parseTxColumns(names)
+	if err != nil {
+		return err
+	}
+
+	t := textTable{color: opts.color, right: make([]bool, len(columns))}
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.header
+		t.right[i] = c.amount != nil
+	}
+	t.add(header, nil)
+	for _, tx := range transactions {
+		row := make([]string, len(columns))
+		styles := make([]string, len(columns))
+		for i, c := range columns {
+			var style []string
+			if tx.IsPending {
+				style = append(style, ansiDim)
+			}
+			if c.amount != nil {
+				amount, ok := c.amount(tx)
+				if !ok {
+					continue
+				}
+				if amount < 0 {
+					style = append(style, ansiRed)
+				}
+			}
+			row[i] = c.value(tx)
+			if c.wrap {
+				row[i] = truncate(row[i], opts.maxWidth)
+			}
+			styles[i] = strings.Join(style, ";")
+		}
+		t.add(row, styles)
+	}
+	t.write(os.Stdout)
+	return nil
+}
+
+// textTable aligns columns like newTabWriter does, but can right-align
+// amounts and color cells without the escape codes counting as width.
+type textTable struct {
+	rows   [][]string
+	styles [][]string
+	right  []bool
+	color  bool
+}
+
+func (t *textTable) add(row, styles []string) {
+	t.rows = append(t.rows, row)
+	t.styles = append(t.styles, styles)
+}
+
+func (t *textTable) write(w io.Writer) {
+	widths := make([]int, len(t.right))
+	for _, row := range t.rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	var b strings.Builder
+	for r, row := range t.rows {
+		b.Reset()
+		for i, cell := range row {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			last := i == len(row)-1
+			if t.right[i] {
+				b.WriteString(pad)
+			}
+			if style := t.styles[r]; t.color && style != nil && style[i] != "" && cell != "" {
+				b.WriteString("\x1b[" + style[i] + "m" + cell + ansiReset)
+			} else {
+				b.WriteString(cell)
+			}
+			if !t.right[i] && !last {
+				b.WriteString(pad)
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// truncate shortens s to at most width characters, marking the cut with an
+// ellipsis. A width of 0 or less leaves s alone.
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// useColor resolves --color: auto colors only a terminal, and NO_COLOR or
+// TERM=dumb turn it off.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid --color %q (expected auto, always or never)", mode)
+	}
+}
+
+// parseTxTemplate parses a --template run once per transaction. Besides
+// the transactionView fields it offers money, upper, lower and trunc.
+func parseTxTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("--template").Funcs(template.FuncMap{
+		"money": formatAmountWithCurrency,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trunc": func(width int, s string) string { return truncate(s, width) },
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func printTransactionsTemplate(transactions []transactionView, tmpl *template.Template) error {
+	for _, tx := range transactions {
+		if err := tmpl.Execute(os.Stdout, tx); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// completeTxColumns suggests column names for the comma-separated
+// --columns flag.
+func completeTxColumns(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, toComplete = toComplete[:i+1], toComplete[i+1:]
+	}
+	var out []cobra.Completion
+	for _, name := range txColumnNames() {
+		if strings.HasPrefix(name, strings.ToLower(toComplete)) {
+			out = append(out, prefix+name)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
@@ -0,0 +1,382 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// transferPayeeHints are payee fragments that suggest money moving between
+// the user's own accounts.
+var transferPayeeHints = []string{
+	"payment", "pymt", "transfer", "xfer", "autopay", "auto pay", "thank you",
+	"credit card", "card services", "online banking", "epay", "e-payment",
+}
+
+// transferMatch is a scored pair of opposite transfer legs.
+type transferMatch struct {
+	Out     transactionView   `json:"out"`
+	In      transactionView   `json:"in"`
+	Amount  lunchmoney.Amount `json:"amount"`
+	GapDays int               `json:"gap_days"`
+	Score   float64           `json:"score"`
+	Reasons []string          `json:"reasons"`
+
+	outTx lunchmoney.Transaction
+	inTx  lunchmoney.Transaction
+}
+
+func newTransfersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "transfers",
+		Short: "Work with transfers between your own accounts",
+	}
+	cmd.AddCommand(newTransfersDetectCmd())
+	return cmd
+}
+
+func newTransfersDetectCmd() *cobra.Command {
+	var (
+		startDate  string
+		endDate    string
+		days       int
+		category   string
+		auto       bool
+		minScore   float64
+		yes        bool
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "detect",
+		Short: "Find opposite-amount pairs across accounts and group them as transfers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endDate == "" {
+				endDate = time.Now().Format("2006-01-02")
+			}
+			if err := validateDateRange(startDate, endDate); err != nil {
+				return err
+			}
+			if days < 0 {
+				return errors.New("--days cannot be negative")
+			}
+			if minScore < 0 || minScore > 1 {
+				return errors.New("--min-score must be between 0 and 1")
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			lookups, err := loadTxLookups(ctx, client)
+			if err != nil {
+				return err
+			}
+			// Resolve the category before any output so a renamed or
+			// deleted default fails before matches are shown.
+			var categoryID int64
+			if !jsonOutput {
+				if categoryID, err = resolveCategoryID(lookups.categories, category); err != nil {
+					return err
+				}
+			}
+			transactions, err := client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+				StartDate: startDate,
+				EndDate:   endDate,
+				Limit:     1000,
+			})
+			if err != nil {
+				return err
+			}
+
+			matches := detectTransfers(transactions, lookups, days)
+			if jsonOutput {
+				return printJSON(matches)
+			}
+			if len(matches) == 0 {
+				fmt.Println("No transfer pairs found.")
+				return nil
+			}
+			printTransferMatches(matches, lookups.baseCurrency)
+
+			// planned counts the pairs to group, to report how far an
+			// interrupted run got. Prompted pairs count once accepted.
+			interactive := !auto && !yes && !client.DryRun()
+			planned := 0
+			if !interactive {
+				for _, m := range matches {
+					if !auto || m.Score >= minScore {
+						planned++
+					}
+				}
+			}
+
+			var legs []int64
+			grouped, failed := 0, 0
+			for _, m := range matches {
+				if ctx.Err() != nil {
+					break
+				}
+				accept := false
+				switch {
+				case auto:
+					accept = m.Score >= minScore
+				case !interactive:
+					accept = true
+				default:
+					accept, err = confirm(cmd.Context(), fmt.Sprintf("Group %d and %d (%s) as a transfer?", m.outTx.ID, m.inTx.ID, formatAmountWithCurrency(m.Amount, lookups.baseCurrency)))
+					if err != nil {
+						return err
+					}
+					if accept {
+						planned++
+					}
+				}
+				if !accept {
+					continue
+				}
+
+				group, err := applyTransferMatch(ctx, client, m, categoryID)
+				if errors.Is(err, lunchmoney.ErrNotSent) {
+					break
+				}
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "failed to group %d and %d: %v\n", m.outTx.ID, m.inTx.ID, err)
+					continue
+				}
+				grouped++
+				legs = append(legs, m.outTx.ID, m.inTx.ID)
+				if client.DryRun() {
+					fmt.Printf("Would group %d and %d as a transfer.\n", m.outTx.ID, m.inTx.ID)
+				} else {
+					fmt.Printf("Grouped %d and %d as a transfer (group %d).\n", m.outTx.ID, m.inTx.ID, group.ID)
+				}
+			}
+
+			if err := stopped(ctx, legs, 2*planned); err != nil {
+				return err
+			}
+			fmt.Printf("%d pair(s) grouped, %d skipped.\n", grouped, len(matches)-grouped-failed)
+			if failed > 0 {
+				return fmt.Errorf("%d pair(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
+	cmd.Flags().IntVar(&days, "days", 3, "Maximum days between the two legs of a transfer")
+	cmd.Flags().StringVar(&category, "category", "Payment, Transfer", "Category (name or ID) to assign to both legs")
+	_ = cmd.RegisterFlagCompletionFunc("category", completeNames(completionCategories, false))
+	cmd.Flags().BoolVar(&auto, "auto", false, "Group matches scoring at least --min-score without prompting")
+	cmd.Flags().Float64Var(&minScore, "min-score", 0.8, "Minimum score for --auto")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Group every match without prompting")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output matches as JSON without changing anything")
+	cmd.MarkFlagsMutuallyExclusive("auto", "yes", "json")
+	_ = cmd.MarkFlagRequired("start")
+
+	return cmd
+}
+
+// detectTransfers scores every candidate pair and keeps the best pairing,
+// highest score first, so each transaction appears in at most one match.
+// Recurring transactions are skipped because the API cannot group them.
+func detectTransfers(transactions []lunchmoney.Transaction, lookups txLookups, days int) []transferMatch {
+	eligible := make([]lunchmoney.Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if tx.RecurringID == nil && !tx.IsPending {
+			eligible = append(eligible, tx)
+		}
+	}
+	// Views are built one by one so "already a transfer" reflects the
+	// category, not pairing within this batch.
+	views := make([]transactionView, len(eligible))
+	for i, tx := range eligible {
+		views[i] = lookups.view(tx)
+	}
+
+	candidates := transferCandidates(eligible, days)
+	matches := make([]transferMatch, 0, len(candidates))
+	for _, c := range candidates {
+		m := transferMatch{
+			Out:     views[c.out],
+			In:      views[c.in],
+			Amount:  eligible[c.out].ToBase.Abs(),
+			GapDays: c.gapDays,
+			outTx:   eligible[c.out],
+			inTx:    eligible[c.in],
+		}
+		m.Score, m.Reasons = scoreTransfer(m)
+		matches = append(matches, m)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].GapDays != matches[j].GapDays {
+			return matches[i].GapDays < matches[j].GapDays
+		}
+		if matches[i].outTx.ID != matches[j].outTx.ID {
+			return matches[i].outTx.ID < matches[j].outTx.ID
+		}
+		return matches[i].inTx.ID < matches[j].inTx.ID
+	})
+
+	used := make(map[int64]bool)
+	kept := matches[:0]
+	for _, m := range matches {
+		if used[m.outTx.ID] || used[m.inTx.ID] {
+			continue
+		}
+		used[m.outTx.ID] = true
+		used[m.inTx.ID] = true
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// scoreTransfer starts from the exact opposite amounts on different accounts
+// and adds confidence for close dates and payee hints.
+func scoreTransfer(m transferMatch) (float64, []string) {
+	score := 0.4
+	reasons := []string{"opposite amounts on different accounts"}
+
+	switch {
+	case m.GapDays == 0:
+		score += 0.2
+		reasons = append(reasons, "same day")
+	case m.GapDays == 1:
+		score += 0.15
+		reasons = append(reasons, "1 day apart")
+	case m.GapDays <= 3:
+		score += 0.1
+		reasons = append(reasons, fmt.Sprintf("%d days apart", m.GapDays))
+	}
+
+	if hint := transferHint(m.Out.Description, m.In.Description); hint != "" {
+		score += 0.2
+		reasons = append(reasons, fmt.Sprintf("payee mentions %q", hint))
+	}
+	if mentionsAccount(m.Out.Description, m.In) || mentionsAccount(m.In.Description, m.Out) {
+		score += 0.15
+		reasons = append(reasons, "payee names the other account")
+	}
+	if m.Out.Type == txTypeTransfer || m.In.Type == txTypeTransfer {
+		score += 0.1
+		reasons = append(reasons, "already classified as transfer")
+	}
+
+	return math.Round(min(score, 1)*100) / 100, reasons
+}
+
+func transferHint(payees ...string) string {
+	for _, payee := range payees {
+		lower := strings.ToLower(payee)
+		for _, hint := range transferPayeeHints {
+			if strings.Contains(lower, hint) {
+				return hint
+			}
+		}
+	}
+	return ""
+}
+
+// mentionsAccount reports whether the payee contains a word of four or more
+// letters from the other leg's account or institution name.
+func mentionsAccount(payee string, other transactionView) bool {
+	lower := strings.ToLower(payee)
+	for _, word := range strings.Fields(strings.ToLower(other.Account + " " + other.Institution)) {
+		word = strings.Trim(word, ".,-()")
+		if len(word) >= 4 && strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTransferMatch categorizes both legs and groups them into a single
+// transaction, journaling both steps as one entry.
+func applyTransferMatch(ctx context.Context, client *lunchmoney.Client, m transferMatch, categoryID int64) (lunchmoney.Transaction, error) {
+	var updates []lunchmoney.BulkTransactionUpdate
+	befores := make(map[int64]lunchmoney.Transaction, 2)
+	for _, tx := range []lunchmoney.Transaction{m.outTx, m.inTx} {
+		if tx.CategoryID != nil && *tx.CategoryID == categoryID {
+			continue
+		}
+		id := categoryID
+		updates = append(updates, lunchmoney.BulkTransactionUpdate{ID: tx.ID, Current: &tx, TransactionUpdate: lunchmoney.TransactionUpdate{CategoryID: &id}})
+		befores[tx.ID] = tx
+	}
+
+	var changes []journalChange
+	defer func() { recordMutation(ctx, changes) }()
+	if len(updates) > 0 {
+		updated, errs := client.UpdateTransactions(ctx, updates)
+		afterByID := make(map[int64]lunchmoney.Transaction, len(updated))
+		for _, tx := range updated {
+			afterByID[tx.ID] = tx
+		}
+		var firstErr error
+		for i, err := range errs {
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			id := updates[i].ID
+			changes = append(changes, journalChange{TxID: id, Fields: updates[i].Fields(), Before: befores[id], After: afterByID[id]})
+		}
+		if firstErr != nil {
+			return lunchmoney.Transaction{}, firstErr
+		}
+	}
+
+	payee := fmt.Sprintf("Transfer: %s to %s", m.Out.Account, m.In.Account)
+	if r := []rune(payee); len(r) > 140 {
+		payee = string(r[:140])
+	}
+	children := []int64{m.outTx.ID, m.inTx.ID}
+	group, err := client.GroupTransactions(ctx, lunchmoney.GroupTransactionsRequest{
+		IDs:        children,
+		Date:       m.outTx.Date,
+		Payee:      payee,
+		CategoryID: &categoryID,
+		Status:     "reviewed",
+	})
+	if err != nil {
+		return lunchmoney.Transaction{}, err
+	}
+	changes = append(changes, journalChange{TxID: group.ID, Action: journalGroup, Children: children, After: group})
+	return group, nil
+}
+
+func printTransferMatches(matches []transferMatch, baseCurrency string) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "SCORE\tAMOUNT\tOUT\tOUT_DATE\tFROM\tOUT_PAYEE\tIN\tIN_DATE\tTO\tIN_PAYEE")
+	for _, m := range matches {
+		fmt.Fprintf(
+			w,
+			"%.2f\t%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			m.Score,
+			formatAmountWithCurrency(m.Amount, baseCurrency),
+			m.Out.ID, m.Out.Date, m.Out.Account, m.Out.Description,
+			m.In.ID, m.In.Date, m.In.Account, m.In.Description,
+		)
+	}
+	_ = w.Flush()
+}
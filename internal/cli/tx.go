@@ -2,14 +2,20 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 
 	"lunchmoney-cli/internal/lunchmoney"
 )
@@ -35,6 +41,10 @@ func newTxCmd() *cobra.Command {
 	txCmd.AddCommand(newTxListCmd())
 	txCmd.AddCommand(newTxUpdateCmd())
 	txCmd.AddCommand(newTxMarkReviewedCmd())
+	txCmd.AddCommand(newTxApplyCmd())
+	txCmd.AddCommand(newTxEditCmd())
+	txCmd.AddCommand(newTxDuplicatesCmd())
+	txCmd.AddCommand(newTxSuggestCmd())
 
 	return txCmd
 }
@@ -45,7 +55,17 @@ func newTxListCmd() *cobra.Command {
 		endDate        string
 		unreviewed     bool
 		includePending bool
+		currencyMode   string
+		totals         bool
+		txType         string
+		format         string
 		jsonOutput     bool
+		columns        []string
+		sortBy         string
+		reverse        bool
+		templateText   string
+		maxWidth       int
+		colorMode      string
 	)
 
 	cmd := &cobra.Command{
@@ -66,16 +86,71 @@ func newTxListCmd() *cobra.Command {
 			if includePending && !unreviewed {
 				return errors.New("--include-pending requires --unreviewed (pending transactions are always unreviewed)")
 			}
+			if currencyMode != currencyBase && currencyMode != currencyOriginal {
+				return fmt.Errorf("invalid --currency %q (expected base or original)", currencyMode)
+			}
+			switch txType {
+			case "", txTypeExpense, txTypeIncome, txTypeTransfer:
+			default:
+				return fmt.Errorf("invalid --type %q (expected expense, income or transfer)", txType)
+			}
+			if jsonOutput {
+				if format != "" && format != "json" {
+					return fmt.Errorf("--json cannot be combined with --format %s", format)
+				}
+				format = "json"
+			}
+			switch format {
+			case "":
+				format = "table"
+			case "table", "json", "ndjson", "csv":
+			default:
+				return fmt.Errorf("invalid --format %q (expected table, json, ndjson or csv)", format)
+			}
+			if templateText != "" && format != "table" {
+				return fmt.Errorf("--template cannot be combined with --format %s", format)
+			}
+			if len(columns) > 0 && (format != "table" || templateText != "") {
+				return errors.New("--columns only applies to the table format")
+			}
+			if (sortBy != "" || reverse) && (format == "ndjson" || format == "csv") {
+				return fmt.Errorf("--sort and --reverse cannot be combined with --format %s (it streams in date order)", format)
+			}
+			var sortColumn *txColumn
+			if sortBy != "" {
+				c, err := lookupTxColumn(sortBy, "--sort")
+				if err != nil {
+					return err
+				}
+				sortColumn = &c
+			}
+			if _, err := parseTxColumns(columns); err != nil {
+				return err
+			}
+			var tmpl *template.Template
+			if templateText != "" {
+				var err error
+				if tmpl, err = parseTxTemplate(templateText); err != nil {
+					return err
+				}
+			}
+			if maxWidth < 0 {
+				return fmt.Errorf("invalid --max-width %d (expected 0 or more)", maxWidth)
+			}
+			color, err := useColor(colorMode)
+			if err != nil {
+				return err
+			}
 
-			client, err := lunchmoney.NewFromEnv()
+			client, err := newClient(cmd.Context())
 			if err != nil {
 				return err
 			}
 
 			params := lunchmoney.ListTransactionsParams{
-				StartDate:      startDate,
-				EndDate:        endDate,
-				Limit:          1000,
+				StartDate: startDate,
+				EndDate:   endDate,
+				Limit:     1000,
 			}
 			if includePending {
 				pendingOnly := true
@@ -84,53 +159,64 @@ func newTxListCmd() *cobra.Command {
 				params.Status = status
 			}
 
-			transactions, err := client.ListTransactions(context.Background(), params)
-			if err != nil {
-				return err
+			if format == "ndjson" || format == "csv" {
+				return streamTxList(cmd.Context(), client, params, format, currencyMode, txType, unreviewed)
 			}
 
-			categories, err := client.ListCategories(context.Background())
-			if err != nil {
+			var (
+				transactions []lunchmoney.Transaction
+				lookups      txLookups
+			)
+			g, ctx := errgroup.WithContext(cmd.Context())
+			g.Go(func() (err error) {
+				transactions, err = client.ListTransactions(ctx, params)
 				return err
-			}
-			categoryLookup := buildCategoryLookup(categories)
-
-			tags, err := client.ListTags(context.Background())
-			if err != nil {
+			})
+			g.Go(func() (err error) {
+				lookups, err = loadTxLookups(ctx, client)
 				return err
-			}
-			tagLookup := make(map[int64]string, len(tags))
-			for _, t := range tags {
-				tagLookup[t.ID] = t.Name
-			}
-
-			manualAccounts, err := client.ListManualAccounts(context.Background())
-			if err != nil {
+			})
+			if err := g.Wait(); err != nil {
 				return err
 			}
-			manualLookup := buildManualAccountLookup(manualAccounts)
 
-			plaidAccounts, err := client.ListPlaidAccounts(context.Background())
-			if err != nil {
-				return err
+			// Classify the whole batch so transfer legs can pair up even when
+			// one of them is filtered out below.
+			all := lookups.views(transactions)
+			if unreviewed {
+				addSuggestions(ctx, all, transactions, lookups)
 			}
-			plaidLookup := buildPlaidAccountLookup(plaidAccounts)
-
-			views := make([]transactionView, 0, len(transactions))
-			for _, tx := range transactions {
-				if !unreviewed && shouldExcludeFromTotalsFilter(tx, categoryLookup) {
-					continue
+			views := make([]transactionView, 0, len(all))
+			for i, tx := range transactions {
+				if listed(tx, all[i], lookups, unreviewed, txType) {
+					views = append(views, all[i])
 				}
-				views = append(views, toTransactionView(tx, categoryLookup, tagLookup, manualLookup, plaidLookup))
 			}
 
 			sortTransactionsNewestFirst(views)
+			applyCurrencyMode(views, currencyMode)
+			if sortColumn != nil {
+				sortTransactions(views, *sortColumn)
+			}
+			if reverse {
+				slices.Reverse(views)
+			}
 
-			if jsonOutput {
+			if format == "json" {
 				return printJSON(views)
 			}
 
-			printTransactionsTable(views)
+			if tmpl != nil {
+				err = printTransactionsTemplate(views, tmpl)
+			} else {
+				err = printTransactionsTable(views, currencyMode, tableOptions{columns: columns, maxWidth: maxWidth, color: color})
+			}
+			if err != nil {
+				return err
+			}
+			if totals {
+				printTotals(views)
+			}
 			return nil
 		},
 	}
@@ -139,81 +225,292 @@ func newTxListCmd() *cobra.Command {
 	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
 	cmd.Flags().BoolVar(&unreviewed, "unreviewed", false, "List unreviewed transactions (default is reviewed)")
 	cmd.Flags().BoolVar(&includePending, "include-pending", false, "List pending transactions only (requires --unreviewed)")
-	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON")
+	cmd.Flags().StringVar(&currencyMode, "currency", currencyBase, "Amount to show: base (primary currency) or original")
+	cmd.Flags().BoolVar(&totals, "totals", false, "Print totals in base currency with a per-currency breakdown")
+	cmd.Flags().StringVar(&txType, "type", "", "Only show transactions of this type: expense, income or transfer")
+	cmd.Flags().StringVar(&format, "format", "", "Output format: table, json, ndjson or csv (ndjson and csv stream oldest first)")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]cobra.Completion{"table", "json", "ndjson", "csv"}, cobra.ShellCompDirectiveNoFileComp))
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON (same as --format json)")
+	cmd.Flags().StringSliceVar(&columns, "columns", nil, "Table columns in order, e.g. date,payee,amount,tags")
+	_ = cmd.RegisterFlagCompletionFunc("columns", completeTxColumns)
+	cmd.Flags().StringVar(&sortBy, "sort", "", "Sort by a column, ascending (default is newest first)")
+	_ = cmd.RegisterFlagCompletionFunc("sort", cobra.FixedCompletions(txColumnNames(), cobra.ShellCompDirectiveNoFileComp))
+	cmd.Flags().BoolVar(&reverse, "reverse", false, "Reverse the sort order")
+	cmd.Flags().StringVar(&templateText, "template", "", "Print each transaction with a Go text/template, e.g. '{{.Date}} {{money .Amount .Currency}}'")
+	cmd.Flags().IntVar(&maxWidth, "max-width", defaultMaxWidth, "Truncate descriptions, notes and tags to this many characters (0 for no limit)")
+	cmd.Flags().StringVar(&colorMode, "color", colorAuto, "Color negative amounts and pending rows: auto, always or never")
+	_ = cmd.RegisterFlagCompletionFunc("color", cobra.FixedCompletions([]cobra.Completion{colorAuto, colorAlways, colorNever}, cobra.ShellCompDirectiveNoFileComp))
 	_ = cmd.MarkFlagRequired("start")
 
 	return cmd
 }
 
+// listed reports whether lm tx list shows a transaction: reviewed listings
+// skip categories excluded from totals, and --type filters on the view.
+func listed(tx lunchmoney.Transaction, v transactionView, lookups txLookups, unreviewed bool, txType string) bool {
+	if !unreviewed && shouldExcludeFromTotalsFilter(tx, lookups.categoryByID) {
+		return false
+	}
+	return txType == "" || v.Type == txType
+}
+
+// streamTxList writes ndjson or csv rows page by page in the API's date
+// order, so long ranges print immediately and memory stays flat.
+func streamTxList(ctx context.Context, client *lunchmoney.Client, params lunchmoney.ListTransactionsParams, format, currencyMode, txType string, unreviewed bool) error {
+	lookups, err := loadTxLookups(ctx, client)
+	if err != nil {
+		return err
+	}
+	var suggest func(lunchmoney.Transaction, *transactionView)
+	if unreviewed {
+		suggest = loadSuggester(ctx, lookups)
+	}
+
+	out := newViewWriter(os.Stdout, format, unreviewed)
+	err = lookups.eachView(client.IterTransactions(ctx, params), func(tx lunchmoney.Transaction, v transactionView) error {
+		if !listed(tx, v, lookups, unreviewed, txType) {
+			return nil
+		}
+		if suggest != nil {
+			suggest(tx, &v)
+		}
+		if currencyMode == currencyOriginal {
+			v.Amount, v.Currency = v.OriginalAmount, v.OriginalCurrency
+		}
+		return out.write(v)
+	})
+	if closeErr := out.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 func newTxUpdateCmd() *cobra.Command {
 	var (
-		categoryID int64
-		note       string
+		categoryID          int64
+		category            string
+		clearCategory       bool
+		note                string
+		clearNote           bool
+		date                string
+		amount              string
+		currency            string
+		payee               string
+		status              string
+		recurringID         int64
+		clearRecurring      bool
+		tags                []string
+		addTags             []string
+		clearTags           bool
+		externalID          string
+		clearExternalID     bool
+		customMetadata      string
+		clearCustomMetadata bool
+		manualAccountID     int64
+		plaidAccountID      int64
+		cash                bool
 	)
 
 	cmd := &cobra.Command{
-		Use:   "update <tx-id>",
-		Short: "Update a transaction category and/or note",
-		Args:  cobra.ExactArgs(1),
+		Use:               "update <tx-id>",
+		ValidArgsFunction: completeUnreviewedIDs(true),
+		Short:             "Update fields on a transaction",
+		Args:              cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			txID, err := parseTxID(args[0])
 			if err != nil {
 				return err
 			}
 
-			categorySet := cmd.Flags().Changed("category-id")
-			noteSet := cmd.Flags().Changed("note")
-			if !categorySet && !noteSet {
-				return errors.New("must provide at least one of --category-id or --note")
+			flags := cmd.Flags()
+			var update lunchmoney.TransactionUpdate
+			if flags.Changed("category-id") {
+				if categoryID <= 0 {
+					return errors.New("--category-id must be a positive integer")
+				}
+				update.CategoryID = &categoryID
+			}
+			update.ClearCategory = clearCategory
+
+			if flags.Changed("note") {
+				if strings.TrimSpace(note) == "" {
+					return errors.New("--note cannot be empty (use --clear-note to remove the note)")
+				}
+				noteValue := note
+				update.Notes = &noteValue
+			}
+			update.ClearNotes = clearNote
+
+			if flags.Changed("date") {
+				if _, err := time.Parse("2006-01-02", date); err != nil {
+					return fmt.Errorf("invalid --date %q (expected YYYY-MM-DD)", date)
+				}
+				update.Date = &date
+			}
+			if flags.Changed("amount") {
+				parsed, err := lunchmoney.ParseAmount(amount)
+				if err != nil || !amountPattern.MatchString(amount) {
+					return fmt.Errorf("invalid --amount %q (expected a number with up to 4 decimal places)", amount)
+				}
+				update.Amount = &parsed
+			}
+			if flags.Changed("currency") {
+				if len(currency) != 3 {
+					return fmt.Errorf("invalid --currency %q (expected a three-letter ISO 4217 code)", currency)
+				}
+				update.Currency = &currency
+			}
+			if flags.Changed("payee") {
+				if strings.TrimSpace(payee) == "" {
+					return errors.New("--payee cannot be empty")
+				}
+				update.Payee = &payee
+			}
+			if flags.Changed("status") {
+				if status != "reviewed" && status != "unreviewed" {
+					return fmt.Errorf("invalid --status %q (expected reviewed or unreviewed)", status)
+				}
+				update.Status = &status
+			}
+
+			if flags.Changed("recurring-id") {
+				if recurringID <= 0 {
+					return errors.New("--recurring-id must be a positive integer")
+				}
+				update.RecurringID = &recurringID
 			}
-			if categorySet && categoryID <= 0 {
-				return errors.New("--category-id must be a positive integer")
+			update.ClearRecurring = clearRecurring
+
+			if flags.Changed("external-id") {
+				update.ExternalID = &externalID
 			}
-			if noteSet && strings.TrimSpace(note) == "" {
-				return errors.New("--note cannot be empty")
+			update.ClearExternalID = clearExternalID
+
+			if flags.Changed("custom-metadata") {
+				var metadata map[string]any
+				if err := json.Unmarshal([]byte(customMetadata), &metadata); err != nil || metadata == nil {
+					return errors.New("--custom-metadata must be a JSON object")
+				}
+				update.CustomMetadata = metadata
 			}
+			update.ClearCustomMetadata = clearCustomMetadata
 
-			var categoryPtr *int64
-			if categorySet {
-				categoryPtr = &categoryID
+			if flags.Changed("manual-account-id") {
+				if manualAccountID <= 0 {
+					return errors.New("--manual-account-id must be a positive integer")
+				}
+				update.ManualAccountID = &manualAccountID
 			}
-			var notePtr *string
-			if noteSet {
-				noteValue := note
-				notePtr = &noteValue
+			if flags.Changed("plaid-account-id") {
+				if plaidAccountID <= 0 {
+					return errors.New("--plaid-account-id must be a positive integer")
+				}
+				update.PlaidAccountID = &plaidAccountID
 			}
+			update.ClearAccount = cash
+
+			update.ClearTags = clearTags
 
-			client, err := lunchmoney.NewFromEnv()
+			// --category and --tags are resolved to IDs below; count them
+			// here so a bare global flag like --dry-run cannot send an
+			// empty PUT.
+			if update.IsEmpty() && !flags.Changed("category") && !flags.Changed("tags") && !flags.Changed("add-tags") {
+				return errors.New("must provide at least one field to update (see --help)")
+			}
+
+			client, err := newClient(cmd.Context())
 			if err != nil {
 				return err
 			}
-			if _, err := client.UpdateTransaction(context.Background(), txID, categoryPtr, notePtr); err != nil {
-				return err
+
+			if flags.Changed("category") {
+				categories, err := client.ListCategories(cmd.Context())
+				if err != nil {
+					return err
+				}
+				id, err := resolveCategoryID(categories, category)
+				if err != nil {
+					return err
+				}
+				update.CategoryID = &id
 			}
 
-			updatedFields := make([]string, 0, 2)
-			if categorySet {
-				updatedFields = append(updatedFields, "category")
+			if flags.Changed("tags") || flags.Changed("add-tags") {
+				allTags, err := client.ListTags(cmd.Context())
+				if err != nil {
+					return err
+				}
+				if flags.Changed("tags") {
+					update.TagIDs, err = resolveTagIDs(allTags, tags)
+				} else {
+					update.AdditionalTagIDs, err = resolveTagIDs(allTags, addTags)
+				}
+				if err != nil {
+					return err
+				}
 			}
-			if noteSet {
-				updatedFields = append(updatedFields, "note")
+
+			before, err := client.GetTransaction(cmd.Context(), txID)
+			if err != nil {
+				return err
 			}
-			fmt.Printf("Updated transaction %d (%s).\n", txID, strings.Join(updatedFields, ", "))
+			after, err := client.UpdateTransaction(cmd.Context(), txID, update)
+			if err != nil {
+				return err
+			}
+			recordMutation(cmd.Context(), []journalChange{{TxID: txID, Fields: update.Fields(), Before: before, After: after}})
+
+			fmt.Printf("Updated transaction %d (%s).\n", txID, strings.Join(updatedFieldNames(update), ", "))
 			return nil
 		},
 	}
 
 	cmd.Flags().Int64Var(&categoryID, "category-id", 0, "Category ID")
+	cmd.Flags().StringVar(&category, "category", "", "Category name or ID")
+	cmd.Flags().BoolVar(&clearCategory, "clear-category", false, "Remove the transaction's category")
 	cmd.Flags().StringVar(&note, "note", "", "Transaction note")
+	cmd.Flags().BoolVar(&clearNote, "clear-note", false, "Remove the transaction's note")
+	cmd.Flags().StringVar(&date, "date", "", "Transaction date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&amount, "amount", "", "Amount (positive is a debit, negative is a credit)")
+	cmd.Flags().StringVar(&currency, "currency", "", "Three-letter currency code")
+	cmd.Flags().StringVar(&payee, "payee", "", "Payee name")
+	cmd.Flags().StringVar(&status, "status", "", "Review status (reviewed or unreviewed)")
+	cmd.Flags().Int64Var(&recurringID, "recurring-id", 0, "Recurring item ID")
+	cmd.Flags().BoolVar(&clearRecurring, "clear-recurring", false, "Detach the transaction from its recurring item")
+	cmd.Flags().StringSliceVar(&tags, "tags", nil, "Replace tags (comma-separated names or IDs)")
+	cmd.Flags().StringSliceVar(&addTags, "add-tags", nil, "Add tags (comma-separated names or IDs)")
+	cmd.Flags().BoolVar(&clearTags, "clear-tags", false, "Remove all tags")
+	cmd.Flags().StringVar(&externalID, "external-id", "", "External ID (manual accounts only)")
+	cmd.Flags().BoolVar(&clearExternalID, "clear-external-id", false, "Remove the external ID")
+	cmd.Flags().StringVar(&customMetadata, "custom-metadata", "", "Custom metadata as a JSON object")
+	cmd.Flags().BoolVar(&clearCustomMetadata, "clear-custom-metadata", false, "Remove custom metadata")
+	cmd.Flags().Int64Var(&manualAccountID, "manual-account-id", 0, "Move to a manual account")
+	cmd.Flags().Int64Var(&plaidAccountID, "plaid-account-id", 0, "Move to a Plaid account")
+	cmd.Flags().BoolVar(&cash, "cash", false, "Detach from any account (cash transaction)")
+	cmd.MarkFlagsMutuallyExclusive("category-id", "category", "clear-category")
+	cmd.MarkFlagsMutuallyExclusive("note", "clear-note")
+	cmd.MarkFlagsMutuallyExclusive("recurring-id", "clear-recurring")
+	cmd.MarkFlagsMutuallyExclusive("tags", "add-tags", "clear-tags")
+	cmd.MarkFlagsMutuallyExclusive("external-id", "clear-external-id")
+	cmd.MarkFlagsMutuallyExclusive("custom-metadata", "clear-custom-metadata")
+	cmd.MarkFlagsMutuallyExclusive("manual-account-id", "plaid-account-id", "cash")
+	_ = cmd.RegisterFlagCompletionFunc("category-id", completeIDs(completionCategories))
+	_ = cmd.RegisterFlagCompletionFunc("category", completeNames(completionCategories, false))
+	_ = cmd.RegisterFlagCompletionFunc("tags", completeNames(completionTags, true))
+	_ = cmd.RegisterFlagCompletionFunc("add-tags", completeNames(completionTags, true))
+	_ = cmd.RegisterFlagCompletionFunc("manual-account-id", completeIDs(completionManual))
+	_ = cmd.RegisterFlagCompletionFunc("plaid-account-id", completeIDs(completionPlaid))
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions([]cobra.Completion{"reviewed", "unreviewed"}, cobra.ShellCompDirectiveNoFileComp))
 
 	return cmd
 }
 
 func newTxMarkReviewedCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "mark-reviewed <tx-id> [<tx-id>...]",
-		Short: "Mark one or more transactions as reviewed",
-		Args:  cobra.MinimumNArgs(1),
+		Use:               "mark-reviewed <tx-id> [<tx-id>...]",
+		ValidArgsFunction: completeUnreviewedIDs(false),
+		Short:             "Mark one or more transactions as reviewed",
+		Args:              cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ids := make([]int64, 0, len(args))
 			for _, raw := range args {
@@ -224,11 +521,28 @@ func newTxMarkReviewedCmd() *cobra.Command {
 				ids = append(ids, id)
 			}
 
-			client, err := lunchmoney.NewFromEnv()
+			client, err := newClient(cmd.Context())
 			if err != nil {
 				return err
 			}
-			updated, err := client.MarkReviewed(context.Background(), ids)
+			befores := make(map[int64]lunchmoney.Transaction, len(ids))
+			for _, id := range ids {
+				tx, err := client.GetTransaction(cmd.Context(), id)
+				if err != nil {
+					return err
+				}
+				befores[id] = tx
+			}
+
+			updated, err := client.MarkReviewed(cmd.Context(), ids)
+			changes := make([]journalChange, 0, len(updated))
+			for _, tx := range updated {
+				changes = append(changes, journalChange{TxID: tx.ID, Fields: []string{"status"}, Before: befores[tx.ID], After: tx})
+			}
+			recordMutation(cmd.Context(), changes)
+			if err := stopped(cmd.Context(), changedIDs(changes), len(ids)); err != nil {
+				return err
+			}
 			if err != nil {
 				return err
 			}
@@ -256,6 +570,8 @@ func validateDateRange(startDate, endDate string) error {
 	return nil
 }
 
+var amountPattern = regexp.MustCompile(`^-?\d+(\.\d{1,4})?$`)
+
 func parseTxID(raw string) (int64, error) {
 	id, err := strconv.ParseInt(raw, 10, 64)
 	if err != nil || id <= 0 {
@@ -332,27 +648,26 @@ func toTransactionView(
 	tags map[int64]string,
 	manual map[int64]accountMeta,
 	plaid map[int64]accountMeta,
+	baseCurrency string,
+	txType string,
 ) transactionView {
-	normalizedAmount := -tx.ToBase
+	normalizedAmount := tx.ToBase.Neg()
+
+	// The API reports amount in the transaction's own currency and to_base in
+	// the user's primary currency. Both are flipped to outflow-negative.
+	originalAmount := tx.Amount.Neg()
+	originalCurrency := strings.ToLower(tx.Currency)
+	if originalCurrency == "" {
+		originalAmount = normalizedAmount
+		originalCurrency = baseCurrency
+	}
 
 	categoryName := ""
 	categoryGroup := ""
-	txType := "expense"
-	if normalizedAmount > 0 {
-		txType = "income"
-	}
-
 	if tx.CategoryID != nil {
 		if c, ok := categories[*tx.CategoryID]; ok {
 			categoryName = c.Name
 			categoryGroup = c.Group
-			if c.IsIncome {
-				txType = "income"
-			} else if c.Name == "Payment, Transfer" {
-				txType = "transfer"
-			} else {
-				txType = "expense"
-			}
 		}
 	}
 
@@ -361,14 +676,6 @@ func toTransactionView(
 		notes = *tx.Notes
 	}
 
-	tagNames := make([]string, 0, len(tx.TagIDs))
-	for _, tagID := range tx.TagIDs {
-		if name, ok := tags[tagID]; ok {
-			tagNames = append(tagNames, name)
-		}
-	}
-	sort.Strings(tagNames)
-
 	account := "Cash Transaction"
 	institution := ""
 	if tx.ManualAccountID != nil {
@@ -388,19 +695,41 @@ func toTransactionView(
 	}
 
 	return transactionView{
-		ID:          tx.ID,
-		Date:        tx.Date,
-		Description: tx.Payee,
-		Category:    categoryName,
-		Amount:      normalizedAmount,
-		Account:     account,
-		Institution: institution,
-		Group:       categoryGroup,
-		Type:        txType,
-		Notes:       notes,
-		Tags:        strings.Join(tagNames, ", "),
-		Status:      tx.Status,
-		IsPending:   tx.IsPending,
+		ID:               tx.ID,
+		Date:             tx.Date,
+		Description:      tx.Payee,
+		Category:         categoryName,
+		Amount:           normalizedAmount,
+		Currency:         baseCurrency,
+		OriginalAmount:   originalAmount,
+		OriginalCurrency: originalCurrency,
+		BaseAmount:       normalizedAmount,
+		BaseCurrency:     baseCurrency,
+		Account:          account,
+		Institution:      institution,
+		Group:            categoryGroup,
+		Type:             txType,
+		Notes:            notes,
+		Tags:             tagNames(tx.TagIDs, tags),
+		Status:           tx.Status,
+		IsPending:        tx.IsPending,
+	}
+}
+
+const (
+	currencyBase     = "base"
+	currencyOriginal = "original"
+)
+
+// applyCurrencyMode sets each view's Amount and Currency from either the base
+// or the original amount. Views default to base.
+func applyCurrencyMode(views []transactionView, mode string) {
+	if mode != currencyOriginal {
+		return
+	}
+	for i := range views {
+		views[i].Amount = views[i].OriginalAmount
+		views[i].Currency = views[i].OriginalCurrency
 	}
 }
 
@@ -414,6 +743,30 @@ func sortTransactionsNewestFirst(transactions []transactionView) {
 	})
 }
 
+// updatedFieldNames lists the user-facing names of the fields an update
+// touches, in a stable order for status messages.
+func updatedFieldNames(u lunchmoney.TransactionUpdate) []string {
+	var fields []string
+	add := func(cond bool, name string) {
+		if cond {
+			fields = append(fields, name)
+		}
+	}
+	add(u.Date != nil, "date")
+	add(u.Amount != nil, "amount")
+	add(u.Currency != nil, "currency")
+	add(u.Payee != nil, "payee")
+	add(u.CategoryID != nil || u.ClearCategory, "category")
+	add(u.Notes != nil || u.ClearNotes, "note")
+	add(u.Status != nil, "status")
+	add(u.RecurringID != nil || u.ClearRecurring, "recurring")
+	add(u.TagIDs != nil || u.ClearTags || u.AdditionalTagIDs != nil, "tags")
+	add(u.ExternalID != nil || u.ClearExternalID, "external id")
+	add(u.CustomMetadata != nil || u.ClearCustomMetadata, "custom metadata")
+	add(u.ManualAccountID != nil || u.PlaidAccountID != nil || u.ClearAccount, "account")
+	return fields
+}
+
 func stringOrDefault(v *string, fallback string) string {
 	if v == nil {
 		return fallback
@@ -0,0 +1,652 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// txEdit is one requested change read from an apply file. Nil fields are left
+// untouched; for clearable fields an empty value clears the field.
+type txEdit struct {
+	Row        int
+	ID         int64
+	Date       *string
+	Payee      *string
+	Category   *string
+	Notes      *string
+	Tags       *[]string
+	Status     *string
+	ExternalID *string
+}
+
+type fieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type plannedEdit struct {
+	Row     int
+	ID      int64
+	Update  lunchmoney.TransactionUpdate
+	Changes []fieldChange
+	Before  lunchmoney.Transaction
+}
+
+type applyResult struct {
+	Row    int    `json:"row"`
+	ID     int64  `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// txEditIgnoredFields are transactionView fields that cannot be changed via
+// apply. They are accepted and ignored so `lm tx list --json` output can be
+// edited and fed back in.
+var txEditIgnoredFields = map[string]bool{
+	"amount":                true,
+	"currency":              true,
+	"original_amount":       true,
+	"original_currency":     true,
+	"base_amount":           true,
+	"base_currency":         true,
+	"account":               true,
+	"institution":           true,
+	"group":                 true,
+	"type":                  true,
+	"is_pending":            true,
+	"suggestion":            true,
+	"suggestion_confidence": true,
+}
+
+func newTxApplyCmd() *cobra.Command {
+	var (
+		format     string
+		yes        bool
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply [file]",
+		Short: "Apply a batch of transaction edits from CSV, JSON or NDJSON",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				in   io.Reader = os.Stdin
+				name           = "-"
+			)
+			if len(args) == 1 && args[0] != "-" {
+				name = args[0]
+				f, err := os.Open(name)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			if format == "" {
+				format = formatFromExtension(name)
+			}
+
+			edits, err := readTxEdits(in, format)
+			if err != nil {
+				return err
+			}
+			if len(edits) == 0 {
+				return errors.New("no edits found in input")
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			plans, errs := planTxEdits(cmd.Context(), client, edits)
+			if len(errs) > 0 {
+				for _, err := range errs {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				return fmt.Errorf("%d row(s) failed validation; nothing was changed", len(errs))
+			}
+
+			printEditPlan(plans)
+
+			pending := make([]plannedEdit, 0, len(plans))
+			for _, p := range plans {
+				if len(p.Changes) > 0 {
+					pending = append(pending, p)
+				}
+			}
+			if len(pending) == 0 {
+				fmt.Println("Nothing to change.")
+				return nil
+			}
+			if !yes && !client.DryRun() {
+				ok, err := confirm(cmd.Context(), fmt.Sprintf("Update %d transaction(s)?", len(pending)))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errors.New("aborted")
+				}
+			}
+
+			results := submitEditPlan(cmd.Context(), client, plans)
+			if jsonOutput {
+				if err := printJSON(results); err != nil {
+					return err
+				}
+			} else {
+				printApplyResults(results)
+			}
+
+			if err := stopped(cmd.Context(), updatedIDs(results), len(pending)); err != nil {
+				return err
+			}
+			failed := 0
+			for _, r := range results {
+				if r.Result == "failed" {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d update(s) failed", failed, len(pending))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "Input format: csv, json or ndjson (default: from file extension or content)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply without asking for confirmation")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the per-row report as JSON")
+
+	return cmd
+}
+
+func formatFromExtension(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	return ""
+}
+
+// readTxEdits parses edits in the given format. An empty format is detected
+// from the first non-space byte of the input.
+func readTxEdits(r io.Reader, format string) ([]txEdit, error) {
+	br := bufio.NewReader(r)
+	if format == "" {
+		format = detectEditFormat(br)
+	}
+
+	switch format {
+	case "csv":
+		return readTxEditsCSV(br)
+	case "json":
+		return readTxEditsJSON(br)
+	case "ndjson":
+		return readTxEditsNDJSON(br)
+	default:
+		return nil, fmt.Errorf("unsupported format %q (expected csv, json or ndjson)", format)
+	}
+}
+
+func detectEditFormat(br *bufio.Reader) string {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return "csv"
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.ReadByte()
+		case '[':
+			return "json"
+		case '{':
+			return "ndjson"
+		default:
+			return "csv"
+		}
+	}
+}
+
+// readTxEditsCSV reads a CSV file with a header row. Empty cells leave the
+// field unchanged and the literal value "null" clears it.
+func readTxEditsCSV(r io.Reader) ([]txEdit, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if !slices.Contains(header, "id") {
+		return nil, errors.New("csv header must include an id column")
+	}
+
+	var edits []txEdit
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		edit := txEdit{Row: row}
+		for i, key := range header {
+			cell := strings.TrimSpace(record[i])
+			if cell == "" {
+				continue
+			}
+			if err := edit.set(key, cell, cell == "null"); err != nil {
+				return nil, fmt.Errorf("row %d: %w", row, err)
+			}
+		}
+		if edit.ID == 0 {
+			return nil, fmt.Errorf("row %d: id is required", row)
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+func readTxEditsJSON(r io.Reader) ([]txEdit, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var objects []map[string]any
+	if err := dec.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("failed to parse json: %w", err)
+	}
+
+	edits := make([]txEdit, 0, len(objects))
+	for i, obj := range objects {
+		edit, err := txEditFromObject(i+1, obj)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+func readTxEditsNDJSON(r io.Reader) ([]txEdit, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var edits []txEdit
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("row %d: invalid json: %w", line, err)
+		}
+		edit, err := txEditFromObject(line, obj)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// txEditFromObject converts a decoded JSON object. Missing keys leave the
+// field unchanged; null or an empty string clears it.
+func txEditFromObject(row int, obj map[string]any) (txEdit, error) {
+	edit := txEdit{Row: row}
+	for key, raw := range obj {
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		switch v := raw.(type) {
+		case nil:
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		case []any:
+			parts := make([]string, 0, len(v))
+			for _, item := range v {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			value = strings.Join(parts, ",")
+		default:
+			return txEdit{}, fmt.Errorf("row %d: unsupported value for %q", row, key)
+		}
+		if err := edit.set(key, value, raw == nil); err != nil {
+			return txEdit{}, fmt.Errorf("row %d: %w", row, err)
+		}
+	}
+	if edit.ID == 0 {
+		return txEdit{}, fmt.Errorf("row %d: id is required", row)
+	}
+	return edit, nil
+}
+
+func (e *txEdit) set(key, value string, null bool) error {
+	value = strings.TrimSpace(value)
+	if null {
+		value = ""
+	}
+	required := func() error {
+		if value == "" {
+			return fmt.Errorf("%s cannot be empty", key)
+		}
+		return nil
+	}
+
+	switch key {
+	case "id":
+		id, err := parseTxID(value)
+		if err != nil {
+			return err
+		}
+		e.ID = id
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", value)
+		}
+		e.Date = &value
+	case "payee", "description":
+		if err := required(); err != nil {
+			return err
+		}
+		e.Payee = &value
+	case "category":
+		e.Category = &value
+	case "notes", "note":
+		e.Notes = &value
+	case "tags":
+		tags := make([]string, 0)
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+		e.Tags = &tags
+	case "status":
+		// delete_pending is accepted so unchanged rows round-trip; planTxEdit
+		// rejects it as a new value.
+		if value != "reviewed" && value != "unreviewed" && value != "delete_pending" {
+			return fmt.Errorf("invalid status %q (expected reviewed or unreviewed)", value)
+		}
+		e.Status = &value
+	case "external_id":
+		e.ExternalID = &value
+	default:
+		if !txEditIgnoredFields[key] {
+			return fmt.Errorf("unknown field %q", key)
+		}
+	}
+	return nil
+}
+
+// planTxEdits validates every edit against the current transaction state and
+// computes the minimal update for each. All problems are collected so they
+// can be reported together before anything is written.
+func planTxEdits(ctx context.Context, client *lunchmoney.Client, edits []txEdit) ([]plannedEdit, []error) {
+	categories, err := client.ListCategories(ctx)
+	if err != nil {
+		return nil, []error{err}
+	}
+	tags, err := client.ListTags(ctx)
+	if err != nil {
+		return nil, []error{err}
+	}
+	lookups := txLookups{
+		categories:   categories,
+		tags:         tags,
+		categoryByID: buildCategoryLookup(categories),
+		tagByID:      buildTagLookup(tags),
+	}
+
+	var (
+		plans = make([]plannedEdit, 0, len(edits))
+		errs  []error
+		seen  = make(map[int64]int, len(edits))
+	)
+	for _, edit := range edits {
+		if prev, ok := seen[edit.ID]; ok {
+			errs = append(errs, fmt.Errorf("row %d: transaction %d already edited on row %d", edit.Row, edit.ID, prev))
+			continue
+		}
+		seen[edit.ID] = edit.Row
+
+		current, err := client.GetTransaction(ctx, edit.ID)
+		if err != nil {
+			if lunchmoney.IsNotFound(err) {
+				err = fmt.Errorf("transaction %d does not exist", edit.ID)
+			}
+			errs = append(errs, fmt.Errorf("row %d: %w", edit.Row, err))
+			continue
+		}
+
+		plan, err := planTxEdit(edit, current, lookups)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w", edit.Row, err))
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	return plans, errs
+}
+
+// planTxEdit diffs an edit against the current transaction. Only the
+// categories, tags and their ID maps in lookups are used.
+func planTxEdit(edit txEdit, current lunchmoney.Transaction, lookups txLookups) (plannedEdit, error) {
+	plan := plannedEdit{Row: edit.Row, ID: edit.ID, Before: current}
+	change := func(field, before, after string) {
+		plan.Changes = append(plan.Changes, fieldChange{Field: field, Before: before, After: after})
+	}
+
+	if edit.Date != nil && *edit.Date != current.Date {
+		plan.Update.Date = edit.Date
+		change("date", current.Date, *edit.Date)
+	}
+	if edit.Payee != nil && *edit.Payee != current.Payee {
+		plan.Update.Payee = edit.Payee
+		change("payee", current.Payee, *edit.Payee)
+	}
+
+	if edit.Category != nil {
+		before := ""
+		if current.CategoryID != nil {
+			before = lookups.categoryByID[*current.CategoryID].Name
+		}
+		if *edit.Category == "" {
+			if current.CategoryID != nil {
+				plan.Update.ClearCategory = true
+				change("category", before, "")
+			}
+		} else {
+			id, err := resolveCategoryID(lookups.categories, *edit.Category)
+			if err != nil {
+				return plannedEdit{}, err
+			}
+			if current.CategoryID == nil || *current.CategoryID != id {
+				plan.Update.CategoryID = &id
+				change("category", before, lookups.categoryByID[id].Name)
+			}
+		}
+	}
+
+	if edit.Notes != nil {
+		before := stringOrDefault(current.Notes, "")
+		if *edit.Notes != before {
+			if *edit.Notes == "" {
+				plan.Update.ClearNotes = true
+			} else {
+				plan.Update.Notes = edit.Notes
+			}
+			change("notes", before, *edit.Notes)
+		}
+	}
+
+	if edit.Tags != nil {
+		ids, err := resolveTagIDs(lookups.tags, *edit.Tags)
+		if err != nil {
+			return plannedEdit{}, err
+		}
+		if !sameIDSet(ids, current.TagIDs) {
+			if len(ids) == 0 {
+				plan.Update.ClearTags = true
+			} else {
+				plan.Update.TagIDs = ids
+			}
+			change("tags", tagNames(current.TagIDs, lookups.tagByID), tagNames(ids, lookups.tagByID))
+		}
+	}
+
+	if edit.Status != nil && *edit.Status != current.Status {
+		if *edit.Status == "delete_pending" {
+			return plannedEdit{}, errors.New("status can only be changed to reviewed or unreviewed")
+		}
+		plan.Update.Status = edit.Status
+		change("status", current.Status, *edit.Status)
+	}
+
+	if edit.ExternalID != nil {
+		before := stringOrDefault(current.ExternalID, "")
+		if *edit.ExternalID != before {
+			if *edit.ExternalID == "" {
+				plan.Update.ClearExternalID = true
+			} else {
+				plan.Update.ExternalID = edit.ExternalID
+			}
+			change("external_id", before, *edit.ExternalID)
+		}
+	}
+
+	return plan, nil
+}
+
+func submitEditPlan(ctx context.Context, client *lunchmoney.Client, plans []plannedEdit) []applyResult {
+	results := make([]applyResult, len(plans))
+	updates := make([]lunchmoney.BulkTransactionUpdate, 0, len(plans))
+	indexes := make([]int, 0, len(plans))
+	for i, p := range plans {
+		results[i] = applyResult{Row: p.Row, ID: p.ID, Result: "unchanged"}
+		if len(p.Changes) == 0 {
+			continue
+		}
+		updates = append(updates, lunchmoney.BulkTransactionUpdate{ID: p.ID, Current: &p.Before, TransactionUpdate: p.Update})
+		indexes = append(indexes, i)
+	}
+
+	updated, errs := client.UpdateTransactions(ctx, updates)
+	afterByID := make(map[int64]lunchmoney.Transaction, len(updated))
+	for _, tx := range updated {
+		afterByID[tx.ID] = tx
+	}
+
+	changes := make([]journalChange, 0, len(updates))
+	for j, err := range errs {
+		r := &results[indexes[j]]
+		if err != nil {
+			r.Result = "failed"
+			r.Error = err.Error()
+			continue
+		}
+		r.Result = "updated"
+		if client.DryRun() {
+			r.Result = "would update"
+		}
+		p := plans[indexes[j]]
+		changes = append(changes, journalChange{TxID: p.ID, Fields: p.Update.Fields(), Before: p.Before, After: afterByID[p.ID]})
+	}
+	recordMutation(ctx, changes)
+	return results
+}
+
+// updatedIDs lists the transactions that submitEditPlan updated.
+func updatedIDs(results []applyResult) []int64 {
+	var ids []int64
+	for _, r := range results {
+		if r.Result == "updated" {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
+func printEditPlan(plans []plannedEdit) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "ROW\tID\tFIELD\tCURRENT\tNEW")
+	changed := 0
+	for _, p := range plans {
+		if len(p.Changes) > 0 {
+			changed++
+		}
+		for _, c := range p.Changes {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", p.Row, p.ID, c.Field, c.Before, c.After)
+		}
+	}
+	_ = w.Flush()
+	fmt.Printf("%d transaction(s) to update, %d unchanged.\n", changed, len(plans)-changed)
+}
+
+func printApplyResults(results []applyResult) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "ROW\tID\tRESULT\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", r.Row, r.ID, r.Result, r.Error)
+	}
+	_ = w.Flush()
+}
+
+func sameIDSet(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	x := slices.Clone(a)
+	y := slices.Clone(b)
+	slices.Sort(x)
+	slices.Sort(y)
+	return slices.Equal(x, y)
+}
+
+func tagNames(ids []int64, lookup map[int64]string) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := lookup[id]; ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
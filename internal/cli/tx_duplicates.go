@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// duplicateCluster is a set of transactions that look like one real
+// transaction imported or entered more than once.
+type duplicateCluster struct {
+	Confidence   float64           `json:"confidence"`
+	Keep         int64             `json:"keep"`
+	Extras       []int64           `json:"extras"`
+	Transactions []transactionView `json:"transactions"`
+}
+
+func newTxDuplicatesCmd() *cobra.Command {
+	var (
+		startDate     string
+		endDate       string
+		days          int
+		minConfidence float64
+		deleteExtras  bool
+		yes           bool
+		jsonOutput    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "duplicates",
+		Short: "Find likely duplicate transactions and optionally delete the extras",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if endDate == "" {
+				endDate = time.Now().Format("2006-01-02")
+			}
+			if err := validateDateRange(startDate, endDate); err != nil {
+				return err
+			}
+			if days < 0 {
+				return errors.New("--days cannot be negative")
+			}
+			if minConfidence < 0 || minConfidence > 1 {
+				return errors.New("--min-confidence must be between 0 and 1")
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			transactions, err := client.ListTransactions(ctx, lunchmoney.ListTransactionsParams{
+				StartDate: startDate,
+				EndDate:   endDate,
+				Limit:     1000,
+			})
+			if err != nil {
+				return err
+			}
+			lookups, err := loadTxLookups(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			clusters := findDuplicates(transactions, lookups, days, minConfidence)
+			if jsonOutput {
+				return printJSON(clusters)
+			}
+			if len(clusters) == 0 {
+				fmt.Println("No duplicates found.")
+				return nil
+			}
+			printDuplicateClusters(clusters)
+
+			extras := make([]int64, 0, len(clusters))
+			for _, c := range clusters {
+				extras = append(extras, c.Extras...)
+			}
+			fmt.Printf("%d cluster(s), %d extra transaction(s).\n", len(clusters), len(extras))
+			if !deleteExtras {
+				return nil
+			}
+
+			if !yes && !client.DryRun() {
+				ok, err := confirm(cmd.Context(), fmt.Sprintf("Permanently delete %d transaction(s)? This cannot be undone.", len(extras)))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+
+			byID := make(map[int64]lunchmoney.Transaction, len(transactions))
+			for _, tx := range transactions {
+				byID[tx.ID] = tx
+			}
+			var (
+				changes []journalChange
+				failure error
+			)
+			for _, id := range extras {
+				if err := client.DeleteTransaction(ctx, id); err != nil {
+					failure = fmt.Errorf("deleted %d of %d; failed to delete transaction %d: %w", len(changes), len(extras), id, err)
+					break
+				}
+				changes = append(changes, journalChange{TxID: id, Action: journalDelete, Before: byID[id]})
+			}
+			recordMutation(ctx, changes)
+			if err := stopped(ctx, changedIDs(changes), len(extras)); err != nil {
+				return err
+			}
+			if failure != nil {
+				return failure
+			}
+			deleted := len(changes)
+			if client.DryRun() {
+				fmt.Printf("Would delete %d transaction(s).\n", deleted)
+			} else {
+				fmt.Printf("Deleted %d transaction(s).\n", deleted)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
+	cmd.Flags().IntVar(&days, "days", 2, "Maximum days between duplicates")
+	cmd.Flags().Float64Var(&minConfidence, "min-confidence", 0.7, "Minimum pair confidence to report")
+	cmd.Flags().BoolVar(&deleteExtras, "delete", false, "Delete every transaction except the one kept in each cluster")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation prompt for --delete")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output clusters as JSON")
+	cmd.MarkFlagsMutuallyExclusive("delete", "json")
+	_ = cmd.MarkFlagRequired("start")
+
+	return cmd
+}
+
+// findDuplicates groups transactions with the same currency and amount.
+// Taking candidates to keep in preferKeep order, each cluster is one kept
+// transaction plus every other not yet clustered that is at most days away
+// from it and scores at least minConfidence against it directly, so a
+// charge that repeats daily is never chained into one large cluster. A
+// cluster's confidence is its weakest extra.
+func findDuplicates(transactions []lunchmoney.Transaction, lookups txLookups, days int, minConfidence float64) []duplicateCluster {
+	type entry struct {
+		tx    lunchmoney.Transaction
+		date  time.Time
+		payee string
+	}
+	type amountKey struct {
+		currency string
+		amount   lunchmoney.Amount
+	}
+	byAmount := make(map[amountKey][]entry)
+	for _, tx := range transactions {
+		date, err := time.Parse("2006-01-02", tx.Date)
+		if err != nil || tx.Amount == 0 {
+			continue
+		}
+		key := amountKey{strings.ToLower(tx.Currency), tx.Amount}
+		byAmount[key] = append(byAmount[key], entry{tx: tx, date: date, payee: cleanPayee(tx.Payee)})
+	}
+
+	var clusters []duplicateCluster
+	for _, entries := range byAmount {
+		sort.Slice(entries, func(i, j int) bool { return preferKeep(entries[i].tx, entries[j].tx) })
+		clustered := make([]bool, len(entries))
+		for k, keep := range entries {
+			if clustered[k] {
+				continue
+			}
+			c := duplicateCluster{Confidence: 1, Keep: keep.tx.ID, Transactions: []transactionView{lookups.view(keep.tx)}}
+			for i := k + 1; i < len(entries); i++ {
+				x := entries[i]
+				gap := int(math.Abs(keep.date.Sub(x.date).Hours() / 24))
+				if clustered[i] || gap > days {
+					continue
+				}
+				score := scoreDuplicate(keep.tx, x.tx, keep.payee, x.payee, gap)
+				if score < minConfidence {
+					continue
+				}
+				clustered[i] = true
+				c.Confidence = min(c.Confidence, score)
+				c.Extras = append(c.Extras, x.tx.ID)
+				c.Transactions = append(c.Transactions, lookups.view(x.tx))
+			}
+			if len(c.Extras) > 0 {
+				clustered[k] = true
+				clusters = append(clusters, c)
+			}
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Confidence != clusters[j].Confidence {
+			return clusters[i].Confidence > clusters[j].Confidence
+		}
+		return clusters[i].Transactions[0].Date > clusters[j].Transactions[0].Date
+	})
+	return clusters
+}
+
+// scoreDuplicate rates two transactions with the same amount from 0 to 1.
+func scoreDuplicate(a, b lunchmoney.Transaction, payeeA, payeeB string, gapDays int) float64 {
+	score := 0.3
+	switch gapDays {
+	case 0:
+		score += 0.2
+	case 1:
+		score += 0.1
+	}
+
+	switch {
+	case payeeA != "" && payeeA == payeeB:
+		score += 0.35
+	case payeeA != "" && payeeB != "" && (strings.Contains(payeeA, payeeB) || strings.Contains(payeeB, payeeA)):
+		score += 0.25
+	case firstWord(payeeA) != "" && firstWord(payeeA) == firstWord(payeeB):
+		score += 0.15
+	}
+
+	accountA, accountB := transactionAccountKey(a), transactionAccountKey(b)
+	switch {
+	case accountA == accountB:
+		score += 0.15
+	case (a.PlaidAccountID == nil) != (b.PlaidAccountID == nil):
+		// A manual or cash entry duplicating a synced one.
+		score += 0.05
+	default:
+		// Identical charges on two different synced (or two different
+		// manual) accounts are usually both real.
+		score -= 0.2
+	}
+
+	return math.Round(max(0, min(score, 1))*100) / 100
+}
+
+// preferKeep orders a cluster so the transaction to keep comes first:
+// account-linked before cash, reviewed before unreviewed, then oldest ID.
+func preferKeep(a, b lunchmoney.Transaction) bool {
+	if ra, rb := a.PlaidAccountID != nil, b.PlaidAccountID != nil; ra != rb {
+		return ra
+	}
+	if ra, rb := transactionAccountKey(a) != "", transactionAccountKey(b) != ""; ra != rb {
+		return ra
+	}
+	if ra, rb := a.Status == "reviewed", b.Status == "reviewed"; ra != rb {
+		return ra
+	}
+	return a.ID < b.ID
+}
+
+func firstWord(s string) string {
+	word, _, _ := strings.Cut(s, " ")
+	return word
+}
+
+func printDuplicateClusters(clusters []duplicateCluster) {
+	w := newTabWriter(os.Stdout)
+	fmt.Fprintln(w, "CLUSTER\tCONFIDENCE\tACTION\tID\tDATE\tDESCRIPTION\tAMOUNT\tACCOUNT\tSTATUS")
+	for i, c := range clusters {
+		for _, v := range c.Transactions {
+			action := "delete"
+			if v.ID == c.Keep {
+				action = "keep"
+			}
+			fmt.Fprintf(
+				w,
+				"%d\t%.2f\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+				i+1,
+				c.Confidence,
+				action,
+				v.ID,
+				v.Date,
+				v.Description,
+				formatAmountWithCurrency(v.Amount, v.Currency),
+				v.Account,
+				v.Status,
+			)
+		}
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func TestFindDuplicatesDoesNotChain(t *testing.T) {
+	account := int64(10)
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	var txs []lunchmoney.Transaction
+	for day := range 30 {
+		txs = append(txs, lunchmoney.Transaction{
+			ID:             int64(day + 1),
+			Date:           start.AddDate(0, 0, day).Format(time.DateOnly),
+			Amount:         lunchmoney.AmountFromFloat(2.75),
+			Currency:       "usd",
+			ToBase:         lunchmoney.AmountFromFloat(2.75),
+			Payee:          "MTA FARE",
+			PlaidAccountID: &account,
+		})
+	}
+	dates := map[int64]time.Time{}
+	for _, tx := range txs {
+		dates[tx.ID], _ = time.Parse(time.DateOnly, tx.Date)
+	}
+
+	for _, c := range findDuplicates(txs, txLookups{}, 2, 0.7) {
+		if len(c.Extras) > 4 {
+			t.Errorf("cluster keeping %d has %d extras", c.Keep, len(c.Extras))
+		}
+		for _, id := range c.Extras {
+			if gap := dates[id].Sub(dates[c.Keep]).Abs(); gap > 48*time.Hour {
+				t.Errorf("cluster keeping %d holds %d, %v away", c.Keep, id, gap)
+			}
+		}
+	}
+}
+
+func TestFindDuplicatesSameCurrencyAmount(t *testing.T) {
+	account := int64(10)
+	// Two CAD charges in a USD budget converted at different rates.
+	txs := []lunchmoney.Transaction{
+		{ID: 1, Date: "2025-03-01", Amount: lunchmoney.AmountFromFloat(40), Currency: "cad", ToBase: lunchmoney.AmountFromFloat(28.1), Payee: "Tim Hortons", PlaidAccountID: &account},
+		{ID: 2, Date: "2025-03-02", Amount: lunchmoney.AmountFromFloat(40), Currency: "CAD", ToBase: lunchmoney.AmountFromFloat(28.3), Payee: "TIM HORTONS #44", PlaidAccountID: &account},
+		{ID: 3, Date: "2025-03-01", Amount: lunchmoney.AmountFromFloat(40), Currency: "usd", ToBase: lunchmoney.AmountFromFloat(40), Payee: "Tim Hortons", PlaidAccountID: &account},
+	}
+	clusters := findDuplicates(txs, txLookups{}, 2, 0.7)
+	if len(clusters) != 1 || clusters[0].Keep != 1 || len(clusters[0].Extras) != 1 || clusters[0].Extras[0] != 2 {
+		t.Fatalf("clusters = %+v, want 2 as a duplicate of 1", clusters)
+	}
+}
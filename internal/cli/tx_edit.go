@@ -0,0 +1,401 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+const editDocHelp = `# Edit transactions below, then save and quit.
+# Columns are tab-separated; use \t, \n and \\ for tabs, newlines and backslashes.
+# An empty category, notes or tags cell clears that field. amount and account are read-only.
+# Delete a line to leave that transaction unchanged. Delete every line to abort.
+`
+
+const editErrorPrefix = "# ERROR: "
+
+var editDocColumns = []string{"id", "date", "payee", "category", "notes", "tags", "status", "amount", "account"}
+
+func newTxEditCmd() *cobra.Command {
+	var (
+		where      []string
+		startDate  string
+		endDate    string
+		unreviewed bool
+		yes        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "edit [<tx-id>...]",
+		Short: "Edit transactions in $EDITOR",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && len(where) == 0 && !cmd.Flags().Changed("start") {
+				return errors.New("provide transaction ids or --start with optional --where filters")
+			}
+			if len(args) > 0 && (len(where) > 0 || cmd.Flags().Changed("start")) {
+				return errors.New("transaction ids cannot be combined with --where/--start")
+			}
+
+			ids := make([]int64, 0, len(args))
+			for _, raw := range args {
+				id, err := parseTxID(raw)
+				if err != nil {
+					return err
+				}
+				ids = append(ids, id)
+			}
+
+			preds, err := parseWhere(where)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				if startDate == "" {
+					return errors.New("--where requires --start")
+				}
+				if endDate == "" {
+					endDate = time.Now().Format("2006-01-02")
+				}
+				if err := validateDateRange(startDate, endDate); err != nil {
+					return err
+				}
+			}
+
+			client, err := newClient(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+
+			lookups, err := loadTxLookups(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			var originals []lunchmoney.Transaction
+			if len(ids) > 0 {
+				for _, id := range ids {
+					tx, err := client.GetTransaction(ctx, id)
+					if err != nil {
+						if lunchmoney.IsNotFound(err) {
+							return fmt.Errorf("transaction %d does not exist", id)
+						}
+						return err
+					}
+					originals = append(originals, tx)
+				}
+			} else {
+				params := lunchmoney.ListTransactionsParams{StartDate: startDate, EndDate: endDate}
+				if unreviewed {
+					params.Status = "unreviewed"
+				}
+				transactions, err := client.ListTransactions(ctx, params)
+				if err != nil {
+					return err
+				}
+				for _, tx := range transactions {
+					if matchesWhere(lookups.view(tx), preds) {
+						originals = append(originals, tx)
+					}
+				}
+			}
+			if len(originals) == 0 {
+				fmt.Println("No transactions found.")
+				return nil
+			}
+
+			plans, err := editTransactions(originals, lookups)
+			if err != nil {
+				return err
+			}
+			if plans == nil {
+				fmt.Println("Aborted; nothing was changed.")
+				return nil
+			}
+
+			printEditPlan(plans)
+			changed := 0
+			for _, p := range plans {
+				if len(p.Changes) > 0 {
+					changed++
+				}
+			}
+			if changed == 0 {
+				fmt.Println("Nothing to change.")
+				return nil
+			}
+			if !yes && !client.DryRun() {
+				ok, err := confirm(cmd.Context(), fmt.Sprintf("Update %d transaction(s)?", changed))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errors.New("aborted")
+				}
+			}
+
+			results := submitEditPlan(ctx, client, plans)
+			printApplyResults(results)
+			if err := stopped(ctx, updatedIDs(results), changed); err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.Result == "failed" {
+					return errors.New("some updates failed")
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&where, "where", nil, "Filter as <field><op><value>; ops are = != ~ !~ < <= > >= (repeatable)")
+	cmd.Flags().StringVar(&startDate, "start", "", "Start date (YYYY-MM-DD) when selecting with --where")
+	cmd.Flags().StringVar(&endDate, "end", "", "End date (YYYY-MM-DD), defaults to today")
+	cmd.Flags().BoolVar(&unreviewed, "unreviewed", false, "Only select unreviewed transactions")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply without asking for confirmation")
+
+	return cmd
+}
+
+// editTransactions runs the editor loop until the document parses and
+// validates cleanly. It returns nil plans if the user emptied the document.
+func editTransactions(originals []lunchmoney.Transaction, lookups txLookups) ([]plannedEdit, error) {
+	f, err := os.CreateTemp("", "lm-edit-*.tsv")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	doc := renderEditDoc(originals, lookups)
+	if _, err := f.WriteString(doc); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]lunchmoney.Transaction, len(originals))
+	for _, tx := range originals {
+		byID[tx.ID] = tx
+	}
+
+	for {
+		if err := runEditor(path); err != nil {
+			return nil, err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		text := stripEditErrors(string(raw))
+		plans, lineErrs, empty := parseEditDoc(text, byID, lookups)
+		if empty {
+			return nil, nil
+		}
+		if len(lineErrs) == 0 {
+			return plans, nil
+		}
+
+		if err := os.WriteFile(path, []byte(annotateEditDoc(text, lineErrs)), 0o600); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Found %d problem(s); reopening the editor.\n", countErrors(lineErrs))
+	}
+}
+
+func renderEditDoc(originals []lunchmoney.Transaction, lookups txLookups) string {
+	var b strings.Builder
+	b.WriteString(editDocHelp)
+	b.WriteString(strings.Join(editDocColumns, "\t"))
+	b.WriteByte('\n')
+	for _, tx := range originals {
+		v := lookups.view(tx)
+		cells := []string{
+			fmt.Sprint(v.ID),
+			v.Date,
+			v.Description,
+			v.Category,
+			v.Notes,
+			v.Tags,
+			v.Status,
+			formatAmountWithCurrency(v.Amount, v.Currency),
+			v.Account,
+		}
+		for i, c := range cells {
+			cells[i] = escapeEditCell(c)
+		}
+		b.WriteString(strings.Join(cells, "\t"))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// parseEditDoc parses and validates an edited document. Errors are keyed by
+// 1-based line number (0 for problems not tied to a line).
+func parseEditDoc(text string, byID map[int64]lunchmoney.Transaction, lookups txLookups) ([]plannedEdit, map[int][]string, bool) {
+	lineErrs := map[int][]string{}
+	addErr := func(line int, err error) {
+		lineErrs[line] = append(lineErrs[line], err.Error())
+	}
+
+	var (
+		header []string
+		plans  []plannedEdit
+		seen   = map[int64]int{}
+		rows   int
+	)
+	for i, line := range strings.Split(text, "\n") {
+		lineNo := i + 1
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cells := strings.Split(line, "\t")
+		if header == nil {
+			header = make([]string, len(cells))
+			for j, c := range cells {
+				header[j] = strings.ToLower(strings.TrimSpace(c))
+			}
+			if !slices.Contains(header, "id") {
+				addErr(lineNo, errors.New("header must include an id column"))
+				return nil, lineErrs, false
+			}
+			continue
+		}
+
+		rows++
+		if len(cells) != len(header) {
+			addErr(lineNo, fmt.Errorf("expected %d tab-separated columns, found %d", len(header), len(cells)))
+			continue
+		}
+
+		edit := txEdit{Row: lineNo}
+		var rowErr error
+		for j, key := range header {
+			if err := edit.set(key, unescapeEditCell(cells[j]), false); err != nil {
+				rowErr = err
+				break
+			}
+		}
+		if rowErr != nil {
+			addErr(lineNo, rowErr)
+			continue
+		}
+
+		current, ok := byID[edit.ID]
+		if !ok {
+			addErr(lineNo, fmt.Errorf("transaction %d was not part of this edit", edit.ID))
+			continue
+		}
+		if prev, dup := seen[edit.ID]; dup {
+			addErr(lineNo, fmt.Errorf("transaction %d already appears on line %d", edit.ID, prev))
+			continue
+		}
+		seen[edit.ID] = lineNo
+
+		plan, err := planTxEdit(edit, current, lookups)
+		if err != nil {
+			addErr(lineNo, err)
+			continue
+		}
+		plans = append(plans, plan)
+	}
+
+	if rows == 0 && len(lineErrs) == 0 {
+		return nil, nil, true
+	}
+	return plans, lineErrs, false
+}
+
+func annotateEditDoc(text string, lineErrs map[int][]string) string {
+	var b strings.Builder
+	for _, msg := range lineErrs[0] {
+		b.WriteString(editErrorPrefix + msg + "\n")
+	}
+	for i, line := range strings.Split(text, "\n") {
+		for _, msg := range lineErrs[i+1] {
+			b.WriteString(editErrorPrefix + msg + "\n")
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func stripEditErrors(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, editErrorPrefix) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func countErrors(lineErrs map[int][]string) int {
+	n := 0
+	for _, msgs := range lineErrs {
+		n += len(msgs)
+	}
+	return n
+}
+
+func escapeEditCell(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+func unescapeEditCell(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// runEditor opens path in $VISUAL or $EDITOR (falling back to vi). The
+// editor string is run through the shell so values like "code --wait" work.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "lm-editor", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package lunchmoney
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// redactedHeaders never appear in traces or cassettes.
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// interaction is one request/response pair as stored in a cassette file.
+// Path is relative to the client's base URL so a recording made against the
+// real API replays against any base URL.
+type interaction struct {
+	Request struct {
+		Method  string            `json:"method"`
+		URL     string            `json:"url"`
+		Path    string            `json:"path"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    json.RawMessage   `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    json.RawMessage   `json:"body,omitempty"`
+	} `json:"response"`
+	Latency string `json:"latency"`
+}
+
+// SetTrace logs every request and response to w: method, URL, status,
+// latency, headers with credentials redacted, and both bodies.
+func (c *Client) SetTrace(w io.Writer) {
+	c.trace = &tracer{w: w}
+}
+
+// SetRecord saves every request and response as a numbered cassette file in
+// dir, creating it if needed. Recording into a directory that already holds
+// cassettes appends to them.
+func (c *Client) SetRecord(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return err
+	}
+	c.recorder = &recorder{dir: dir}
+	if len(files) > 0 {
+		c.recorder.next = cassetteNumber(files[len(files)-1])
+	}
+	return nil
+}
+
+// NewReplay returns a client that answers requests from the cassettes in dir
+// instead of the network, so no API key is needed. Requests are matched on
+// method, path, query and body; repeats of the same request are answered in
+// recorded order, and the last answer is reused once they run out.
+func NewReplay(dir string) (*Client, error) {
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no cassettes found in %s", dir)
+	}
+	r := &replayer{dir: dir, queue: map[string][]interaction{}}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var it interaction
+		if err := json.Unmarshal(data, &it); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		key := replayKey(it.Request.Method, it.Request.Path, bodyBytes(it.Request.Body))
+		r.queue[key] = append(r.queue[key], it)
+	}
+
+	c, err := newClient("")
+	if err != nil {
+		return nil, err
+	}
+	c.replay = r
+	return c, nil
+}
+
+// send performs req once and reads the whole response body into memory so
+// it can be traced and recorded. In replay mode the response comes from a
+// cassette.
+func (c *Client) send(req *http.Request, reqBody []byte) (*http.Response, error) {
+	start := time.Now()
+	var (
+		resp *http.Response
+		err  error
+	)
+	if c.replay != nil {
+		resp, err = c.replay.lookup(req.Method, c.relativePath(req), reqBody)
+	} else {
+		resp, err = c.httpClient.Do(req)
+	}
+	var respBody []byte
+	if err == nil {
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	}
+	latency := time.Since(start)
+
+	if c.trace != nil {
+		c.trace.log(req, reqBody, resp, respBody, latency, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.recorder != nil {
+		if err := c.recorder.save(c.relativePath(req), req, reqBody, resp, respBody, latency); err != nil {
+			return nil, fmt.Errorf("failed to record response: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+// relativePath is the request path below the base URL plus its query.
+func (c *Client) relativePath(req *http.Request) string {
+	p := strings.TrimPrefix(req.URL.Path, strings.TrimRight(c.baseURL.Path, "/"))
+	if req.URL.RawQuery != "" {
+		p += "?" + req.URL.RawQuery
+	}
+	return p
+}
+
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (t *tracer) log(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, latency time.Duration, err error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--> %s %s\n", req.Method, req.URL)
+	writeTraceHeaders(&b, req.Header)
+	writeTraceBody(&b, reqBody)
+	if err != nil {
+		fmt.Fprintf(&b, "<-- error after %s: %v\n\n", latency.Round(time.Millisecond), err)
+	} else {
+		fmt.Fprintf(&b, "<-- %s (%s)\n", resp.Status, latency.Round(time.Millisecond))
+		writeTraceHeaders(&b, resp.Header)
+		writeTraceBody(&b, respBody)
+		b.WriteByte('\n')
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = t.w.Write(b.Bytes())
+}
+
+func writeTraceHeaders(b *bytes.Buffer, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "    %s: %s\n", name, headerValue(name, h))
+	}
+}
+
+func writeTraceBody(b *bytes.Buffer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "    ", "  "); err == nil {
+		body = pretty.Bytes()
+	}
+	fmt.Fprintf(b, "    %s\n", bytes.TrimSpace(body))
+}
+
+func headerValue(name string, h http.Header) string {
+	value := strings.Join(h.Values(name), ", ")
+	if !redactedHeaders[http.CanonicalHeaderKey(name)] {
+		return value
+	}
+	if scheme, _, ok := strings.Cut(value, " "); ok {
+		return scheme + " " + redacted
+	}
+	return redacted
+}
+
+func headerMap(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(h))
+	for name := range h {
+		m[name] = headerValue(name, h)
+	}
+	return m
+}
+
+type recorder struct {
+	mu   sync.Mutex
+	dir  string
+	next int
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func (r *recorder) save(rel string, req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, latency time.Duration) error {
+	var it interaction
+	it.Request.Method = req.Method
+	it.Request.URL = req.URL.String()
+	it.Request.Path = rel
+	it.Request.Headers = headerMap(req.Header)
+	it.Request.Body = rawBody(reqBody)
+	it.Response.Status = resp.StatusCode
+	it.Response.Headers = headerMap(resp.Header)
+	it.Response.Body = rawBody(respBody)
+	it.Latency = latency.Round(time.Millisecond).String()
+
+	data, err := json.MarshalIndent(it, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	pathOnly, _, _ := strings.Cut(rel, "?")
+	name := fmt.Sprintf("%04d-%s-%s.json", r.next, req.Method, strings.Trim(unsafeFileChars.ReplaceAllString(pathOnly, "_"), "_"))
+	return os.WriteFile(filepath.Join(r.dir, name), append(data, '\n'), 0o600)
+}
+
+type replayer struct {
+	mu    sync.Mutex
+	dir   string
+	queue map[string][]interaction
+}
+
+func (r *replayer) lookup(method, rel string, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := replayKey(method, rel, body)
+	queue := r.queue[key]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("replay: no recorded response in %s for %s %s", r.dir, method, rel)
+	}
+	it := queue[0]
+	if len(queue) > 1 {
+		r.queue[key] = queue[1:]
+	}
+
+	resp := &http.Response{
+		StatusCode: it.Response.Status,
+		Status:     fmt.Sprintf("%d %s", it.Response.Status, http.StatusText(it.Response.Status)),
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(bodyBytes(it.Response.Body))),
+	}
+	for name, value := range it.Response.Headers {
+		resp.Header.Set(name, value)
+	}
+	// The stored body may have been reformatted.
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// replayKey identifies a request. JSON bodies are compacted so formatting
+// differences do not matter.
+func replayKey(method, rel string, body []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		body = compact.Bytes()
+	}
+	return method + " " + rel + "\n" + string(body)
+}
+
+// rawBody stores JSON bodies as JSON so cassettes stay readable, and
+// anything else as a JSON string.
+func rawBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+func bodyBytes(raw json.RawMessage) []byte {
+	var s string
+	if len(raw) > 0 && raw[0] == '"' && json.Unmarshal(raw, &s) == nil {
+		return []byte(s)
+	}
+	return raw
+}
+
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "[0-9][0-9][0-9][0-9]-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(files, func(i, j int) bool { return cassetteNumber(files[i]) < cassetteNumber(files[j]) })
+	return files, nil
+}
+
+func cassetteNumber(file string) int {
+	prefix, _, _ := strings.Cut(filepath.Base(file), "-")
+	n, _ := strconv.Atoi(prefix)
+	return n
+}
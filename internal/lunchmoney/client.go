@@ -7,24 +7,62 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 const (
-	envAPIKey      = "LUNCHMONEY_API_KEY"
+	// EnvAPIKey is the environment variable holding the API key.
+	EnvAPIKey = "LUNCHMONEY_API_KEY"
+	// EnvBaseURL is the environment variable that points the client at
+	// another server.
+	EnvBaseURL     = "LUNCHMONEY_BASE_URL"
 	defaultBaseURL = "https://api.lunchmoney.dev/v2"
+
+	// defaultConcurrency is how many requests one call may have in flight.
+	defaultConcurrency = 4
+
+	defaultRequestTimeout = 30 * time.Second
 )
 
+// ErrNoAPIKey is returned by a KeyProvider that holds no key, and wrapped
+// by NewFromEnv when no key is found anywhere.
+var ErrNoAPIKey = errors.New("no API key")
+
+// KeyProvider is a place the API key may be kept besides the
+// LUNCHMONEY_API_KEY environment variable, such as a keyring, an encrypted
+// file or a password manager.
+type KeyProvider interface {
+	// Name describes the provider in messages, e.g. "api_key_command".
+	Name() string
+	// APIKey returns the key, or an error wrapping ErrNoAPIKey if the
+	// provider holds none.
+	APIKey() (string, error)
+}
+
+// ErrNotSent wraps the error for a write that was never sent because its
+// context was already done.
+var ErrNotSent = errors.New("not sent")
+
 type Client struct {
-	apiKey     string
-	baseURL    *url.URL
-	httpClient *http.Client
+	apiKey      string
+	keySource   string
+	baseURL     *url.URL
+	httpClient  *http.Client
+	dryRun      io.Writer
+	concurrency int
+	trace       *tracer
+	recorder    *recorder
+	replay      *replayer
 }
 
 type ListTransactionsParams struct {
@@ -37,18 +75,34 @@ type ListTransactionsParams struct {
 }
 
 type Transaction struct {
+	ID              int64          `json:"id"`
+	Date            string         `json:"date"`
+	Amount          Amount         `json:"amount"`
+	Currency        string         `json:"currency"`
+	ToBase          Amount         `json:"to_base"`
+	Payee           string         `json:"payee"`
+	OriginalName    *string        `json:"original_name"`
+	CategoryID      *int64         `json:"category_id"`
+	ManualAccountID *int64         `json:"manual_account_id"`
+	PlaidAccountID  *int64         `json:"plaid_account_id"`
+	RecurringID     *int64         `json:"recurring_id"`
+	Notes           *string        `json:"notes"`
+	Status          string         `json:"status"`
+	IsPending       bool           `json:"is_pending"`
+	TagIDs          []int64        `json:"tag_ids"`
+	ExternalID      *string        `json:"external_id"`
+	CustomMetadata  map[string]any `json:"custom_metadata,omitempty"`
+	UpdatedAt       string         `json:"updated_at"`
+}
+
+type User struct {
 	ID              int64   `json:"id"`
-	Date            string  `json:"date"`
-	Amount          string  `json:"amount"`
-	ToBase          float64 `json:"to_base"`
-	Payee           string  `json:"payee"`
-	CategoryID      *int64  `json:"category_id"`
-	ManualAccountID *int64  `json:"manual_account_id"`
-	PlaidAccountID  *int64  `json:"plaid_account_id"`
-	Notes           *string `json:"notes"`
-	Status          string  `json:"status"`
-	IsPending       bool    `json:"is_pending"`
-	TagIDs          []int64 `json:"tag_ids"`
+	Name            string  `json:"name"`
+	Email           string  `json:"email"`
+	AccountID       int64   `json:"account_id"`
+	BudgetName      string  `json:"budget_name"`
+	PrimaryCurrency string  `json:"primary_currency"`
+	APIKeyLabel     *string `json:"api_key_label"`
 }
 
 type Category struct {
@@ -66,11 +120,19 @@ type Tag struct {
 	Name string `json:"name"`
 }
 
+// Account balances use the account's own sign: a positive credit card or
+// loan balance is money owed.
 type ManualAccount struct {
 	ID              int64   `json:"id"`
 	Name            string  `json:"name"`
 	InstitutionName *string `json:"institution_name"`
 	DisplayName     *string `json:"display_name"`
+	Type            string  `json:"type"`
+	Subtype         *string `json:"subtype"`
+	Balance         Amount  `json:"balance"`
+	Currency        string  `json:"currency"`
+	ToBase          Amount  `json:"to_base"`
+	Status          string  `json:"status"`
 }
 
 type PlaidAccount struct {
@@ -78,28 +140,119 @@ type PlaidAccount struct {
 	Name            string  `json:"name"`
 	InstitutionName string  `json:"institution_name"`
 	DisplayName     *string `json:"display_name"`
+	Type            string  `json:"type"`
+	Subtype         *string `json:"subtype"`
+	Balance         Amount  `json:"balance"`
+	Currency        string  `json:"currency"`
+	ToBase          Amount  `json:"to_base"`
+	Status          string  `json:"status"`
 }
 
-func NewFromEnv() (*Client, error) {
-	apiKey := strings.TrimSpace(os.Getenv(envAPIKey))
+// New returns a client that authenticates with apiKey.
+func New(apiKey string) (*Client, error) {
+	apiKey = strings.TrimSpace(apiKey)
 	if apiKey == "" {
-		return nil, fmt.Errorf("%s is not set", envAPIKey)
+		return nil, errors.New("API key is empty")
 	}
+	return newClient(apiKey)
+}
 
-	baseURL, err := url.Parse(defaultBaseURL)
+// NewFromEnv returns a client that authenticates with the key found by
+// ResolveAPIKey.
+func NewFromEnv(providers ...KeyProvider) (*Client, error) {
+	apiKey, source, err := ResolveAPIKey(providers...)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newClient(apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("invalid default base url: %w", err)
+		return nil, err
+	}
+	c.keySource = source
+	return c, nil
+}
+
+// ResolveAPIKey returns LUNCHMONEY_API_KEY if it is set, or else the key of
+// the first provider that holds one, and says where the key came from. A
+// provider's error other than ErrNoAPIKey stops the search.
+func ResolveAPIKey(providers ...KeyProvider) (key, source string, err error) {
+	if key := strings.TrimSpace(os.Getenv(EnvAPIKey)); key != "" {
+		return key, "$" + EnvAPIKey, nil
+	}
+	for _, p := range providers {
+		key, err := p.APIKey()
+		if errors.Is(err, ErrNoAPIKey) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if key = strings.TrimSpace(key); key == "" {
+			return "", "", fmt.Errorf("%s returned an empty API key", p.Name())
+		}
+		return key, p.Name(), nil
+	}
+	return "", "", fmt.Errorf("%w: %s is not set", ErrNoAPIKey, EnvAPIKey)
+}
+
+// KeySource says where the client's API key came from: "$LUNCHMONEY_API_KEY"
+// or a KeyProvider's name. It is empty for clients made by New or NewReplay.
+func (c *Client) KeySource() string {
+	return c.keySource
+}
+
+func newClient(apiKey string) (*Client, error) {
+	// LUNCHMONEY_BASE_URL points the client at another server, such as
+	// `lm dev mock-server`.
+	rawURL := defaultBaseURL
+	if override := strings.TrimSpace(os.Getenv(EnvBaseURL)); override != "" {
+		rawURL = override
+	}
+	baseURL, err := url.Parse(rawURL)
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid base url %q", rawURL)
 	}
 
 	return &Client{
 		apiKey:  apiKey,
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: defaultRequestTimeout,
 		},
+		concurrency: defaultConcurrency,
 	}, nil
 }
 
+// SetTimeout sets the limit for a single request, including reading the
+// response; 0 means no limit.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.httpClient.Timeout = d
+}
+
+// SetConcurrency sets how many requests a single call such as
+// ListTransactions may have in flight; n < 1 means one at a time.
+func (c *Client) SetConcurrency(n int) {
+	c.concurrency = max(n, 1)
+}
+
+func (c *Client) GetMe(ctx context.Context) (User, error) {
+	u := c.endpoint("/me")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return User{}, err
+	}
+
+	var user User
+	if err := c.doJSON(req, http.StatusOK, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// ListTransactions fetches every transaction in the date range. Ranges
+// spanning several calendar months are split into monthly windows that are
+// fetched concurrently (see SetConcurrency), each paging through its own
+// results; the first failure cancels the rest.
 func (c *Client) ListTransactions(ctx context.Context, params ListTransactionsParams) ([]Transaction, error) {
 	if params.StartDate == "" {
 		return nil, errors.New("start date is required")
@@ -110,49 +263,159 @@ func (c *Client) ListTransactions(ctx context.Context, params ListTransactionsPa
 	if params.Limit <= 0 {
 		params.Limit = 1000
 	}
+	windows, err := monthlyWindows(params.StartDate, params.EndDate)
+	if err != nil {
+		return nil, err
+	}
 
-	all := make([]Transaction, 0, params.Limit)
-	offset := 0
+	results := make([][]Transaction, len(windows))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.concurrency)
+	for i, w := range windows {
+		g.Go(func() error {
+			p := params
+			p.StartDate, p.EndDate = w[0], w[1]
+			txs, err := c.listTransactionPages(ctx, p)
+			results[i] = txs
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	all := []Transaction{}
+	for _, txs := range results {
+		all = append(all, txs...)
+	}
+	return all, nil
+}
 
-	for {
-		q := url.Values{}
-		q.Set("start_date", params.StartDate)
-		q.Set("end_date", params.EndDate)
-		if params.Status != "" {
-			q.Set("status", params.Status)
+// IterTransactions yields transactions in the date range one page at a time,
+// fetching the next page only when the caller has consumed the current one,
+// so memory stays flat however long the range. Pages are fetched serially in
+// the API's order. An error is yielded once and ends the iteration.
+func (c *Client) IterTransactions(ctx context.Context, params ListTransactionsParams) iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		if params.StartDate == "" {
+			yield(Transaction{}, errors.New("start date is required"))
+			return
 		}
-		q.Set("limit", strconv.Itoa(params.Limit))
-		q.Set("offset", strconv.Itoa(offset))
-		if params.IsPending != nil {
-			q.Set("is_pending", strconv.FormatBool(*params.IsPending))
-		} else if params.IncludePending {
-			q.Set("include_pending", "true")
+		if params.EndDate == "" {
+			yield(Transaction{}, errors.New("end date is required"))
+			return
 		}
+		if params.Limit <= 0 {
+			params.Limit = 1000
+		}
+		offset := 0
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(Transaction{}, err)
+				return
+			}
+			resp, err := c.listTransactionsPage(ctx, params, offset)
+			if err != nil {
+				yield(Transaction{}, err)
+				return
+			}
+			for _, tx := range resp.Transactions {
+				if !yield(tx, nil) {
+					return
+				}
+			}
+			if !resp.HasMore {
+				return
+			}
+			if len(resp.Transactions) == 0 {
+				yield(Transaction{}, errors.New("pagination indicated more results but received empty page"))
+				return
+			}
+			offset += len(resp.Transactions)
+		}
+	}
+}
 
-		u := c.endpoint("/transactions")
-		u.RawQuery = q.Encode()
-
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+// listTransactionPages fetches one date range page by page.
+func (c *Client) listTransactionPages(ctx context.Context, params ListTransactionsParams) ([]Transaction, error) {
+	var all []Transaction
+	for tx, err := range c.IterTransactions(ctx, params) {
 		if err != nil {
 			return nil, err
 		}
+		all = append(all, tx)
+	}
+	return all, nil
+}
 
-		var resp listTransactionsResponse
-		if err := c.doJSON(req, http.StatusOK, &resp); err != nil {
-			return nil, err
-		}
+func (c *Client) listTransactionsPage(ctx context.Context, params ListTransactionsParams, offset int) (listTransactionsResponse, error) {
+	q := url.Values{}
+	q.Set("start_date", params.StartDate)
+	q.Set("end_date", params.EndDate)
+	if params.Status != "" {
+		q.Set("status", params.Status)
+	}
+	q.Set("limit", strconv.Itoa(params.Limit))
+	q.Set("offset", strconv.Itoa(offset))
+	if params.IsPending != nil {
+		q.Set("is_pending", strconv.FormatBool(*params.IsPending))
+	} else if params.IncludePending {
+		q.Set("include_pending", "true")
+	}
 
-		all = append(all, resp.Transactions...)
-		if !resp.HasMore {
-			break
-		}
-		offset += len(resp.Transactions)
-		if len(resp.Transactions) == 0 {
-			return nil, errors.New("pagination indicated more results but received empty page")
+	u := c.endpoint("/transactions")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return listTransactionsResponse{}, err
+	}
+
+	var resp listTransactionsResponse
+	if err := c.doJSON(req, http.StatusOK, &resp); err != nil {
+		return listTransactionsResponse{}, err
+	}
+	return resp, nil
+}
+
+// monthlyWindows splits an inclusive YYYY-MM-DD range at calendar month
+// boundaries.
+func monthlyWindows(start, end string) ([][2]string, error) {
+	from, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q (expected YYYY-MM-DD)", start)
+	}
+	to, err := time.Parse(time.DateOnly, end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q (expected YYYY-MM-DD)", end)
+	}
+	if to.Before(from) {
+		return [][2]string{{start, end}}, nil
+	}
+
+	var windows [][2]string
+	for from.Compare(to) <= 0 {
+		monthEnd := time.Date(from.Year(), from.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+		if monthEnd.After(to) {
+			monthEnd = to
 		}
+		windows = append(windows, [2]string{from.Format(time.DateOnly), monthEnd.Format(time.DateOnly)})
+		from = monthEnd.AddDate(0, 0, 1)
 	}
+	return windows, nil
+}
 
-	return all, nil
+func (c *Client) GetTransaction(ctx context.Context, txID int64) (Transaction, error) {
+	u := c.endpoint(path.Join("/transactions", strconv.FormatInt(txID, 10)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	var tx Transaction
+	if err := c.doJSON(req, http.StatusOK, &tx); err != nil {
+		return Transaction{}, err
+	}
+	return tx, nil
 }
 
 func (c *Client) ListCategories(ctx context.Context) ([]Category, error) {
@@ -224,22 +487,365 @@ func (c *Client) ListPlaidAccounts(ctx context.Context) ([]PlaidAccount, error)
 	return resp.PlaidAccounts, nil
 }
 
-func (c *Client) UpdateTransaction(ctx context.Context, txID int64, categoryID *int64, note *string) (Transaction, error) {
-	if categoryID == nil && note == nil {
-		return Transaction{}, errors.New("must provide category_id and/or note")
+// RecurringItem is an entry from /recurring_items. Only reviewed items are
+// applied to transactions; suggested ones are proposals.
+type RecurringItem struct {
+	ID                  int64             `json:"id"`
+	Description         *string           `json:"description"`
+	Status              string            `json:"status"`
+	TransactionCriteria RecurringCriteria `json:"transaction_criteria"`
+	Overrides           RecurringOverride `json:"overrides"`
+	Matches             *RecurringMatches `json:"matches"`
+	Source              string            `json:"source"`
+}
+
+type RecurringCriteria struct {
+	StartDate       *string `json:"start_date"`
+	EndDate         *string `json:"end_date"`
+	Granularity     string  `json:"granularity"`
+	Quantity        int     `json:"quantity"`
+	AnchorDate      string  `json:"anchor_date"`
+	Payee           *string `json:"payee"`
+	Amount          Amount  `json:"amount"`
+	ToBase          Amount  `json:"to_base"`
+	Currency        string  `json:"currency"`
+	PlaidAccountID  *int64  `json:"plaid_account_id"`
+	ManualAccountID *int64  `json:"manual_account_id"`
+}
+
+type RecurringOverride struct {
+	Payee      *string `json:"payee"`
+	Notes      *string `json:"notes"`
+	CategoryID *int64  `json:"category_id"`
+}
+
+type RecurringMatches struct {
+	RequestStartDate        string   `json:"request_start_date"`
+	RequestEndDate          string   `json:"request_end_date"`
+	ExpectedOccurrenceDates []string `json:"expected_occurrence_dates"`
+	FoundTransactions       []struct {
+		Date          string `json:"date"`
+		TransactionID int64  `json:"transaction_id"`
+	} `json:"found_transactions"`
+	MissingTransactionDates []string `json:"missing_transaction_dates"`
+}
+
+// ListRecurringItems returns recurring items, including suggested ones. The
+// date range only controls the matches object; both dates may be empty for
+// the current month.
+func (c *Client) ListRecurringItems(ctx context.Context, startDate, endDate string) ([]RecurringItem, error) {
+	u := c.endpoint("/recurring_items")
+	q := url.Values{}
+	if startDate != "" && endDate != "" {
+		q.Set("start_date", startDate)
+		q.Set("end_date", endDate)
+	}
+	q.Set("include_suggested", "true")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		RecurringItems []RecurringItem `json:"recurring_items"`
+	}
+	if err := c.doJSON(req, http.StatusOK, &resp); err != nil {
+		return nil, err
+	}
+	return resp.RecurringItems, nil
+}
+
+// TransactionUpdate describes the fields to change on a transaction. Nil
+// fields are left untouched; the Clear* flags send an explicit null (or empty
+// value) so the field is removed on the server.
+type TransactionUpdate struct {
+	Date                *string
+	Amount              *Amount
+	Currency            *string
+	Payee               *string
+	CategoryID          *int64
+	ClearCategory       bool
+	Notes               *string
+	ClearNotes          bool
+	Status              *string
+	RecurringID         *int64
+	ClearRecurring      bool
+	TagIDs              []int64
+	ClearTags           bool
+	AdditionalTagIDs    []int64
+	ExternalID          *string
+	ClearExternalID     bool
+	CustomMetadata      map[string]any
+	ClearCustomMetadata bool
+	ManualAccountID     *int64
+	PlaidAccountID      *int64
+	ClearAccount        bool
+}
+
+// IsEmpty reports whether the update would not change any field.
+func (u TransactionUpdate) IsEmpty() bool {
+	return len(u.payload()) == 0
+}
+
+// Fields returns the API field names the update would send, sorted.
+func (u TransactionUpdate) Fields() []string {
+	payload := u.payload()
+	fields := make([]string, 0, len(payload))
+	for k := range payload {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// RestoreUpdate builds an update that sets the given API fields back to their
+// values in tx. It is the inverse of an update whose Fields() were fields and
+// whose previous state was tx.
+func RestoreUpdate(tx Transaction, fields []string) TransactionUpdate {
+	var u TransactionUpdate
+	for _, field := range fields {
+		switch field {
+		case "date":
+			u.Date = &tx.Date
+		case "amount":
+			u.Amount = &tx.Amount
+		case "currency":
+			u.Currency = &tx.Currency
+		case "payee":
+			u.Payee = &tx.Payee
+		case "category_id":
+			u.CategoryID = tx.CategoryID
+			u.ClearCategory = tx.CategoryID == nil
+		case "notes":
+			u.Notes = tx.Notes
+			u.ClearNotes = tx.Notes == nil || *tx.Notes == ""
+			if u.ClearNotes {
+				u.Notes = nil
+			}
+		case "status":
+			u.Status = &tx.Status
+		case "recurring_id":
+			u.RecurringID = tx.RecurringID
+			u.ClearRecurring = tx.RecurringID == nil
+		case "tag_ids", "additional_tag_ids":
+			u.TagIDs = append([]int64{}, tx.TagIDs...)
+			u.ClearTags = len(tx.TagIDs) == 0
+			if u.ClearTags {
+				u.TagIDs = nil
+			}
+		case "external_id":
+			u.ExternalID = tx.ExternalID
+			u.ClearExternalID = tx.ExternalID == nil
+		case "custom_metadata":
+			u.CustomMetadata = tx.CustomMetadata
+			u.ClearCustomMetadata = tx.CustomMetadata == nil
+		case "manual_account_id", "plaid_account_id":
+			u.ManualAccountID = tx.ManualAccountID
+			u.PlaidAccountID = tx.PlaidAccountID
+			u.ClearAccount = tx.ManualAccountID == nil && tx.PlaidAccountID == nil
+		}
+	}
+	return u
+}
+
+func (u TransactionUpdate) validate() error {
+	if u.CategoryID != nil && u.ClearCategory {
+		return errors.New("cannot both set and clear category")
 	}
+	if u.Notes != nil && u.ClearNotes {
+		return errors.New("cannot both set and clear notes")
+	}
+	if u.RecurringID != nil && u.ClearRecurring {
+		return errors.New("cannot both set and clear recurring_id")
+	}
+	if (u.TagIDs != nil || u.ClearTags) && u.AdditionalTagIDs != nil {
+		return errors.New("tag_ids and additional_tag_ids cannot both be set")
+	}
+	if u.ExternalID != nil && u.ClearExternalID {
+		return errors.New("cannot both set and clear external_id")
+	}
+	if u.CustomMetadata != nil && u.ClearCustomMetadata {
+		return errors.New("cannot both set and clear custom_metadata")
+	}
+	if u.ManualAccountID != nil && u.PlaidAccountID != nil {
+		return errors.New("manual_account_id and plaid_account_id cannot both be set")
+	}
+	if (u.ManualAccountID != nil || u.PlaidAccountID != nil) && u.ClearAccount {
+		return errors.New("cannot both set and clear the account")
+	}
+	if u.Status != nil && *u.Status != "reviewed" && *u.Status != "unreviewed" {
+		return fmt.Errorf("invalid status %q (expected reviewed or unreviewed)", *u.Status)
+	}
+	return nil
+}
 
+func (u TransactionUpdate) payload() map[string]any {
 	payload := map[string]any{}
-	if categoryID != nil {
-		payload["category_id"] = *categoryID
+	if u.Date != nil {
+		payload["date"] = *u.Date
+	}
+	if u.Amount != nil {
+		payload["amount"] = u.Amount.String()
+	}
+	if u.Currency != nil {
+		payload["currency"] = strings.ToLower(*u.Currency)
+	}
+	if u.Payee != nil {
+		payload["payee"] = *u.Payee
+	}
+	if u.CategoryID != nil {
+		payload["category_id"] = *u.CategoryID
+	} else if u.ClearCategory {
+		payload["category_id"] = nil
+	}
+	if u.Notes != nil {
+		payload["notes"] = *u.Notes
+	} else if u.ClearNotes {
+		// The API clears notes when given an empty string.
+		payload["notes"] = ""
 	}
-	if note != nil {
-		payload["notes"] = *note
+	if u.Status != nil {
+		payload["status"] = *u.Status
+	}
+	if u.RecurringID != nil {
+		payload["recurring_id"] = *u.RecurringID
+	} else if u.ClearRecurring {
+		payload["recurring_id"] = nil
+	}
+	if u.ClearTags {
+		payload["tag_ids"] = []int64{}
+	} else if u.TagIDs != nil {
+		payload["tag_ids"] = u.TagIDs
+	}
+	if u.AdditionalTagIDs != nil {
+		payload["additional_tag_ids"] = u.AdditionalTagIDs
+	}
+	if u.ExternalID != nil {
+		payload["external_id"] = *u.ExternalID
+	} else if u.ClearExternalID {
+		payload["external_id"] = nil
+	}
+	if u.CustomMetadata != nil {
+		payload["custom_metadata"] = u.CustomMetadata
+	} else if u.ClearCustomMetadata {
+		payload["custom_metadata"] = nil
+	}
+	if u.ManualAccountID != nil {
+		payload["manual_account_id"] = *u.ManualAccountID
+		payload["plaid_account_id"] = nil
+	} else if u.PlaidAccountID != nil {
+		payload["plaid_account_id"] = *u.PlaidAccountID
+		payload["manual_account_id"] = nil
+	} else if u.ClearAccount {
+		payload["manual_account_id"] = nil
+		payload["plaid_account_id"] = nil
+	}
+	return payload
+}
+
+func (c *Client) UpdateTransaction(ctx context.Context, txID int64, update TransactionUpdate) (Transaction, error) {
+	if err := update.validate(); err != nil {
+		return Transaction{}, err
+	}
+	payload := update.payload()
+	if len(payload) == 0 {
+		return Transaction{}, errors.New("no fields to update")
 	}
 
 	return c.updateTransaction(ctx, txID, payload)
 }
 
+// BulkTransactionUpdate pairs a transaction ID with the fields to change.
+// Current, when set, is the transaction as the caller last fetched it; a
+// dry run diffs against it instead of fetching the transaction again.
+type BulkTransactionUpdate struct {
+	ID      int64
+	Current *Transaction
+	TransactionUpdate
+}
+
+// maxBulkUpdate is the largest batch accepted by PUT /transactions.
+const maxBulkUpdate = 500
+
+// UpdateTransactions submits updates through the bulk update endpoint in
+// batches of up to 500. The returned slice has one error per input update
+// (nil on success); a rejected batch marks every update in it as failed.
+func (c *Client) UpdateTransactions(ctx context.Context, updates []BulkTransactionUpdate) ([]Transaction, []error) {
+	errs := make([]error, len(updates))
+	updated := make([]Transaction, 0, len(updates))
+
+	if c.dryRun != nil {
+		known := make(map[int64]Transaction)
+		for _, u := range updates {
+			if u.Current != nil {
+				known[u.ID] = *u.Current
+			}
+		}
+		ctx = context.WithValue(ctx, dryRunCurrentKey{}, known)
+	}
+
+	for start := 0; start < len(updates); start += maxBulkUpdate {
+		end := min(start+maxBulkUpdate, len(updates))
+		batch := updates[start:end]
+
+		items := make([]map[string]any, 0, len(batch))
+		for i, u := range batch {
+			if err := u.validate(); err != nil {
+				errs[start+i] = err
+				continue
+			}
+			payload := u.payload()
+			if len(payload) == 0 {
+				errs[start+i] = errors.New("no fields to update")
+				continue
+			}
+			payload["id"] = u.ID
+			items = append(items, payload)
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		txs, err := c.putTransactions(ctx, items)
+		if err != nil {
+			for i := range batch {
+				if errs[start+i] == nil {
+					errs[start+i] = err
+				}
+			}
+			continue
+		}
+		updated = append(updated, txs...)
+	}
+
+	return updated, errs
+}
+
+func (c *Client) putTransactions(ctx context.Context, items []map[string]any) ([]Transaction, error) {
+	body, err := json.Marshal(map[string]any{"transactions": items})
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.endpoint("/transactions")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	if err := c.doJSON(req, http.StatusOK, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Transactions, nil
+}
+
+// MarkReviewed marks each transaction as reviewed in order. On failure it
+// returns the transactions updated so far along with the error.
 func (c *Client) MarkReviewed(ctx context.Context, txIDs []int64) ([]Transaction, error) {
 	if len(txIDs) == 0 {
 		return nil, errors.New("at least one transaction id is required")
@@ -249,13 +855,73 @@ func (c *Client) MarkReviewed(ctx context.Context, txIDs []int64) ([]Transaction
 	for _, txID := range txIDs {
 		tx, err := c.updateTransaction(ctx, txID, map[string]any{"status": "reviewed"})
 		if err != nil {
-			return nil, fmt.Errorf("failed to mark transaction %d as reviewed: %w", txID, err)
+			return updated, fmt.Errorf("failed to mark transaction %d as reviewed: %w", txID, err)
 		}
 		updated = append(updated, tx)
 	}
 	return updated, nil
 }
 
+// DeleteTransaction permanently deletes a transaction. Split and grouped
+// transactions are rejected by the API.
+func (c *Client) DeleteTransaction(ctx context.Context, txID int64) error {
+	u := c.endpoint(path.Join("/transactions", strconv.FormatInt(txID, 10)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(req, http.StatusNoContent, nil)
+}
+
+// UngroupTransactions deletes the group with the given parent ID, which
+// restores the transactions it held.
+func (c *Client) UngroupTransactions(ctx context.Context, groupID int64) error {
+	u := c.endpoint(path.Join("/transactions/group", strconv.FormatInt(groupID, 10)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	return c.doJSONWithStatuses(req, []int{http.StatusOK, http.StatusNoContent}, nil)
+}
+
+// GroupTransactionsRequest is the body of POST /transactions/group.
+type GroupTransactionsRequest struct {
+	IDs        []int64 `json:"ids"`
+	Date       string  `json:"date"`
+	Payee      string  `json:"payee"`
+	CategoryID *int64  `json:"category_id,omitempty"`
+	Notes      *string `json:"notes,omitempty"`
+	Status     string  `json:"status,omitempty"`
+}
+
+// GroupTransactions groups existing transactions into a new parent
+// transaction and returns it.
+func (c *Client) GroupTransactions(ctx context.Context, group GroupTransactionsRequest) (Transaction, error) {
+	if len(group.IDs) < 2 {
+		return Transaction{}, errors.New("at least two transaction ids are required to group")
+	}
+	if group.Date == "" || group.Payee == "" {
+		return Transaction{}, errors.New("group date and payee are required")
+	}
+
+	body, err := json.Marshal(group)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	u := c.endpoint("/transactions/group")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	var tx Transaction
+	if err := c.doJSONWithStatuses(req, []int{http.StatusOK, http.StatusCreated}, &tx); err != nil {
+		return Transaction{}, err
+	}
+	return tx, nil
+}
+
 func (c *Client) updateTransaction(ctx context.Context, txID int64, payload map[string]any) (Transaction, error) {
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -286,15 +952,29 @@ func (c *Client) doJSON(req *http.Request, expectedStatus int, out any) error {
 }
 
 func (c *Client) doJSONWithStatuses(req *http.Request, expectedStatuses []int, out any) error {
+	ctx := req.Context()
+	if req.Method != http.MethodGet {
+		// A write is either not sent at all or sent to completion: once it
+		// has started, cancelling the caller's context (Ctrl-C, --timeout)
+		// no longer cuts it off, so the caller knows whether it happened.
+		// The client's request timeout still applies.
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %w", ErrNotSent, err)
+		}
+		req = req.WithContext(context.WithoutCancel(ctx))
+	}
+	if c.dryRun != nil && req.Method != http.MethodGet {
+		return c.recordWrite(req, out)
+	}
+
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.sendWithRetry(ctx, req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
 	if !containsStatus(expectedStatuses, resp.StatusCode) {
 		return decodeAPIError(resp)
@@ -321,6 +1001,25 @@ func containsStatus(allowed []int, got int) bool {
 	return false
 }
 
+// APIError is returned when the API responds with an unexpected status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("api request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("api request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an API 404 response.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
 func decodeAPIError(resp *http.Response) error {
 	body, _ := io.ReadAll(resp.Body)
 
@@ -331,11 +1030,7 @@ func decodeAPIError(resp *http.Response) error {
 		} `json:"errors"`
 	}
 	if err := json.Unmarshal(body, &e); err != nil {
-		trimmed := strings.TrimSpace(string(body))
-		if trimmed == "" {
-			return fmt.Errorf("api request failed with status %d", resp.StatusCode)
-		}
-		return fmt.Errorf("api request failed with status %d: %s", resp.StatusCode, trimmed)
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
 	}
 
 	parts := make([]string, 0, len(e.Errors)+1)
@@ -347,10 +1042,7 @@ func decodeAPIError(resp *http.Response) error {
 			parts = append(parts, detail.ErrMsg)
 		}
 	}
-	if len(parts) == 0 {
-		return fmt.Errorf("api request failed with status %d", resp.StatusCode)
-	}
-	return fmt.Errorf("api request failed with status %d: %s", resp.StatusCode, strings.Join(parts, "; "))
+	return &APIError{StatusCode: resp.StatusCode, Message: strings.Join(parts, "; ")}
 }
 
 type listTransactionsResponse struct {
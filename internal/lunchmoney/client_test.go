@@ -0,0 +1,55 @@
+package lunchmoney_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+type fakeKey struct {
+	name  string
+	key   string
+	err   error
+	asked int
+}
+
+func (k *fakeKey) Name() string { return k.name }
+
+func (k *fakeKey) APIKey() (string, error) {
+	k.asked++
+	return k.key, k.err
+}
+
+func TestNewFromEnvKeyProviders(t *testing.T) {
+	t.Setenv("LUNCHMONEY_BASE_URL", "http://127.0.0.1:1/v2")
+
+	t.Setenv("LUNCHMONEY_API_KEY", "from-env")
+	keyring := &fakeKey{name: "keyring", key: "from-keyring"}
+	client, err := lunchmoney.NewFromEnv(keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.KeySource() != "$LUNCHMONEY_API_KEY" || keyring.asked != 0 {
+		t.Errorf("source %q, keyring asked %d times; want the environment first", client.KeySource(), keyring.asked)
+	}
+
+	t.Setenv("LUNCHMONEY_API_KEY", "")
+	empty := &fakeKey{name: "empty", err: fmt.Errorf("%w stored", lunchmoney.ErrNoAPIKey)}
+	client, err = lunchmoney.NewFromEnv(empty, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.KeySource() != "keyring" {
+		t.Errorf("source %q, want keyring after a provider with no key", client.KeySource())
+	}
+
+	broken := &fakeKey{name: "broken", err: errors.New("locked")}
+	if _, err := lunchmoney.NewFromEnv(broken, keyring); err == nil || err.Error() != "locked" {
+		t.Errorf("err = %v, want the provider's error", err)
+	}
+	if _, err := lunchmoney.NewFromEnv(empty); !errors.Is(err, lunchmoney.ErrNoAPIKey) {
+		t.Errorf("err = %v, want ErrNoAPIKey", err)
+	}
+}
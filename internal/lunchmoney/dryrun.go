@@ -0,0 +1,212 @@
+package lunchmoney
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetDryRun makes the client print write requests to w instead of sending
+// them. Reads still go to the API so updates and groups can be diffed
+// against current values and callers receive a simulated response. A
+// simulated group has ID 0, as none was created.
+func (c *Client) SetDryRun(w io.Writer) {
+	c.dryRun = w
+}
+
+// DryRun reports whether writes are being recorded instead of sent.
+func (c *Client) DryRun() bool {
+	return c.dryRun != nil
+}
+
+func (c *Client) recordWrite(req *http.Request, out any) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(c.dryRun, "DRY RUN %s %s\n", req.Method, req.URL.Path)
+	if len(body) > 0 {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "  ", "  "); err == nil {
+			fmt.Fprintf(c.dryRun, "  %s\n", pretty.String())
+		} else {
+			fmt.Fprintf(c.dryRun, "  %s\n", body)
+		}
+	}
+
+	rel := strings.TrimPrefix(req.URL.Path, strings.TrimRight(c.baseURL.Path, "/"))
+	if req.Method == http.MethodPost && rel == "/transactions/group" {
+		return c.simulateGroup(req.Context(), body, out)
+	}
+	if req.Method != http.MethodPut || !strings.HasPrefix(rel, "/transactions") {
+		return nil
+	}
+
+	var items []map[string]any
+	if rel == "/transactions" {
+		var bulk struct {
+			Transactions []map[string]any `json:"transactions"`
+		}
+		if err := json.Unmarshal(body, &bulk); err != nil {
+			return err
+		}
+		items = bulk.Transactions
+	} else {
+		id, err := strconv.ParseInt(strings.TrimPrefix(rel, "/transactions/"), 10, 64)
+		if err != nil {
+			return nil
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return err
+		}
+		payload["id"] = id
+		items = []map[string]any{payload}
+	}
+
+	known, _ := req.Context().Value(dryRunCurrentKey{}).(map[int64]Transaction)
+	simulated := make([]Transaction, 0, len(items))
+	for _, item := range items {
+		tx, err := c.simulateUpdate(req.Context(), item, known)
+		if err != nil {
+			return err
+		}
+		simulated = append(simulated, tx)
+	}
+
+	if out == nil {
+		return nil
+	}
+	var resp any = simulated[0]
+	if rel == "/transactions" {
+		resp = map[string]any{"transactions": simulated}
+	}
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// dryRunCurrentKey carries the transactions UpdateTransactions callers
+// passed as Current, so a dry run does not fetch them one at a time.
+type dryRunCurrentKey struct{}
+
+// simulateUpdate prints a field diff for the payload against the current
+// transaction, taken from known or fetched, and returns the transaction as
+// it would look afterwards.
+func (c *Client) simulateUpdate(ctx context.Context, payload map[string]any, known map[int64]Transaction) (Transaction, error) {
+	idValue, _ := payload["id"].(float64)
+	id := int64(idValue)
+	if v, ok := payload["id"].(int64); ok {
+		id = v
+	}
+
+	current, ok := known[id]
+	if !ok {
+		var err error
+		current, err = c.GetTransaction(ctx, id)
+		if err != nil {
+			return Transaction{}, fmt.Errorf("dry run: failed to fetch transaction %d: %w", id, err)
+		}
+	}
+
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return Transaction{}, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return Transaction{}, err
+	}
+
+	keys := make([]string, 0, len(payload))
+	for k := range payload {
+		if k != "id" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		target := k
+		next := payload[k]
+		if k == "additional_tag_ids" {
+			target = "tag_ids"
+			existing, _ := fields["tag_ids"].([]any)
+			added, _ := next.([]any)
+			next = append(append([]any{}, existing...), added...)
+		}
+		fmt.Fprintf(c.dryRun, "  transaction %d %s: %s -> %s\n", id, target, compactJSON(fields[target]), compactJSON(next))
+		fields[target] = next
+	}
+
+	raw, err = json.Marshal(fields)
+	if err != nil {
+		return Transaction{}, err
+	}
+	var simulated Transaction
+	if err := json.Unmarshal(raw, &simulated); err != nil {
+		return Transaction{}, err
+	}
+	return simulated, nil
+}
+
+// simulateGroup fetches the transactions to group, prints them and returns
+// the parent the API would create.
+func (c *Client) simulateGroup(ctx context.Context, body []byte, out any) error {
+	var group GroupTransactionsRequest
+	if err := json.Unmarshal(body, &group); err != nil {
+		return err
+	}
+	parent := Transaction{
+		Date:       group.Date,
+		Payee:      group.Payee,
+		CategoryID: group.CategoryID,
+		Notes:      group.Notes,
+		Status:     group.Status,
+	}
+	if parent.Status == "" {
+		parent.Status = "unreviewed"
+	}
+	for i, id := range group.IDs {
+		child, err := c.GetTransaction(ctx, id)
+		if err != nil {
+			return fmt.Errorf("dry run: failed to fetch transaction %d: %w", id, err)
+		}
+		if i == 0 {
+			parent.Currency = child.Currency
+		}
+		parent.Amount += child.Amount
+		parent.ToBase += child.ToBase
+		fmt.Fprintf(c.dryRun, "  transaction %d (%s %s) -> group %q\n", id, child.Payee, child.Amount.Format(child.Currency), group.Payee)
+	}
+
+	if out == nil {
+		return nil
+	}
+	raw, err := json.Marshal(parent)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func compactJSON(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(raw)
+}
@@ -0,0 +1,250 @@
+package lunchmoney_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+	"lunchmoney-cli/internal/mockapi"
+)
+
+func newMockClient(tb testing.TB, api *mockapi.Server) *lunchmoney.Client {
+	tb.Helper()
+	ts := httptest.NewServer(api)
+	tb.Cleanup(ts.Close)
+	tb.Setenv("LUNCHMONEY_BASE_URL", ts.URL+"/v2")
+	tb.Setenv("LUNCHMONEY_API_KEY", mockapi.DefaultAPIKey)
+	client, err := lunchmoney.NewFromEnv()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return client
+}
+
+func TestListTransactionsMonthlyWindows(t *testing.T) {
+	api := mockapi.New(mockapi.DefaultFixture())
+	api.PageSize = 4
+	client := newMockClient(t, api)
+	params := lunchmoney.ListTransactionsParams{StartDate: "2024-12-15", EndDate: "2025-03-31"}
+
+	client.SetConcurrency(1)
+	serial, err := client.ListTransactions(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetConcurrency(4)
+	parallel, err := client.ListTransactions(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parallel) == 0 || !slices.EqualFunc(serial, parallel, func(a, b lunchmoney.Transaction) bool { return a.ID == b.ID }) {
+		t.Fatalf("concurrent fetch returned %d transactions, serial %d, or in another order", len(parallel), len(serial))
+	}
+	if !slices.IsSortedFunc(parallel, func(a, b lunchmoney.Transaction) int { return cmp.Compare(a.Date, b.Date) }) {
+		t.Error("transactions are not in date order across windows")
+	}
+
+	windows := map[string]bool{}
+	for _, req := range api.Requests() {
+		var start, end string
+		if _, err := fmt.Sscanf(req, "GET /transactions?end_date=%10s&limit=1000&offset=0&start_date=%10s", &end, &start); err == nil {
+			windows[start+".."+end] = true
+		}
+	}
+	for _, want := range []string{"2024-12-15..2024-12-31", "2025-01-01..2025-01-31", "2025-02-01..2025-02-28", "2025-03-01..2025-03-31"} {
+		if !windows[want] {
+			t.Errorf("no request for window %s (got %v)", want, windows)
+		}
+	}
+}
+
+func TestListTransactionsFailsOnFirstError(t *testing.T) {
+	api := mockapi.New(mockapi.DefaultFixture())
+	api.Fail(http.MethodGet, "/transactions", http.StatusInternalServerError, 1)
+	client := newMockClient(t, api)
+
+	_, err := client.ListTransactions(context.Background(), lunchmoney.ListTransactionsParams{StartDate: "2024-12-01", EndDate: "2025-03-31"})
+	if err == nil {
+		t.Fatal("expected an error when one window fails")
+	}
+}
+
+func TestRetriesTooManyRequests(t *testing.T) {
+	api := mockapi.New(mockapi.DefaultFixture())
+	api.Fail(http.MethodGet, "/tags", http.StatusTooManyRequests, 2)
+	api.Fail(http.MethodPut, "/transactions/1038", http.StatusTooManyRequests, 1)
+	client := newMockClient(t, api)
+
+	start := time.Now()
+	tags, err := client.ListTags(context.Background())
+	if err != nil {
+		t.Fatalf("ListTags after two 429s: %v", err)
+	}
+	if len(tags) == 0 {
+		t.Error("no tags after retrying")
+	}
+	if elapsed := time.Since(start); elapsed < 2*time.Second {
+		t.Errorf("retried after %v, want Retry-After (1s) honoured twice", elapsed)
+	}
+	reviewed := "reviewed"
+	if _, err := client.UpdateTransaction(context.Background(), 1038, lunchmoney.TransactionUpdate{Status: &reviewed}); err != nil {
+		t.Fatalf("update after a 429: %v", err)
+	}
+	if tx, _ := api.Transaction(1038); tx.Status != "reviewed" {
+		t.Errorf("status after retried update = %q, want reviewed", tx.Status)
+	}
+
+	// Retries are bounded: the fourth 429 in a row is returned.
+	api.Fail(http.MethodGet, "/me", http.StatusTooManyRequests, 10)
+	var apiErr *lunchmoney.APIError
+	if _, err := client.GetMe(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("GetMe after repeated 429s: err = %v, want a 429 APIError", err)
+	}
+	if got := countRequests(api, "GET /me"); got != 4 {
+		t.Errorf("sent GET /me %d times, want 4", got)
+	}
+
+	// Waiting stops with the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetMe(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetMe cancelled while waiting to retry: err = %v, want DeadlineExceeded", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	api.Fail(http.MethodPost, "/transactions/group", http.StatusTooManyRequests, 1)
+	_, err = client.GroupTransactions(ctx, lunchmoney.GroupTransactionsRequest{Date: "2025-01-10", Payee: "Group", IDs: []int64{1036, 1037}})
+	if !errors.Is(err, lunchmoney.ErrNotSent) {
+		t.Fatalf("write cancelled while waiting to retry: err = %v, want ErrNotSent", err)
+	}
+}
+
+func countRequests(api *mockapi.Server, req string) int {
+	n := 0
+	for _, r := range api.Requests() {
+		if r == req {
+			n++
+		}
+	}
+	return n
+}
+
+// BenchmarkListTransactions fetches two years of transactions from a mock
+// server with 5ms of latency per request, one month per page, at several
+// concurrency levels.
+func BenchmarkListTransactions(b *testing.B) {
+	var fixture mockapi.Fixture
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for day := range 730 {
+		for n := range 3 {
+			fixture.Transactions = append(fixture.Transactions, lunchmoney.Transaction{
+				ID:       int64(day*3 + n + 1),
+				Date:     start.AddDate(0, 0, day).Format(time.DateOnly),
+				Amount:   lunchmoney.AmountFromFloat(12.34),
+				Currency: "usd",
+				ToBase:   lunchmoney.AmountFromFloat(12.34),
+				Payee:    "Bench",
+				Status:   "reviewed",
+			})
+		}
+	}
+	api := mockapi.New(fixture)
+	api.Latency = 5 * time.Millisecond
+	client := newMockClient(b, api)
+	params := lunchmoney.ListTransactionsParams{StartDate: "2023-01-01", EndDate: "2024-12-31", Limit: 100}
+
+	for _, n := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", n), func(b *testing.B) {
+			client.SetConcurrency(n)
+			for b.Loop() {
+				txs, err := client.ListTransactions(context.Background(), params)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(txs) != len(fixture.Transactions) {
+					b.Fatalf("got %d transactions, want %d", len(txs), len(fixture.Transactions))
+				}
+			}
+		})
+	}
+}
+
+func TestIterTransactionsFetchesLazily(t *testing.T) {
+	api := mockapi.New(mockapi.DefaultFixture())
+	api.PageSize = 5
+	client := newMockClient(t, api)
+	params := lunchmoney.ListTransactionsParams{StartDate: "2024-12-01", EndDate: "2025-03-31", Limit: 5}
+
+	n := 0
+	for tx, err := range client.IterTransactions(context.Background(), params) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 && tx.ID == 0 {
+			t.Fatal("first transaction is empty")
+		}
+		if n++; n == 7 {
+			break
+		}
+	}
+	if got := len(api.Requests()); got != 2 {
+		t.Errorf("made %d requests for 7 transactions at 5 per page, want 2", got)
+	}
+
+	all, err := client.ListTransactions(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed []int64
+	for tx, err := range client.IterTransactions(context.Background(), params) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed = append(streamed, tx.ID)
+	}
+	if len(streamed) != len(all) {
+		t.Errorf("streamed %d transactions, listed %d", len(streamed), len(all))
+	}
+}
+
+func TestCancellationStopsBetweenRequests(t *testing.T) {
+	api := mockapi.New(mockapi.DefaultFixture())
+	api.PageSize = 5
+	client := newMockClient(t, api)
+	params := lunchmoney.ListTransactionsParams{StartDate: "2024-12-01", EndDate: "2025-03-31", Limit: 5}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var err error
+	n := 0
+	for _, err = range client.IterTransactions(ctx, params) {
+		if err != nil {
+			break
+		}
+		if n++; n == 5 {
+			cancel()
+		}
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if got := len(api.Requests()); got != 1 {
+		t.Errorf("made %d requests after cancelling on the first page, want 1", got)
+	}
+
+	_, err = client.MarkReviewed(ctx, []int64{1038})
+	if !errors.Is(err, lunchmoney.ErrNotSent) {
+		t.Fatalf("write after cancel: err = %v, want ErrNotSent", err)
+	}
+	if got := len(api.Requests()); got != 1 {
+		t.Errorf("a write was sent after cancel (%d requests)", got)
+	}
+}
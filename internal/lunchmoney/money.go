@@ -0,0 +1,227 @@
+package lunchmoney
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// amountDecimals is the precision of amounts in the API ("numeric format to 4
+// decimal places").
+const amountDecimals = 4
+
+const amountScale = 10000
+
+// Amount is an exact decimal money value stored as an integer number of
+// ten-thousandths, so sums over any number of transactions never drift.
+//
+// It unmarshals from either a JSON string ("12.3400") or number (12.34) and
+// marshals as a JSON number with no trailing zeros.
+type Amount int64
+
+// ParseAmount parses a decimal string such as "-12.34". Values with more than
+// four decimal places are rounded half away from zero.
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || strings.ContainsAny(s, "/eE") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amountFromRat(r)
+}
+
+func amountFromRat(r *big.Rat) (Amount, error) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(amountScale, 1))
+	num := new(big.Int).Set(scaled.Num())
+	den := scaled.Denom()
+
+	neg := num.Sign() < 0
+	num.Abs(num)
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("amount %s out of range", r.FloatString(amountDecimals))
+	}
+	v := q.Int64()
+	if neg {
+		v = -v
+	}
+	return Amount(v), nil
+}
+
+// AmountFromFloat converts a float, rounding to four decimal places. It is
+// meant for inputs that are already floats, not for arithmetic.
+func AmountFromFloat(f float64) Amount {
+	return Amount(math.Round(f * amountScale))
+}
+
+// Float64 returns an approximate float value for statistics and charts.
+func (a Amount) Float64() float64 {
+	return float64(a) / amountScale
+}
+
+func (a Amount) Neg() Amount { return -a }
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Sign returns -1, 0 or 1.
+func (a Amount) Sign() int {
+	switch {
+	case a < 0:
+		return -1
+	case a > 0:
+		return 1
+	}
+	return 0
+}
+
+// MulInt multiplies by an integer factor.
+func (a Amount) MulInt(n int64) Amount { return a * Amount(n) }
+
+// DivInt divides by n, rounding half away from zero.
+func (a Amount) DivInt(n int64) Amount {
+	if n == 0 {
+		return 0
+	}
+	q, r := int64(a)/n, int64(a)%n
+	if 2*abs64(r) >= abs64(n) {
+		if (a < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Amount(q)
+}
+
+// MulRat multiplies by num/den, rounding half away from zero.
+func (a Amount) MulRat(num, den int64) Amount {
+	return a.MulInt(num).DivInt(den)
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// String returns the amount with four decimal places, the format the API
+// uses for amount strings.
+func (a Amount) String() string {
+	return a.decimalString(amountDecimals)
+}
+
+// Round rounds the amount to the currency's minor units.
+func (a Amount) Round(currency string) Amount {
+	digits := MinorUnits(currency)
+	if digits >= amountDecimals {
+		return a
+	}
+	step := int64(math.Pow10(amountDecimals - digits))
+	return a.DivInt(step).MulInt(step)
+}
+
+// Format renders the amount rounded to the currency's minor units, e.g.
+// "-12.35" for usd, "-1235" for jpy and "-12.345" for bhd.
+func (a Amount) Format(currency string) string {
+	digits := MinorUnits(currency)
+	return a.Round(currency).decimalString(digits)
+}
+
+func (a Amount) decimalString(digits int) string {
+	v := int64(a)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole := v / amountScale
+	frac := v % amountScale
+	if digits <= 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+	fracStr := fmt.Sprintf("%04d", frac)[:min(digits, amountDecimals)]
+	return sign + strconv.FormatInt(whole, 10) + "." + fracStr
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	s := strings.TrimRight(a.String(), "0")
+	s = strings.TrimSuffix(s, ".")
+	return []byte(s), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*a = 0
+		return nil
+	}
+	var s string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else {
+		s = string(data)
+	}
+	parsed, err := parseJSONAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// parseJSONAmount also accepts exponent notation, which JSON numbers allow.
+func parseJSONAmount(s string) (Amount, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return amountFromRat(r)
+}
+
+// zeroDecimalCurrencies and threeDecimalCurrencies list the ISO 4217 minor
+// units for the currencies in the API's currencyEnum that do not use two.
+var (
+	zeroDecimalCurrencies = map[string]bool{
+		"bif": true, "clp": true, "djf": true, "gnf": true, "isk": true,
+		"jpy": true, "kmf": true, "krw": true, "pyg": true, "rwf": true,
+		"ugx": true, "vnd": true, "vuv": true, "xaf": true, "xof": true,
+		"xpf": true,
+	}
+	threeDecimalCurrencies = map[string]bool{
+		"bhd": true, "iqd": true, "jod": true, "kwd": true, "lyd": true,
+		"omr": true, "tnd": true,
+	}
+)
+
+// MinorUnits returns the number of decimal places used by a currency. BTC is
+// capped at the API's four decimal places.
+func MinorUnits(currency string) int {
+	c := strings.ToLower(currency)
+	switch {
+	case zeroDecimalCurrencies[c]:
+		return 0
+	case threeDecimalCurrencies[c]:
+		return 3
+	case c == "btc":
+		return amountDecimals
+	}
+	return 2
+}
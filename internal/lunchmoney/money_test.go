@@ -0,0 +1,149 @@
+package lunchmoney_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want lunchmoney.Amount
+		ok   bool
+	}{
+		{"12.34", 123400, true},
+		{"-12.34", -123400, true},
+		{" 5 ", 50000, true},
+		{"+1.5", 15000, true},
+		{"0.0001", 1, true},
+		{"0.00005", 1, true},
+		{"-0.00005", -1, true},
+		{"0.00004", 0, true},
+		{"-1.23455", -12346, true},
+		{"", 0, false},
+		{"abc", 0, false},
+		{"1e3", 0, false},
+		{"1E-2", 0, false},
+		{"1/3", 0, false},
+		{"99999999999999999999", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := lunchmoney.ParseAmount(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseAmount(%q) error = %v, want ok=%t", tt.in, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAmountDivInt(t *testing.T) {
+	tests := []struct {
+		a    lunchmoney.Amount
+		n    int64
+		want lunchmoney.Amount
+	}{
+		{10, 3, 3},
+		{1, 3, 0},
+		{5, 2, 3},
+		{-5, 2, -3},
+		{5, -2, -3},
+		{-5, -2, 3},
+		{-1, 2, -1},
+		{-4, 3, -1},
+		{-7, 4, -2},
+		{7, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.DivInt(tt.n); got != tt.want {
+			t.Errorf("Amount(%d).DivInt(%d) = %d, want %d", tt.a, tt.n, got, tt.want)
+		}
+	}
+	if got := lunchmoney.Amount(100000).MulRat(1, 3); got != 33333 {
+		t.Errorf("10.0000 * 1/3 = %d, want 33333", got)
+	}
+}
+
+func TestAmountRoundAndFormat(t *testing.T) {
+	tests := []struct {
+		a        lunchmoney.Amount
+		currency string
+		round    lunchmoney.Amount
+		format   string
+	}{
+		{-123456, "usd", -123500, "-12.35"},
+		{-123456, "USD", -123500, "-12.35"},
+		{-123456, "jpy", -120000, "-12"},
+		{125000, "jpy", 130000, "13"},
+		{-5000, "jpy", -10000, "-1"},
+		{4999, "krw", 0, "0"},
+		{-123456, "bhd", -123460, "-12.346"},
+		{123455, "kwd", 123460, "12.346"},
+		{-123456, "btc", -123456, "-12.3456"},
+		{50, "usd", 100, "0.01"},
+		{0, "usd", 0, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Round(tt.currency); got != tt.round {
+			t.Errorf("Amount(%d).Round(%s) = %d, want %d", tt.a, tt.currency, got, tt.round)
+		}
+		if got := tt.a.Format(tt.currency); got != tt.format {
+			t.Errorf("Amount(%d).Format(%s) = %q, want %q", tt.a, tt.currency, got, tt.format)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	marshal := []struct {
+		a    lunchmoney.Amount
+		want string
+	}{
+		{123400, "12.34"},
+		{-50000, "-5"},
+		{1, "0.0001"},
+		{-1, "-0.0001"},
+		{0, "0"},
+	}
+	for _, tt := range marshal {
+		raw, err := json.Marshal(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != tt.want {
+			t.Errorf("Marshal(%d) = %s, want %s", tt.a, raw, tt.want)
+		}
+		var back lunchmoney.Amount
+		if err := json.Unmarshal(raw, &back); err != nil || back != tt.a {
+			t.Errorf("round trip of %d = %d, %v", tt.a, back, err)
+		}
+	}
+
+	unmarshal := []struct {
+		in   string
+		want lunchmoney.Amount
+		ok   bool
+	}{
+		{`"12.3400"`, 123400, true},
+		{`12.34`, 123400, true},
+		{`"-0.5"`, -5000, true},
+		{`1.5e2`, 1500000, true},
+		{`null`, 0, true},
+		{`"x"`, 0, false},
+		{`true`, 0, false},
+	}
+	for _, tt := range unmarshal {
+		a := lunchmoney.Amount(99)
+		err := json.Unmarshal([]byte(tt.in), &a)
+		if (err == nil) != tt.ok {
+			t.Errorf("Unmarshal(%s) error = %v, want ok=%t", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && a != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, a, tt.want)
+		}
+	}
+}
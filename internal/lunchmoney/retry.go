@@ -0,0 +1,78 @@
+package lunchmoney
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxRetries is how many times a request answered with 429 Too Many
+	// Requests is sent again before the 429 is returned to the caller.
+	maxRetries = 3
+
+	// maxRetryWait is the longest Retry-After the client waits out; a
+	// longer one is returned as the error straight away.
+	maxRetryWait = time.Minute
+)
+
+// sendWithRetry sends req again when the API answers 429 Too Many Requests,
+// up to maxRetries times, first waiting as long as its Retry-After header
+// asks (or 1s, 2s, 4s without one). Waiting stops when ctx is done; for a
+// write that only means it was never applied, so the error wraps ErrNotSent.
+// In replay mode recorded 429s are retried without waiting.
+func (c *Client) sendWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if req.Body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		}
+		resp, err := c.send(req, reqBody)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == maxRetries {
+			return resp, err
+		}
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"), attempt)
+		if !ok {
+			return resp, nil
+		}
+		if c.replay != nil {
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if req.Method != http.MethodGet {
+				return nil, fmt.Errorf("%w: %w", ErrNotSent, ctx.Err())
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter is how long to wait before retry attempt+1, from a Retry-After
+// header in seconds or as an HTTP date. It reports false when the server
+// asks for longer than maxRetryWait.
+func retryAfter(header string, attempt int) (time.Duration, bool) {
+	wait := time.Second << attempt
+	if secs, err := strconv.Atoi(strings.TrimSpace(header)); err == nil {
+		wait = time.Duration(max(secs, 0)) * time.Second
+	} else if when, err := http.ParseTime(header); err == nil {
+		wait = max(time.Until(when), 0)
+	}
+	return wait, wait <= maxRetryWait
+}
@@ -0,0 +1,124 @@
+package mockapi
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// Fixture is the data a server starts with. On disk it is a directory with
+// one file per endpoint, each holding that endpoint's response body:
+//
+//	me.json               the /me object
+//	categories.json       {"categories": [...]}
+//	tags.json             {"tags": [...]}
+//	manual_accounts.json  {"manual_accounts": [...]}
+//	plaid_accounts.json   {"plaid_accounts": [...]}
+//	recurring_items.json  {"recurring_items": [...]}
+//	transactions.json     {"transactions": [...]}
+//
+// Missing files leave that resource empty, so captured API responses can be
+// dropped in as they are.
+type Fixture struct {
+	User           lunchmoney.User
+	Categories     []lunchmoney.Category
+	Tags           []lunchmoney.Tag
+	ManualAccounts []lunchmoney.ManualAccount
+	PlaidAccounts  []lunchmoney.PlaidAccount
+	RecurringItems []lunchmoney.RecurringItem
+	Transactions   []lunchmoney.Transaction
+}
+
+//go:embed fixtures/default/*.json
+var defaultFixtures embed.FS
+
+// DefaultFixture is a small budget covering every command: paychecks, rent,
+// subscriptions with a price change, a card payment transfer, duplicates,
+// payee variants, unreviewed and pending transactions.
+func DefaultFixture() Fixture {
+	sub, err := fs.Sub(defaultFixtures, "fixtures/default")
+	if err != nil {
+		panic(err)
+	}
+	f, err := loadFixture(sub)
+	if err != nil {
+		panic(fmt.Sprintf("mockapi: bad default fixture: %v", err))
+	}
+	return f
+}
+
+// LoadFixture reads a fixture directory.
+func LoadFixture(dir string) (Fixture, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return Fixture{}, err
+	}
+	if !info.IsDir() {
+		return Fixture{}, fmt.Errorf("%s is not a directory", dir)
+	}
+	return loadFixture(os.DirFS(dir))
+}
+
+func loadFixture(fsys fs.FS) (Fixture, error) {
+	var f Fixture
+	files := []struct {
+		name string
+		into any
+	}{
+		{"me.json", &f.User},
+		{"categories.json", &struct {
+			Categories *[]lunchmoney.Category `json:"categories"`
+		}{&f.Categories}},
+		{"tags.json", &struct {
+			Tags *[]lunchmoney.Tag `json:"tags"`
+		}{&f.Tags}},
+		{"manual_accounts.json", &struct {
+			ManualAccounts *[]lunchmoney.ManualAccount `json:"manual_accounts"`
+		}{&f.ManualAccounts}},
+		{"plaid_accounts.json", &struct {
+			PlaidAccounts *[]lunchmoney.PlaidAccount `json:"plaid_accounts"`
+		}{&f.PlaidAccounts}},
+		{"recurring_items.json", &struct {
+			RecurringItems *[]lunchmoney.RecurringItem `json:"recurring_items"`
+		}{&f.RecurringItems}},
+		{"transactions.json", &struct {
+			Transactions *[]lunchmoney.Transaction `json:"transactions"`
+		}{&f.Transactions}},
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file.name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Fixture{}, err
+		}
+		if err := json.Unmarshal(data, file.into); err != nil {
+			return Fixture{}, fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+	return f, nil
+}
+
+// clone copies the slices so a server's writes never reach the caller's
+// fixture. Transactions are the only records the server changes.
+func (f Fixture) clone() Fixture {
+	out := f
+	out.Categories = slices.Clone(f.Categories)
+	out.Tags = slices.Clone(f.Tags)
+	out.ManualAccounts = slices.Clone(f.ManualAccounts)
+	out.PlaidAccounts = slices.Clone(f.PlaidAccounts)
+	out.RecurringItems = slices.Clone(f.RecurringItems)
+	out.Transactions = make([]lunchmoney.Transaction, len(f.Transactions))
+	for i, tx := range f.Transactions {
+		tx.TagIDs = slices.Clone(tx.TagIDs)
+		out.Transactions[i] = tx
+	}
+	return out
+}
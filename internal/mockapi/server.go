@@ -0,0 +1,599 @@
+// Package mockapi is an in-memory fake of the Lunch Money v2 API. It serves
+// the endpoints the CLI uses, seeded from fixture files, and can inject
+// errors and rate limiting. It backs `lm dev mock-server` and the end-to-end
+// tests.
+package mockapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+// DefaultAPIKey is the bearer token a new server accepts.
+const DefaultAPIKey = "mock-api-key"
+
+// Server implements http.Handler. Paths may carry a /v2 prefix, so the
+// client's base URL can be either the server root or root + "/v2".
+type Server struct {
+	// APIKey is the bearer token requests must send; empty accepts any.
+	APIKey string
+	// PageSize caps the transactions returned per page regardless of the
+	// requested limit, to exercise pagination. Zero means no cap.
+	PageSize int
+	// Latency delays every response, to make concurrency measurable.
+	Latency time.Duration
+	// OnRequest, if set, is called with each request before it is handled,
+	// e.g. to cancel a client partway through a run.
+	OnRequest func(r *http.Request)
+
+	mu     sync.Mutex
+	data   Fixture
+	nextID int64
+	clock  time.Time
+	// groups holds the transactions each group parent replaced.
+	groups   map[int64][]lunchmoney.Transaction
+	faults   []fault
+	requests []string
+	mux      *http.ServeMux
+}
+
+type fault struct {
+	method string
+	path   string
+	status int
+	left   int
+}
+
+// New returns a server holding a copy of f.
+func New(f Fixture) *Server {
+	s := &Server{
+		APIKey: DefaultAPIKey,
+		data:   f.clone(),
+		clock:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		groups: make(map[int64][]lunchmoney.Transaction),
+	}
+	for _, tx := range s.data.Transactions {
+		s.nextID = max(s.nextID, tx.ID)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /me", s.getMe)
+	mux.HandleFunc("GET /categories", s.listCategories)
+	mux.HandleFunc("GET /tags", s.listTags)
+	mux.HandleFunc("GET /manual_accounts", s.listManualAccounts)
+	mux.HandleFunc("GET /plaid_accounts", s.listPlaidAccounts)
+	mux.HandleFunc("GET /recurring_items", s.listRecurringItems)
+	mux.HandleFunc("GET /transactions", s.listTransactions)
+	mux.HandleFunc("PUT /transactions", s.bulkUpdateTransactions)
+	mux.HandleFunc("POST /transactions/group", s.groupTransactions)
+	mux.HandleFunc("DELETE /transactions/group/{id}", s.ungroupTransactions)
+	mux.HandleFunc("GET /transactions/{id}", s.getTransaction)
+	mux.HandleFunc("PUT /transactions/{id}", s.updateTransaction)
+	mux.HandleFunc("DELETE /transactions/{id}", s.deleteTransaction)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "Not found")
+	})
+	s.mux = mux
+	return s
+}
+
+// Fail makes the next times requests matching method and path (without the
+// /v2 prefix, e.g. "/transactions") fail with status. A 429 carries a
+// Retry-After header.
+func (s *Server) Fail(method, path string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, fault{method: method, path: path, status: status, left: times})
+}
+
+// Requests lists the requests served so far as "METHOD /path?query".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// Transactions returns the current transactions sorted by ID.
+func (s *Server) Transactions() []lunchmoney.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	txs := slices.Clone(s.data.Transactions)
+	sort.Slice(txs, func(i, j int) bool { return txs[i].ID < txs[j].ID })
+	return txs
+}
+
+// Transaction returns one transaction by ID.
+func (s *Server) Transaction(id int64) (lunchmoney.Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.indexOf(id); i >= 0 {
+		return s.data.Transactions[i], true
+	}
+	return lunchmoney.Transaction{}, false
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, "/v2")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	status := s.takeFault(r.Method, r.URL.Path)
+	s.mu.Unlock()
+
+	if s.OnRequest != nil {
+		s.OnRequest(r)
+	}
+	if s.Latency > 0 {
+		time.Sleep(s.Latency)
+	}
+
+	if s.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+s.APIKey {
+		writeError(w, http.StatusUnauthorized, "Unauthorized: invalid API key")
+		return
+	}
+	if status != 0 {
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, status, "Too many requests")
+			return
+		}
+		writeError(w, status, fmt.Sprintf("Injected failure (%d)", status))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) takeFault(method, path string) int {
+	for i := range s.faults {
+		f := &s.faults[i]
+		if f.left > 0 && f.method == method && f.path == path {
+			f.left--
+			return f.status
+		}
+	}
+	return 0
+}
+
+func (s *Server) getMe(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.data.User)
+}
+
+func (s *Server) listCategories(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"categories": s.data.Categories})
+}
+
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"tags": s.data.Tags})
+}
+
+func (s *Server) listManualAccounts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"manual_accounts": s.data.ManualAccounts})
+}
+
+func (s *Server) listPlaidAccounts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"plaid_accounts": s.data.PlaidAccounts})
+}
+
+func (s *Server) listRecurringItems(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]lunchmoney.RecurringItem, 0, len(s.data.RecurringItems))
+	for _, item := range s.data.RecurringItems {
+		if item.Status == "suggested" && r.URL.Query().Get("include_suggested") != "true" {
+			continue
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"recurring_items": items})
+}
+
+// listTransactions filters like the real endpoint: dates are inclusive,
+// pending transactions are left out unless include_pending or is_pending
+// asks for them, and results are ordered by date then ID.
+func (s *Server) listTransactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, end := q.Get("start_date"), q.Get("end_date")
+	if (start == "") != (end == "") {
+		writeError(w, http.StatusBadRequest, "start_date and end_date must be set together")
+		return
+	}
+	limit, offset := 1000, 0
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 2000 {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 2000")
+			return
+		}
+		limit = n
+	}
+	if raw := q.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.PageSize > 0 {
+		limit = min(limit, s.PageSize)
+	}
+
+	var matched []lunchmoney.Transaction
+	for _, tx := range s.data.Transactions {
+		if start != "" && (tx.Date < start || tx.Date > end) {
+			continue
+		}
+		if status := q.Get("status"); status != "" && tx.Status != status {
+			continue
+		}
+		switch raw := q.Get("is_pending"); {
+		case raw != "":
+			if strconv.FormatBool(tx.IsPending) != raw {
+				continue
+			}
+		case tx.IsPending && q.Get("include_pending") != "true":
+			continue
+		}
+		matched = append(matched, tx)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Date != matched[j].Date {
+			return matched[i].Date < matched[j].Date
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	page := []lunchmoney.Transaction{}
+	if offset < len(matched) {
+		page = matched[offset:min(offset+limit, len(matched))]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"transactions": page,
+		"has_more":     offset+len(page) < len(matched),
+	})
+}
+
+func (s *Server) getTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexOf(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction %d not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.data.Transactions[i])
+}
+
+func (s *Server) updateTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var payload map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexOf(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction %d not found", id))
+		return
+	}
+	updated, err := s.applyUpdate(s.data.Transactions[i], payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.data.Transactions[i] = updated
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// bulkUpdateTransactions validates every item before changing anything, so
+// a bad item rejects the whole request like the real endpoint.
+func (s *Server) bulkUpdateTransactions(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Transactions []map[string]json.RawMessage `json:"transactions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if len(body.Transactions) == 0 || len(body.Transactions) > 500 {
+		writeError(w, http.StatusBadRequest, "transactions must contain 1 to 500 items")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	indexes := make([]int, len(body.Transactions))
+	updated := make([]lunchmoney.Transaction, len(body.Transactions))
+	for n, item := range body.Transactions {
+		var id int64
+		if err := json.Unmarshal(item["id"], &id); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("transactions[%d]: id is required", n))
+			return
+		}
+		i := s.indexOf(id)
+		if i < 0 {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction %d not found", id))
+			return
+		}
+		fields := make(map[string]json.RawMessage, len(item))
+		for k, v := range item {
+			if k != "id" {
+				fields[k] = v
+			}
+		}
+		tx, err := s.applyUpdate(s.data.Transactions[i], fields)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("transactions[%d]: %v", n, err))
+			return
+		}
+		indexes[n], updated[n] = i, tx
+	}
+	for n, i := range indexes {
+		s.data.Transactions[i] = updated[n]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"transactions": updated})
+}
+
+func (s *Server) deleteTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexOf(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction %d not found", id))
+		return
+	}
+	s.data.Transactions = slices.Delete(s.data.Transactions, i, i+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// groupTransactions replaces the grouped transactions with one parent whose
+// amount is their sum in the first transaction's currency.
+func (s *Server) groupTransactions(w http.ResponseWriter, r *http.Request) {
+	var req lunchmoney.GroupTransactionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if len(req.IDs) < 2 || req.Date == "" || req.Payee == "" {
+		writeError(w, http.StatusBadRequest, "ids (at least two), date and payee are required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.CategoryID != nil && !s.assignable(*req.CategoryID) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid category_id %d", *req.CategoryID))
+		return
+	}
+	var children []lunchmoney.Transaction
+	for _, id := range req.IDs {
+		i := s.indexOf(id)
+		if i < 0 {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction %d not found", id))
+			return
+		}
+		children = append(children, s.data.Transactions[i])
+	}
+
+	s.nextID++
+	parent := lunchmoney.Transaction{
+		ID:         s.nextID,
+		Date:       req.Date,
+		Payee:      req.Payee,
+		Currency:   children[0].Currency,
+		CategoryID: req.CategoryID,
+		Notes:      req.Notes,
+		Status:     req.Status,
+		UpdatedAt:  s.tick(),
+	}
+	if parent.Status == "" {
+		parent.Status = "unreviewed"
+	}
+	for _, c := range children {
+		parent.Amount += c.Amount
+		parent.ToBase += c.ToBase
+	}
+	s.data.Transactions = slices.DeleteFunc(s.data.Transactions, func(tx lunchmoney.Transaction) bool {
+		return slices.Contains(req.IDs, tx.ID)
+	})
+	s.data.Transactions = append(s.data.Transactions, parent)
+	s.groups[parent.ID] = children
+	writeJSON(w, http.StatusCreated, parent)
+}
+
+// ungroupTransactions removes a group parent and puts back the
+// transactions it replaced.
+func (s *Server) ungroupTransactions(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	children, ok := s.groups[id]
+	i := s.indexOf(id)
+	if !ok || i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Transaction group %d not found", id))
+		return
+	}
+	s.data.Transactions = slices.Delete(s.data.Transactions, i, i+1)
+	delete(s.groups, id)
+	for _, c := range children {
+		c.UpdatedAt = s.tick()
+		s.data.Transactions = append(s.data.Transactions, c)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyUpdate returns tx with the payload's fields applied. Unknown fields
+// and references to missing categories, tags or accounts are errors.
+func (s *Server) applyUpdate(tx lunchmoney.Transaction, payload map[string]json.RawMessage) (lunchmoney.Transaction, error) {
+	if len(payload) == 0 {
+		return tx, fmt.Errorf("no fields to update")
+	}
+	isNull := func(raw json.RawMessage) bool { return string(raw) == "null" }
+	for field, raw := range payload {
+		var err error
+		switch field {
+		case "date":
+			err = json.Unmarshal(raw, &tx.Date)
+			if err == nil {
+				_, err = time.Parse("2006-01-02", tx.Date)
+			}
+		case "amount":
+			err = json.Unmarshal(raw, &tx.Amount)
+			if err == nil {
+				tx.ToBase = tx.Amount
+			}
+		case "currency":
+			err = json.Unmarshal(raw, &tx.Currency)
+		case "payee":
+			err = json.Unmarshal(raw, &tx.Payee)
+		case "notes":
+			tx.Notes = nil
+			if !isNull(raw) {
+				err = json.Unmarshal(raw, &tx.Notes)
+			}
+		case "status":
+			err = json.Unmarshal(raw, &tx.Status)
+			if err == nil && tx.Status != "reviewed" && tx.Status != "unreviewed" {
+				err = fmt.Errorf("invalid status %q", tx.Status)
+			}
+		case "category_id":
+			tx.CategoryID = nil
+			if !isNull(raw) {
+				err = json.Unmarshal(raw, &tx.CategoryID)
+				if err == nil && !s.assignable(*tx.CategoryID) {
+					err = fmt.Errorf("invalid category_id %d", *tx.CategoryID)
+				}
+			}
+		case "recurring_id":
+			tx.RecurringID = nil
+			if !isNull(raw) {
+				err = json.Unmarshal(raw, &tx.RecurringID)
+			}
+		case "tag_ids", "additional_tag_ids":
+			var ids []int64
+			if err = json.Unmarshal(raw, &ids); err != nil {
+				break
+			}
+			for _, id := range ids {
+				if !slices.ContainsFunc(s.data.Tags, func(t lunchmoney.Tag) bool { return t.ID == id }) {
+					err = fmt.Errorf("invalid tag id %d", id)
+				}
+			}
+			if field == "tag_ids" {
+				tx.TagIDs = ids
+			} else {
+				for _, id := range ids {
+					if !slices.Contains(tx.TagIDs, id) {
+						tx.TagIDs = append(tx.TagIDs, id)
+					}
+				}
+			}
+		case "external_id":
+			tx.ExternalID = nil
+			if !isNull(raw) {
+				err = json.Unmarshal(raw, &tx.ExternalID)
+			}
+		case "custom_metadata":
+			tx.CustomMetadata = nil
+			if !isNull(raw) {
+				err = json.Unmarshal(raw, &tx.CustomMetadata)
+			}
+		case "manual_account_id":
+			tx.ManualAccountID = nil
+			if !isNull(raw) {
+				err = json.Unmarshal(raw, &tx.ManualAccountID)
+			}
+		case "plaid_account_id":
+			tx.PlaidAccountID = nil
+			if !isNull(raw) {
+				err = json.Unmarshal(raw, &tx.PlaidAccountID)
+			}
+		default:
+			err = fmt.Errorf("unknown field %q", field)
+		}
+		if err != nil {
+			return tx, fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	tx.UpdatedAt = s.tick()
+	return tx, nil
+}
+
+func (s *Server) assignable(categoryID int64) bool {
+	for _, c := range s.data.Categories {
+		if c.ID == categoryID {
+			return !c.IsGroup && !c.Archived
+		}
+	}
+	return false
+}
+
+func (s *Server) indexOf(id int64) int {
+	return slices.IndexFunc(s.data.Transactions, func(tx lunchmoney.Transaction) bool { return tx.ID == id })
+}
+
+// tick returns a new, strictly increasing updated_at timestamp.
+func (s *Server) tick() string {
+	s.clock = s.clock.Add(time.Second)
+	return s.clock.Format(time.RFC3339)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid transaction id %q", r.PathValue("id")))
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError uses the API's error shape, which the client turns into an
+// APIError.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"message": msg})
+}
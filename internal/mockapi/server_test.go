@@ -0,0 +1,130 @@
+package mockapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"lunchmoney-cli/internal/lunchmoney"
+)
+
+func do(t *testing.T, s *Server, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Authorization", "Bearer "+DefaultAPIKey)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuth(t *testing.T) {
+	s := New(DefaultFixture())
+	req := httptest.NewRequest(http.MethodGet, "/v2/me", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without a key: status %d, want 401", rec.Code)
+	}
+	if rec := do(t, s, http.MethodGet, "/v2/me", ""); rec.Code != http.StatusOK {
+		t.Fatalf("with the key: status %d, want 200", rec.Code)
+	}
+}
+
+func TestListTransactionsPagination(t *testing.T) {
+	s := New(DefaultFixture())
+
+	var ids []int64
+	for offset := 0; ; offset += 10 {
+		rec := do(t, s, http.MethodGet, "/v2/transactions?start_date=2024-12-01&end_date=2025-03-31&limit=10&offset="+strconv.Itoa(offset), "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		var page struct {
+			Transactions []lunchmoney.Transaction `json:"transactions"`
+			HasMore      bool                     `json:"has_more"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range page.Transactions {
+			ids = append(ids, tx.ID)
+		}
+		if !page.HasMore {
+			break
+		}
+	}
+	// Every non-pending fixture transaction, each once.
+	if len(ids) != 41 {
+		t.Fatalf("got %d transactions, want 41", len(ids))
+	}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("transaction %d returned twice", id)
+		}
+		seen[id] = true
+	}
+
+	s.PageSize = 5
+	rec := do(t, s, http.MethodGet, "/v2/transactions?limit=100", "")
+	if !strings.Contains(rec.Body.String(), `"has_more":true`) {
+		t.Errorf("PageSize did not cap the page: %s", rec.Body)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := New(DefaultFixture())
+	s.Fail(http.MethodGet, "/tags", http.StatusTooManyRequests, 2)
+
+	for range 2 {
+		rec := do(t, s, http.MethodGet, "/v2/tags", "")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+			t.Fatalf("status %d, Retry-After %q; want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	if rec := do(t, s, http.MethodGet, "/v2/tags", ""); rec.Code != http.StatusOK {
+		t.Fatalf("after the faults: status %d, want 200", rec.Code)
+	}
+}
+
+func TestBulkUpdateIsAllOrNothing(t *testing.T) {
+	s := New(DefaultFixture())
+
+	rec := do(t, s, http.MethodPut, "/v2/transactions", `{"transactions":[{"id":1041,"category_id":2},{"id":1038,"category_id":999}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400: %s", rec.Code, rec.Body)
+	}
+	if tx, _ := s.Transaction(1041); tx.CategoryID != nil {
+		t.Fatalf("1041 changed by a rejected request: category %d", *tx.CategoryID)
+	}
+
+	rec = do(t, s, http.MethodPut, "/v2/transactions", `{"transactions":[{"id":1041,"category_id":2},{"id":1038,"status":"reviewed"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if tx, _ := s.Transaction(1041); tx.CategoryID == nil || *tx.CategoryID != 2 {
+		t.Errorf("1041 category = %v, want 2", tx.CategoryID)
+	}
+	if tx, _ := s.Transaction(1038); tx.Status != "reviewed" {
+		t.Errorf("1038 status = %q, want reviewed", tx.Status)
+	}
+}
+
+func TestWritesDoNotLeakIntoFixture(t *testing.T) {
+	f := DefaultFixture()
+	s := New(f)
+	do(t, s, http.MethodPut, "/v2/transactions/1041", `{"notes":"changed"}`)
+	for _, tx := range f.Transactions {
+		if tx.ID == 1041 && tx.Notes != nil {
+			t.Fatalf("fixture notes = %q, want unchanged", *tx.Notes)
+		}
+	}
+}